@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"errors"
+	"one-api/common/config"
+	"one-api/common/redis"
+	"time"
+
+	"github.com/coocood/freecache"
+)
+
+// BytesStore 用于缓存较大的原始数据(如完整的响应内容)，不经过序列化
+type BytesStore interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte, expiration time.Duration) error
+	Delete(key string) error
+}
+
+// NewBytesStore 启用 Redis 时使用 Redis，否则使用指定大小(MB)的内存缓存
+// 内存缓存单条数据的最大长度为总大小的 1/1024
+func NewBytesStore(memorySizeMB int) BytesStore {
+	if config.RedisEnabled {
+		return &redisBytesStore{}
+	}
+
+	if memorySizeMB <= 0 {
+		memorySizeMB = 64
+	}
+
+	return &memoryBytesStore{
+		cache: freecache.NewCache(memorySizeMB * 1024 * 1024),
+	}
+}
+
+type redisBytesStore struct{}
+
+func (s *redisBytesStore) Get(key string) ([]byte, error) {
+	value, err := redis.GetRedisClient().Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, CacheNotFound
+	}
+	return value, err
+}
+
+func (s *redisBytesStore) Set(key string, value []byte, expiration time.Duration) error {
+	return redis.GetRedisClient().Set(ctx, key, value, expiration).Err()
+}
+
+func (s *redisBytesStore) Delete(key string) error {
+	return redis.RedisDel(key)
+}
+
+type memoryBytesStore struct {
+	cache *freecache.Cache
+}
+
+func (s *memoryBytesStore) Get(key string) ([]byte, error) {
+	value, err := s.cache.Get([]byte(key))
+	if errors.Is(err, freecache.ErrNotFound) {
+		return nil, CacheNotFound
+	}
+	return value, err
+}
+
+func (s *memoryBytesStore) Set(key string, value []byte, expiration time.Duration) error {
+	return s.cache.Set([]byte(key), value, int(expiration.Seconds()))
+}
+
+func (s *memoryBytesStore) Delete(key string) error {
+	s.cache.Del([]byte(key))
+	return nil
+}
@@ -0,0 +1,28 @@
+package config
+
+type BatchSettings struct {
+	Enabled         bool
+	DiscountRatio   float64 // 批处理请求的计费倍率
+	MaxRunning      int     // 同时执行的批处理数量
+	LineConcurrency int     // 单个批处理内并发执行的请求数
+	MaxLines        int     // 单个批处理最多的请求数
+	MaxFileSize     int     // 上传文件的最大大小，单位 MB
+}
+
+var BatchSettingsInstance = BatchSettings{
+	Enabled:         false,
+	DiscountRatio:   0.5,
+	MaxRunning:      2,
+	LineConcurrency: 5,
+	MaxLines:        50000,
+	MaxFileSize:     100,
+}
+
+func init() {
+	GlobalOption.RegisterBool("BatchEnabled", &BatchSettingsInstance.Enabled)
+	GlobalOption.RegisterFloat("BatchDiscountRatio", &BatchSettingsInstance.DiscountRatio)
+	GlobalOption.RegisterInt("BatchMaxRunning", &BatchSettingsInstance.MaxRunning)
+	GlobalOption.RegisterInt("BatchLineConcurrency", &BatchSettingsInstance.LineConcurrency)
+	GlobalOption.RegisterInt("BatchMaxLines", &BatchSettingsInstance.MaxLines)
+	GlobalOption.RegisterInt("BatchMaxFileSize", &BatchSettingsInstance.MaxFileSize)
+}
@@ -0,0 +1,19 @@
+package config
+
+type ChannelConcurrencySettings struct {
+	QueueSize    int // 每个分组模型最多排队的请求数，0 为不排队
+	QueueTimeout int // 排队等待的最长时间(秒)
+	SlotLease    int // 并发名额的最长占用时间(秒)，防止节点异常退出后名额无法释放
+}
+
+var ChannelConcurrencySettingsInstance = ChannelConcurrencySettings{
+	QueueSize:    100,
+	QueueTimeout: 30,
+	SlotLease:    1800,
+}
+
+func init() {
+	GlobalOption.RegisterInt("ChannelConcurrencyQueueSize", &ChannelConcurrencySettingsInstance.QueueSize)
+	GlobalOption.RegisterInt("ChannelConcurrencyQueueTimeout", &ChannelConcurrencySettingsInstance.QueueTimeout)
+	GlobalOption.RegisterInt("ChannelConcurrencySlotLease", &ChannelConcurrencySettingsInstance.SlotLease)
+}
@@ -0,0 +1,18 @@
+package config
+
+type ChannelProbeSettings struct {
+	FailThreshold        int     // 连续失败多少次后禁用对应能力
+	HistoryRetentionDays int     // 检测记录保留天数
+	FingerprintMinScore  float64 // 指纹检测分数低于该值的渠道不参与对应模型的分配，0 为不限制
+}
+
+var ChannelProbeSettingsInstance = ChannelProbeSettings{
+	FailThreshold:        2,
+	HistoryRetentionDays: 30,
+}
+
+func init() {
+	GlobalOption.RegisterInt("ChannelProbeFailThreshold", &ChannelProbeSettingsInstance.FailThreshold)
+	GlobalOption.RegisterInt("ChannelProbeHistoryRetentionDays", &ChannelProbeSettingsInstance.HistoryRetentionDays)
+	GlobalOption.RegisterFloat("ChannelFingerprintMinScore", &ChannelProbeSettingsInstance.FingerprintMinScore)
+}
@@ -51,6 +51,52 @@ var EnableSafe = false
 // 默认使用系统自带关键词审查工具
 var SafeToolName = "Keyword"
 
+// 敏感信息(个人信息、密钥)的处理方式，可被用户分组和令牌的设置覆盖
+const (
+	SafeRedactModeOff   = "off"   // 不处理
+	SafeRedactModeBlock = "block" // 拒绝请求
+	SafeRedactModeMask  = "mask"  // 替换后再发送到上游
+	SafeRedactModeLog   = "log"   // 只记录日志
+)
+
+var SafeRedactMode = ""
+
+// 启用的敏感信息类别，为空时检测全部类别
+var SafeRedactCategories = []string{}
+
+// 输出内容审查，命中后的处理方式
+const (
+	SafeOutputModeTerminate = "terminate" // 终止输出
+	SafeOutputModeRedact    = "redact"    // 替换命中的内容后继续输出
+)
+
+// 是否审查模型输出的内容，需同时开启 EnableSafe
+var SafeOutputEnabled = false
+var SafeOutputMode = SafeOutputModeTerminate
+
+// 输出审查使用的工具，为空时使用 SafeToolName
+// 流式输出只支持 Keyword、PII、Secret，使用其他工具时只审查非流式输出
+var SafeOutputToolName = ""
+
+// 流式输出审查时保留的上文字符数，用于发现跨分块的违规内容
+var SafeOutputWindow = 100
+
+// 流式输出审查时，暂缓输出的内容遇到句子结束或达到该字符数时检查一次
+var SafeOutputCheckInterval = 50
+
+// Moderation 审查工具使用的模型和分组，通过分组内的渠道调用审核接口
+var SafeModerationModel = "omni-moderation-latest"
+var SafeModerationGroup = "default"
+
+// 各类别的分数阈值，分数达到阈值即视为违规，"*" 为默认阈值；未配置时使用接口返回的 flagged
+var SafeModerationThresholds = map[string]float64{}
+
+// 审核结果缓存时间(秒)，0 为不缓存
+var SafeModerationCacheTTL = 600
+
+// 审核接口调用失败时是否拦截，默认放行
+var SafeModerationFailClosed = false
+
 // 系统自带关键词审查默认字典
 var SafeKeyWords = []string{
 	"fuck",
@@ -187,7 +233,18 @@ var RetryTimes = 0
 var RetryTimeOut = 10
 
 var DefaultChannelWeight = uint(1)
+
+// 熔断器首次打开的时长，之后按连续熔断次数指数增长，为 0 时关闭熔断
 var RetryCooldownSeconds = 5
+var CircuitBreakerMaxOpenSeconds = 600
+var CircuitBreakerFailureThreshold = 5 // 连续失败次数达到该值时熔断
+var CircuitBreakerErrorRate = 0.5      // 统计窗口内错误率达到该值时熔断
+var CircuitBreakerMinRequests = 20     // 统计窗口内请求数达到该值才按错误率判断
+var CircuitBreakerWindowSeconds = 60
+var CircuitBreakerHalfOpenProbes = 1 // 半开状态下允许同时进行的探测请求数
+
+// 渠道负载均衡策略: weighted / latency / least_inflight / success_rate
+var ChannelBalanceStrategy = "weighted"
 
 var CFWorkerImageUrl = ""
 var CFWorkerImageKey = ""
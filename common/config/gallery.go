@@ -0,0 +1,17 @@
+package config
+
+type GallerySettings struct {
+	RehostEnabled bool   // 是否将任务产出转存到长期存储
+	RehostDrive   string // 转存使用的存储，S3 或 AliOSS，为空时自动选择
+	RehostMaxSize int    // 单个文件的最大大小，单位 MB
+}
+
+var GallerySettingsInstance = GallerySettings{
+	RehostMaxSize: 200,
+}
+
+func init() {
+	GlobalOption.RegisterBool("GalleryRehostEnabled", &GallerySettingsInstance.RehostEnabled)
+	GlobalOption.RegisterString("GalleryRehostDrive", &GallerySettingsInstance.RehostDrive)
+	GlobalOption.RegisterInt("GalleryRehostMaxSize", &GallerySettingsInstance.RehostMaxSize)
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	PayloadCaptureSinkDB   = "db"
+	PayloadCaptureSinkFile = "file"
+	PayloadCaptureSinkS3   = "s3"
+)
+
+type PayloadRedactRule struct {
+	Name        string `json:"name"`
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+
+	regexp *regexp.Regexp
+}
+
+type PayloadCaptureSettings struct {
+	sync.RWMutex
+	Enabled       bool
+	Sink          string // 保存位置：db、file、s3
+	RetentionDays int    // 保留天数，0 为不清理
+	MaxBodySize   int    // 请求和响应各自保存的最大长度，单位 KB
+	UserIds       map[int]bool
+	RedactRules   []*PayloadRedactRule
+}
+
+var PayloadCaptureSettingsInstance = PayloadCaptureSettings{
+	Sink:          PayloadCaptureSinkDB,
+	RetentionDays: 7,
+	MaxBodySize:   256,
+	UserIds:       map[int]bool{},
+}
+
+var defaultPayloadRedactRules = `[
+	{"name":"api_key","pattern":"(?i)(\"(?:api[_-]?key|authorization|password|secret|access[_-]?token)\"\\s*:\\s*\")[^\"]*(\")","replacement":"${1}***${2}"},
+	{"name":"bearer","pattern":"(?i)(bearer\\s+)[a-z0-9._\\-]+","replacement":"${1}***"},
+	{"name":"sk","pattern":"sk-[A-Za-z0-9_\\-]{16,}","replacement":"sk-***"}
+]`
+
+func init() {
+	GlobalOption.RegisterBool("PayloadCaptureEnabled", &PayloadCaptureSettingsInstance.Enabled)
+	GlobalOption.RegisterString("PayloadCaptureSink", &PayloadCaptureSettingsInstance.Sink)
+	GlobalOption.RegisterInt("PayloadCaptureRetentionDays", &PayloadCaptureSettingsInstance.RetentionDays)
+	GlobalOption.RegisterInt("PayloadCaptureMaxBodySize", &PayloadCaptureSettingsInstance.MaxBodySize)
+	GlobalOption.RegisterCustom("PayloadCaptureUserIds", func() string {
+		return PayloadCaptureSettingsInstance.GetUserIdsString()
+	}, func(value string) error {
+		return PayloadCaptureSettingsInstance.SetUserIds(value)
+	}, "")
+	GlobalOption.RegisterCustom("PayloadCaptureRedactRules", func() string {
+		return PayloadCaptureSettingsInstance.GetRedactRulesJSONString()
+	}, func(value string) error {
+		return PayloadCaptureSettingsInstance.SetRedactRules(value)
+	}, defaultPayloadRedactRules)
+}
+
+// SetUserIds 设置需要记录的用户，逗号分隔
+func (c *PayloadCaptureSettings) SetUserIds(data string) error {
+	userIds := map[int]bool{}
+	for _, item := range strings.Split(data, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		userId, err := strconv.Atoi(item)
+		if err != nil {
+			return err
+		}
+		userIds[userId] = true
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.UserIds = userIds
+	return nil
+}
+
+func (c *PayloadCaptureSettings) GetUserIdsString() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	userIds := make([]string, 0, len(c.UserIds))
+	for userId := range c.UserIds {
+		userIds = append(userIds, strconv.Itoa(userId))
+	}
+	return strings.Join(userIds, ",")
+}
+
+func (c *PayloadCaptureSettings) IsCaptureUser(userId int) bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.UserIds[userId]
+}
+
+func (c *PayloadCaptureSettings) SetRedactRules(data string) error {
+	rules := make([]*PayloadRedactRule, 0)
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &rules); err != nil {
+			return err
+		}
+	}
+
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return err
+		}
+		rule.regexp = re
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.RedactRules = rules
+	return nil
+}
+
+func (c *PayloadCaptureSettings) GetRedactRulesJSONString() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	jsonData, _ := json.Marshal(c.RedactRules)
+	return string(jsonData)
+}
+
+// Redact 按脱敏规则替换内容
+func (c *PayloadCaptureSettings) Redact(text string) string {
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, rule := range c.RedactRules {
+		text = rule.regexp.ReplaceAllString(text, rule.Replacement)
+	}
+	return text
+}
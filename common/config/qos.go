@@ -0,0 +1,87 @@
+package config
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// 请求优先级，渠道并发用满排队时高优先级的请求排在前面，低优先级的请求优先被拒绝
+const (
+	QoSClassRealtime    = "realtime"    // 实时，不因排队过长被拒绝
+	QoSClassInteractive = "interactive" // 交互
+	QoSClassBatch       = "batch"       // 批处理
+)
+
+var qosPriorities = map[string]int{
+	QoSClassRealtime:    0,
+	QoSClassInteractive: 1,
+	QoSClassBatch:       2,
+}
+
+type QoSSettings struct {
+	sync.RWMutex
+	DefaultClass string         // 令牌和分组都未设置时的优先级
+	ShedDepth    map[string]int // 排队数达到该值后直接拒绝该优先级的新请求，未配置的优先级只受队列上限限制
+	RetryAfter   int            // 拒绝请求时返回的 Retry-After(秒)
+}
+
+var QoSSettingsInstance = QoSSettings{
+	DefaultClass: QoSClassInteractive,
+	ShedDepth:    map[string]int{},
+	RetryAfter:   5,
+}
+
+func init() {
+	GlobalOption.RegisterString("QoSDefaultClass", &QoSSettingsInstance.DefaultClass)
+	GlobalOption.RegisterInt("QoSRetryAfter", &QoSSettingsInstance.RetryAfter)
+	GlobalOption.RegisterCustom("QoSShedDepth", func() string {
+		return QoSSettingsInstance.GetShedDepthJSONString()
+	}, func(value string) error {
+		return QoSSettingsInstance.SetShedDepth(value)
+	}, `{"interactive":50,"batch":10}`)
+}
+
+// IsValidQoSClass 判断优先级是否有效
+func IsValidQoSClass(class string) bool {
+	_, ok := qosPriorities[class]
+	return ok
+}
+
+// QoSPriority 获取优先级的排序值，越小越优先，无效的优先级按交互处理
+func QoSPriority(class string) int {
+	if priority, ok := qosPriorities[class]; ok {
+		return priority
+	}
+	return qosPriorities[QoSClassInteractive]
+}
+
+func (q *QoSSettings) SetShedDepth(data string) error {
+	shedDepth := map[string]int{}
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &shedDepth); err != nil {
+			return err
+		}
+	}
+
+	q.Lock()
+	defer q.Unlock()
+	q.ShedDepth = shedDepth
+	return nil
+}
+
+func (q *QoSSettings) GetShedDepthJSONString() string {
+	q.RLock()
+	defer q.RUnlock()
+
+	jsonBytes, _ := json.Marshal(q.ShedDepth)
+	return string(jsonBytes)
+}
+
+// GetShedDepth 获取优先级的拒绝阈值，返回 false 表示未配置
+func (q *QoSSettings) GetShedDepth(class string) (int, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	depth, ok := q.ShedDepth[class]
+	return depth, ok
+}
@@ -0,0 +1,27 @@
+package config
+
+type RealtimeBridgeSettings struct {
+	Enabled            bool
+	ChatModel          string // 对话使用的模型，为空时使用请求的模型
+	TranscriptionModel string // 语音转文字使用的模型
+	SpeechModel        string // 文字转语音使用的模型
+	Voice              string // 默认音色
+	MaxAudioSize       int    // 单次提交的音频最大大小，单位 MB
+}
+
+var RealtimeBridgeSettingsInstance = RealtimeBridgeSettings{
+	Enabled:            false,
+	TranscriptionModel: "whisper-1",
+	SpeechModel:        "tts-1",
+	Voice:              "alloy",
+	MaxAudioSize:       25,
+}
+
+func init() {
+	GlobalOption.RegisterBool("RealtimeBridgeEnabled", &RealtimeBridgeSettingsInstance.Enabled)
+	GlobalOption.RegisterString("RealtimeBridgeChatModel", &RealtimeBridgeSettingsInstance.ChatModel)
+	GlobalOption.RegisterString("RealtimeBridgeTranscriptionModel", &RealtimeBridgeSettingsInstance.TranscriptionModel)
+	GlobalOption.RegisterString("RealtimeBridgeSpeechModel", &RealtimeBridgeSettingsInstance.SpeechModel)
+	GlobalOption.RegisterString("RealtimeBridgeVoice", &RealtimeBridgeSettingsInstance.Voice)
+	GlobalOption.RegisterInt("RealtimeBridgeMaxAudioSize", &RealtimeBridgeSettingsInstance.MaxAudioSize)
+}
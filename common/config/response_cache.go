@@ -0,0 +1,71 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+type ResponseCacheSettings struct {
+	sync.RWMutex
+	Enabled      bool
+	TTL          int     // 默认缓存时间，单位秒
+	BillingRatio float64 // 命中缓存时的计费倍率，0 为不计费
+	ModelTTL     map[string]int
+}
+
+var ResponseCacheSettingsInstance = ResponseCacheSettings{
+	TTL:      3600,
+	ModelTTL: map[string]int{},
+}
+
+func init() {
+	GlobalOption.RegisterBool("ResponseCacheEnabled", &ResponseCacheSettingsInstance.Enabled)
+	GlobalOption.RegisterInt("ResponseCacheTTL", &ResponseCacheSettingsInstance.TTL)
+	GlobalOption.RegisterFloat("ResponseCacheBillingRatio", &ResponseCacheSettingsInstance.BillingRatio)
+	GlobalOption.RegisterCustom("ResponseCacheModelTTL", func() string {
+		return ResponseCacheSettingsInstance.GetModelTTLJSONString()
+	}, func(value string) error {
+		return ResponseCacheSettingsInstance.SetModelTTL(value)
+	}, "")
+}
+
+func (c *ResponseCacheSettings) SetModelTTL(data string) error {
+	modelTTL := map[string]int{}
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &modelTTL); err != nil {
+			return err
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.ModelTTL = modelTTL
+	return nil
+}
+
+func (c *ResponseCacheSettings) GetModelTTLJSONString() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	jsonData, _ := json.Marshal(c.ModelTTL)
+	return string(jsonData)
+}
+
+// GetTTL 获取模型的缓存时间，支持以 * 结尾的前缀匹配，返回 0 表示该模型不缓存
+func (c *ResponseCacheSettings) GetTTL(model string) int {
+	c.RLock()
+	defer c.RUnlock()
+
+	if ttl, ok := c.ModelTTL[model]; ok {
+		return ttl
+	}
+
+	for pattern, ttl := range c.ModelTTL {
+		if strings.HasSuffix(pattern, "*") && strings.HasPrefix(model, strings.TrimSuffix(pattern, "*")) {
+			return ttl
+		}
+	}
+
+	return c.TTL
+}
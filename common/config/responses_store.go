@@ -0,0 +1,22 @@
+package config
+
+type ResponsesStoreSettings struct {
+	Enabled       bool
+	RetentionDays int // 存储保留天数，0 为不清理
+	MaxPerUser    int // 每个用户最多保留的响应数量，0 为不限制
+	MaxChainDepth int // previous_response_id 最多回溯的轮数
+}
+
+var ResponsesStoreSettingsInstance = ResponsesStoreSettings{
+	Enabled:       true,
+	RetentionDays: 30,
+	MaxPerUser:    1000,
+	MaxChainDepth: 100,
+}
+
+func init() {
+	GlobalOption.RegisterBool("ResponsesStoreEnabled", &ResponsesStoreSettingsInstance.Enabled)
+	GlobalOption.RegisterInt("ResponsesStoreRetentionDays", &ResponsesStoreSettingsInstance.RetentionDays)
+	GlobalOption.RegisterInt("ResponsesStoreMaxPerUser", &ResponsesStoreSettingsInstance.MaxPerUser)
+	GlobalOption.RegisterInt("ResponsesStoreMaxChainDepth", &ResponsesStoreSettingsInstance.MaxChainDepth)
+}
@@ -0,0 +1,11 @@
+package config
+
+type TaskBillingSettings struct {
+	PartialBilling bool // 任务部分产出失败时按成功的比例计费
+}
+
+var TaskBillingSettingsInstance = TaskBillingSettings{}
+
+func init() {
+	GlobalOption.RegisterBool("TaskPartialBillingEnabled", &TaskBillingSettingsInstance.PartialBilling)
+}
@@ -0,0 +1,18 @@
+package config
+
+type TaskWebhookSettings struct {
+	MaxRetries int  // 回调失败后的最大重试次数
+	Timeout    int  // 单次回调的超时时间，单位秒
+	AllowLocal bool // 是否允许回调内网地址
+}
+
+var TaskWebhookSettingsInstance = TaskWebhookSettings{
+	MaxRetries: 5,
+	Timeout:    10,
+}
+
+func init() {
+	GlobalOption.RegisterInt("TaskWebhookMaxRetries", &TaskWebhookSettingsInstance.MaxRetries)
+	GlobalOption.RegisterInt("TaskWebhookTimeout", &TaskWebhookSettingsInstance.Timeout)
+	GlobalOption.RegisterBool("TaskWebhookAllowLocal", &TaskWebhookSettingsInstance.AllowLocal)
+}
@@ -0,0 +1,171 @@
+package limit
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/redis"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	concurrencyFormat = "{%s}:concurrency"
+)
+
+var (
+	//go:embed concurrencyscript.lua
+	concurrencyLuaScript string
+	concurrencyScript    = redis.NewScript(concurrencyLuaScript)
+)
+
+// ConcurrencyLimiter 并发数限制，开启 Redis 时在多个节点间共享
+// 每个名额带有过期时间，节点异常退出后未释放的名额会在过期后自动回收
+type ConcurrencyLimiter struct {
+	mutex       sync.Mutex
+	memoryStore map[string]map[string]int64 // key -> 名额标识 -> 过期时间(毫秒)
+}
+
+// ConcurrencySlot 占用的并发名额，用于之后释放
+type ConcurrencySlot struct {
+	Key    string
+	Member string
+}
+
+var GlobalConcurrencyLimiter = NewConcurrencyLimiter()
+
+func NewConcurrencyLimiter() *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		memoryStore: make(map[string]map[string]int64),
+	}
+}
+
+// Acquire 占用一个并发名额，返回 false 表示已达上限，此时不会占用
+func (l *ConcurrencyLimiter) Acquire(keyPrefix string, limit int) (*ConcurrencySlot, bool, error) {
+	key := fmt.Sprintf(concurrencyFormat, keyPrefix)
+	member, err := newSlotMember()
+	if err != nil {
+		return nil, false, err
+	}
+
+	nowMs := time.Now().UnixMilli()
+	expireAt := nowMs + int64(config.ChannelConcurrencySettingsInstance.SlotLease)*1000
+
+	if !config.RedisEnabled {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		if l.countMemory(key, nowMs) >= limit {
+			return nil, false, nil
+		}
+
+		slots, ok := l.memoryStore[key]
+		if !ok {
+			slots = make(map[string]int64)
+			l.memoryStore[key] = slots
+		}
+		slots[member] = expireAt
+
+		return &ConcurrencySlot{Key: key, Member: member}, true, nil
+	}
+
+	result, err := redis.ScriptRunCtx(
+		context.Background(),
+		concurrencyScript,
+		[]string{key},
+		limit,    // ARGV[1]: 并发上限
+		nowMs,    // ARGV[2]: 当前时间戳
+		expireAt, // ARGV[3]: 名额的过期时间
+		member,   // ARGV[4]: 名额标识
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resultArray, ok := result.([]interface{})
+	if !ok || len(resultArray) < 1 {
+		return nil, false, fmt.Errorf("无法转换并发结果")
+	}
+
+	allowed, ok := resultArray[0].(int64)
+	if !ok {
+		return nil, false, fmt.Errorf("无法转换并发结果")
+	}
+	if allowed != 1 {
+		return nil, false, nil
+	}
+
+	return &ConcurrencySlot{Key: key, Member: member}, true, nil
+}
+
+// Release 释放并发名额
+func (l *ConcurrencyLimiter) Release(slot *ConcurrencySlot) error {
+	if slot == nil {
+		return nil
+	}
+
+	if !config.RedisEnabled {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		if slots, ok := l.memoryStore[slot.Key]; ok {
+			delete(slots, slot.Member)
+			if len(slots) == 0 {
+				delete(l.memoryStore, slot.Key)
+			}
+		}
+		return nil
+	}
+
+	return redis.GetRedisClient().ZRem(context.Background(), slot.Key, slot.Member).Err()
+}
+
+// Count 获取当前占用的并发数
+func (l *ConcurrencyLimiter) Count(keyPrefix string) (int, error) {
+	key := fmt.Sprintf(concurrencyFormat, keyPrefix)
+	nowMs := time.Now().UnixMilli()
+
+	if !config.RedisEnabled {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		return l.countMemory(key, nowMs), nil
+	}
+
+	count, err := redis.GetRedisClient().ZCount(context.Background(), key, "("+strconv.FormatInt(nowMs, 10), "+inf").Result()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(count), nil
+}
+
+// countMemory 统计未过期的名额，并清理已过期的名额，调用方需持有锁
+func (l *ConcurrencyLimiter) countMemory(key string, nowMs int64) int {
+	slots, ok := l.memoryStore[key]
+	if !ok {
+		return 0
+	}
+
+	for member, expireAt := range slots {
+		if expireAt <= nowMs {
+			delete(slots, member)
+		}
+	}
+	if len(slots) == 0 {
+		delete(l.memoryStore, key)
+	}
+
+	return len(slots)
+}
+
+func newSlotMember() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,20 @@
+-- KEYS[1] 作为记录并发名额的有序集合key，成员为名额标识，分数为过期时间(毫秒)
+-- ARGV[1] 作为并发上限
+-- ARGV[2] 作为当前时间戳(毫秒)
+-- ARGV[3] 作为名额的过期时间(毫秒)
+-- ARGV[4] 作为名额标识
+
+-- 1. 移除已过期的名额
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[2])
+
+-- 2. 已达上限时不占用
+local count = redis.call('ZCARD', KEYS[1])
+if count >= tonumber(ARGV[1]) then
+  return {0, count}
+end
+
+-- 3. 占用名额，并设置过期时间
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[4])
+redis.call('PEXPIREAT', KEYS[1], ARGV[3])
+
+return {1, count + 1}
@@ -0,0 +1,187 @@
+package limit
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/redis"
+	"sync"
+	"time"
+)
+
+// 滚动窗口计数器，用于预算和 RPM 统计
+// 窗口按桶统计，每个桶为窗口的 1/60，检查和增加在同一个 Lua 脚本中完成
+
+const (
+	PeriodMinute = "minute"
+	PeriodDay    = "day"
+	PeriodWeek   = "week"
+	PeriodMonth  = "month"
+)
+
+var (
+	//go:embed rollingcounter.lua
+	rollingCounterLuaScript string
+	rollingCounterScript    = redis.NewScript(rollingCounterLuaScript)
+)
+
+// RollingCounter 一个滚动窗口计数器
+type RollingCounter struct {
+	Key    string
+	Window time.Duration
+	Limit  int64 // 上限，0 为不限制
+	Value  int64 // 本次增加的数量，可以为负数
+	// 计数所在的桶，为 0 时计入当前桶；增加后记录实际计入的桶
+	// 撤销或修正占用时计入原来的桶，避免负数落在新桶中，原来的桶滚出窗口后窗口内的计数变为负数
+	Bucket int64
+}
+
+type counterData struct {
+	buckets  map[int64]int64
+	expireAt time.Time
+}
+
+var (
+	memoryCounters     = make(map[string]*counterData)
+	memoryCountersLock sync.Mutex
+	lastCounterCleanup time.Time
+)
+
+// PeriodWindow 返回周期对应的滚动窗口大小，月按 30 天计算
+func PeriodWindow(period string) time.Duration {
+	switch period {
+	case PeriodMinute:
+		return time.Minute
+	case PeriodDay:
+		return 24 * time.Hour
+	case PeriodWeek:
+		return 7 * 24 * time.Hour
+	default:
+		return 30 * 24 * time.Hour
+	}
+}
+
+// ReserveCounters 原子地检查所有计数器，全部未超出上限时才增加计数
+// 返回第一个超出上限的计数器下标和它当前的计数，下标为 -1 表示已增加
+func ReserveCounters(counters []*RollingCounter) (int, int64, error) {
+	return runCounters(counters, true)
+}
+
+// AddCounters 增加计数，不检查上限，用于按实际消耗修正或撤销占用
+func AddCounters(counters []*RollingCounter) error {
+	_, _, err := runCounters(counters, false)
+	return err
+}
+
+func runCounters(counters []*RollingCounter, check bool) (int, int64, error) {
+	if len(counters) == 0 {
+		return -1, 0, nil
+	}
+
+	now := time.Now().Unix()
+	if !config.RedisEnabled {
+		index, used := runMemoryCounters(counters, check, now)
+		return index, used, nil
+	}
+
+	keys := make([]string, 0, len(counters))
+	args := []interface{}{now, 0}
+	if check {
+		args[1] = 1
+	}
+	for _, counter := range counters {
+		keys = append(keys, counter.Key)
+		args = append(args, int64(counter.Window.Seconds()), counter.Limit, counter.Value, counter.Bucket)
+	}
+
+	result, err := redis.ScriptRunCtx(context.Background(), rollingCounterScript, keys, args...)
+	if err != nil {
+		return -1, 0, err
+	}
+
+	resultArray, ok := result.([]interface{})
+	if !ok || len(resultArray) < 2 {
+		return -1, 0, fmt.Errorf("无法转换计数结果")
+	}
+	index, _ := resultArray[0].(int64)
+	used, _ := resultArray[1].(int64)
+	if index == 0 {
+		for _, counter := range counters {
+			if counter.Bucket == 0 {
+				_, counter.Bucket, _ = bucketOf(counter.Window, now)
+			}
+		}
+	}
+
+	return int(index) - 1, used, nil
+}
+
+// bucketOf 返回桶的大小、当前所在的桶，以及编号不大于 oldest 的桶已在窗口外
+func bucketOf(window time.Duration, now int64) (size, current, oldest int64) {
+	seconds := int64(window.Seconds())
+	size = max(1, seconds/60)
+	current = now / size
+	oldest = current - (seconds+size-1)/size
+	return
+}
+
+func runMemoryCounters(counters []*RollingCounter, check bool, now int64) (int, int64) {
+	memoryCountersLock.Lock()
+	defer memoryCountersLock.Unlock()
+
+	cleanupMemoryCounters(time.Unix(now, 0))
+
+	currents := make([]int64, len(counters))
+	oldests := make([]int64, len(counters))
+	for i, counter := range counters {
+		size, current, oldest := bucketOf(counter.Window, now)
+		currents[i] = current
+		oldests[i] = oldest
+
+		data, ok := memoryCounters[counter.Key]
+		if !ok {
+			data = &counterData{buckets: make(map[int64]int64)}
+			memoryCounters[counter.Key] = data
+		}
+		data.expireAt = time.Unix(now, 0).Add(counter.Window + time.Duration(size)*time.Second)
+
+		var used int64
+		for bucket, value := range data.buckets {
+			if bucket <= oldest {
+				delete(data.buckets, bucket)
+				continue
+			}
+			used += value
+		}
+
+		if check && counter.Limit > 0 && used+counter.Value > counter.Limit {
+			return i, used
+		}
+	}
+
+	for i, counter := range counters {
+		if counter.Bucket == 0 {
+			counter.Bucket = currents[i]
+		}
+		// 原来的桶已滚出窗口时不需要修正
+		if counter.Value != 0 && counter.Bucket > oldests[i] {
+			memoryCounters[counter.Key].buckets[counter.Bucket] += counter.Value
+		}
+	}
+
+	return -1, 0
+}
+
+func cleanupMemoryCounters(now time.Time) {
+	if now.Sub(lastCounterCleanup) < 5*time.Minute {
+		return
+	}
+	lastCounterCleanup = now
+
+	for key, data := range memoryCounters {
+		if now.After(data.expireAt) {
+			delete(memoryCounters, key)
+		}
+	}
+}
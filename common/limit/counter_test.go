@@ -0,0 +1,197 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollingCounters(t *testing.T) {
+	const start = int64(1700000040)
+
+	type step struct {
+		offset   int64 // 距离开始的秒数
+		value    int64
+		check    bool
+		expected int // 期望超出上限的下标，-1 为成功
+	}
+
+	cases := []struct {
+		name   string
+		window time.Duration
+		limit  int64
+		steps  []step
+	}{
+		{
+			name:   "未超出上限",
+			window: time.Minute,
+			limit:  10,
+			steps: []step{
+				{0, 4, true, -1},
+				{1, 6, true, -1},
+				{2, 1, true, 0},
+			},
+		},
+		{
+			name:   "超出上限时不增加计数",
+			window: time.Minute,
+			limit:  10,
+			steps: []step{
+				{0, 8, true, -1},
+				{1, 5, true, 0},
+				{2, 2, true, -1},
+			},
+		},
+		{
+			name:   "窗口滚动后释放",
+			window: time.Minute,
+			limit:  10,
+			steps: []step{
+				{0, 10, true, -1},
+				{30, 1, true, 0},
+				{60, 1, true, -1},
+			},
+		},
+		{
+			name:   "负数用于撤销占用",
+			window: time.Minute,
+			limit:  10,
+			steps: []step{
+				{0, 10, true, -1},
+				{1, -4, false, -1},
+				{2, 4, true, -1},
+				{3, 1, true, 0},
+			},
+		},
+		{
+			name:   "不检查时可以超出上限",
+			window: time.Minute,
+			limit:  10,
+			steps: []step{
+				{0, 15, false, -1},
+				{1, 1, true, 0},
+			},
+		},
+		{
+			name:   "上限为 0 不限制",
+			window: time.Minute,
+			limit:  0,
+			steps: []step{
+				{0, 1000, true, -1},
+				{1, 1000, true, -1},
+			},
+		},
+		{
+			name:   "按天滚动",
+			window: PeriodWindow(PeriodDay),
+			limit:  100,
+			steps: []step{
+				{0, 100, true, -1},
+				{23 * 3600, 1, true, 0},
+				{24*3600 + 1440, 1, true, -1},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			memoryCounters = make(map[string]*counterData)
+			for i, s := range c.steps {
+				counters := []*RollingCounter{{Key: "test", Window: c.window, Limit: c.limit, Value: s.value}}
+				index, _ := runMemoryCounters(counters, s.check, start+s.offset)
+				assert.Equal(t, s.expected, index, "step %d", i)
+			}
+		})
+	}
+}
+
+func TestRollingCountersAtomic(t *testing.T) {
+	memoryCounters = make(map[string]*counterData)
+	const now = int64(1700000000)
+
+	counters := func(value int64) []*RollingCounter {
+		return []*RollingCounter{
+			{Key: "day", Window: PeriodWindow(PeriodDay), Limit: 100, Value: value},
+			{Key: "rpm", Window: PeriodWindow(PeriodMinute), Limit: 2, Value: 1},
+		}
+	}
+
+	index, _ := runMemoryCounters(counters(60), true, now)
+	assert.Equal(t, -1, index)
+
+	// 第一个计数器超出上限，第二个计数器也不增加
+	index, used := runMemoryCounters(counters(60), true, now)
+	assert.Equal(t, 0, index)
+	assert.Equal(t, int64(60), used)
+
+	index, _ = runMemoryCounters(counters(40), true, now)
+	assert.Equal(t, -1, index)
+
+	index, used = runMemoryCounters(counters(0), true, now)
+	assert.Equal(t, 1, index)
+	assert.Equal(t, int64(2), used)
+}
+
+func TestRollingCountersCorrectReservedBucket(t *testing.T) {
+	const start = int64(1700000040)
+
+	type step struct {
+		offset   int64 // 距离开始的秒数
+		value    int64
+		reserved bool // 修正第一次占用的桶
+		expected int  // 期望超出上限的下标，-1 为成功
+	}
+
+	cases := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "窗口内修正后原来的桶滚出窗口",
+			steps: []step{
+				{0, 10, false, -1},
+				{30, -6, true, -1},
+				// 占用的桶滚出窗口，修正不会留下负数
+				{60, 10, false, -1},
+				{61, 1, false, 0},
+			},
+		},
+		{
+			name: "原来的桶已滚出窗口时不再修正",
+			steps: []step{
+				{0, 10, false, -1},
+				{60, -6, true, -1},
+				{61, 11, false, 0},
+				{62, 10, false, -1},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			memoryCounters = make(map[string]*counterData)
+			var first *RollingCounter
+			for i, s := range c.steps {
+				counter := &RollingCounter{Key: "test", Window: time.Minute, Limit: 10, Value: s.value}
+				if s.reserved {
+					counter.Bucket = first.Bucket
+				}
+
+				index, _ := runMemoryCounters([]*RollingCounter{counter}, !s.reserved, start+s.offset)
+				assert.Equal(t, s.expected, index, "step %d", i)
+				if first == nil {
+					first = counter
+				}
+			}
+		})
+	}
+}
+
+func TestPeriodWindow(t *testing.T) {
+	assert.Equal(t, time.Minute, PeriodWindow(PeriodMinute))
+	assert.Equal(t, 24*time.Hour, PeriodWindow(PeriodDay))
+	assert.Equal(t, 7*24*time.Hour, PeriodWindow(PeriodWeek))
+	assert.Equal(t, 30*24*time.Hour, PeriodWindow(PeriodMonth))
+	assert.Equal(t, 30*24*time.Hour, PeriodWindow(""))
+}
@@ -0,0 +1,184 @@
+package limit
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueEvicted 队列已满时被更高优先级的请求挤出
+var ErrQueueEvicted = errors.New("evicted by higher priority request")
+
+// FairQueue 按优先级和到达顺序排队的等待队列，每个 key 一个队列
+// 只有队首可以尝试获取资源，资源释放时唤醒各队列的队首
+type FairQueue struct {
+	mutex    sync.Mutex
+	queues   map[string]*list.List
+	onChange func(key string, depth int)
+}
+
+// QueueTicket 排队凭证
+type QueueTicket struct {
+	queue    *FairQueue
+	key      string
+	priority int
+	elem     *list.Element
+	notify   chan struct{}
+	evicted  bool
+}
+
+// NewFairQueue onChange 在队列长度变化时调用，可用于上报监控
+func NewFairQueue(onChange func(key string, depth int)) *FairQueue {
+	return &FairQueue{
+		queues:   make(map[string]*list.List),
+		onChange: onChange,
+	}
+}
+
+// Len 获取排队数
+func (q *FairQueue) Len(key string) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if l, ok := q.queues[key]; ok {
+		return l.Len()
+	}
+	return 0
+}
+
+// Enter 排到同优先级请求的后面，priority 越小越优先
+// 队列已满时挤出排在最后的更低优先级请求，没有可挤出的请求时返回 false
+func (q *FairQueue) Enter(key string, priority int, maxSize int) (*QueueTicket, bool) {
+	q.mutex.Lock()
+	l, ok := q.queues[key]
+	if !ok {
+		l = list.New()
+		q.queues[key] = l
+	}
+	if l.Len() >= maxSize {
+		back := l.Back()
+		if back == nil || back.Value.(*QueueTicket).priority <= priority {
+			if l.Len() == 0 {
+				delete(q.queues, key)
+			}
+			q.mutex.Unlock()
+			return nil, false
+		}
+
+		evicted := back.Value.(*QueueTicket)
+		l.Remove(back)
+		evicted.elem = nil
+		evicted.evicted = true
+		evicted.wake()
+	}
+
+	ticket := &QueueTicket{
+		queue:    q,
+		key:      key,
+		priority: priority,
+		notify:   make(chan struct{}, 1),
+	}
+
+	mark := l.Back()
+	for mark != nil && mark.Value.(*QueueTicket).priority > priority {
+		mark = mark.Prev()
+	}
+	if mark == nil {
+		ticket.elem = l.PushFront(ticket)
+	} else {
+		ticket.elem = l.InsertAfter(ticket, mark)
+	}
+	depth := l.Len()
+	q.mutex.Unlock()
+
+	q.changed(key, depth)
+	return ticket, true
+}
+
+// NotifyAll 唤醒所有队列的队首
+func (q *FairQueue) NotifyAll() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for _, l := range q.queues {
+		if front := l.Front(); front != nil {
+			front.Value.(*QueueTicket).wake()
+		}
+	}
+}
+
+func (q *FairQueue) changed(key string, depth int) {
+	if q.onChange != nil {
+		q.onChange(key, depth)
+	}
+}
+
+// IsHead 是否排在队首
+func (t *QueueTicket) IsHead() bool {
+	t.queue.mutex.Lock()
+	defer t.queue.mutex.Unlock()
+
+	l, ok := t.queue.queues[t.key]
+	return ok && t.elem != nil && l.Front() == t.elem
+}
+
+// Wait 等待被唤醒，poll 为没有被唤醒时的最长等待时间
+// 其他节点释放资源时不会唤醒本节点的队列，需要定时重试；被挤出队列时返回 ErrQueueEvicted
+func (t *QueueTicket) Wait(ctx context.Context, poll time.Duration) error {
+	timer := time.NewTimer(poll)
+	defer timer.Stop()
+
+	select {
+	case <-t.notify:
+	case <-timer.C:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if t.Evicted() {
+		return ErrQueueEvicted
+	}
+	return nil
+}
+
+// Evicted 是否已被挤出队列
+func (t *QueueTicket) Evicted() bool {
+	t.queue.mutex.Lock()
+	defer t.queue.mutex.Unlock()
+
+	return t.evicted
+}
+
+// Leave 离开队列，队首离开时唤醒下一个
+func (t *QueueTicket) Leave() {
+	q := t.queue
+	q.mutex.Lock()
+	l, ok := q.queues[t.key]
+	if !ok || t.elem == nil {
+		q.mutex.Unlock()
+		return
+	}
+
+	wasHead := l.Front() == t.elem
+	l.Remove(t.elem)
+	t.elem = nil
+
+	depth := l.Len()
+	if depth == 0 {
+		delete(q.queues, t.key)
+	} else if wasHead {
+		l.Front().Value.(*QueueTicket).wake()
+	}
+	q.mutex.Unlock()
+
+	q.changed(t.key, depth)
+}
+
+func (t *QueueTicket) wake() {
+	select {
+	case t.notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,159 @@
+package limit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// queueOrder 返回队列中各凭证的名称，按排队顺序
+func queueOrder(q *FairQueue, key string, names map[*QueueTicket]string) []string {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	order := make([]string, 0)
+	l, ok := q.queues[key]
+	if !ok {
+		return order
+	}
+	for e := l.Front(); e != nil; e = e.Next() {
+		order = append(order, names[e.Value.(*QueueTicket)])
+	}
+	return order
+}
+
+func TestFairQueueEnter(t *testing.T) {
+	type entry struct {
+		name     string
+		priority int
+		ok       bool
+	}
+
+	cases := []struct {
+		name     string
+		maxSize  int
+		entries  []entry
+		expected []string
+		evicted  []string
+	}{
+		{
+			name:     "同优先级按到达顺序",
+			maxSize:  10,
+			entries:  []entry{{"a", 1, true}, {"b", 1, true}, {"c", 1, true}},
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "高优先级排在低优先级前面",
+			maxSize:  10,
+			entries:  []entry{{"a", 2, true}, {"b", 1, true}, {"c", 0, true}, {"d", 1, true}},
+			expected: []string{"c", "b", "d", "a"},
+		},
+		{
+			name:     "队列已满时挤出最后的低优先级请求",
+			maxSize:  2,
+			entries:  []entry{{"a", 1, true}, {"b", 2, true}, {"c", 0, true}},
+			expected: []string{"c", "a"},
+			evicted:  []string{"b"},
+		},
+		{
+			name:     "队列已满且没有更低优先级时拒绝",
+			maxSize:  2,
+			entries:  []entry{{"a", 1, true}, {"b", 1, true}, {"c", 1, false}, {"d", 2, false}},
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "只挤出排在最后的一个",
+			maxSize:  3,
+			entries:  []entry{{"a", 2, true}, {"b", 2, true}, {"c", 2, true}, {"d", 0, true}, {"e", 1, true}},
+			expected: []string{"d", "e", "a"},
+			evicted:  []string{"c", "b"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			q := NewFairQueue(nil)
+			names := make(map[*QueueTicket]string)
+			tickets := make(map[string]*QueueTicket)
+			for _, e := range c.entries {
+				ticket, ok := q.Enter("test", e.priority, c.maxSize)
+				assert.Equal(t, e.ok, ok, e.name)
+				if ok {
+					names[ticket] = e.name
+					tickets[e.name] = ticket
+				}
+			}
+
+			assert.Equal(t, c.expected, queueOrder(q, "test", names))
+
+			evicted := make([]string, 0)
+			for _, e := range c.entries {
+				if ticket, ok := tickets[e.name]; ok && ticket.Evicted() {
+					evicted = append(evicted, e.name)
+				}
+			}
+			expectedEvicted := c.evicted
+			if expectedEvicted == nil {
+				expectedEvicted = []string{}
+			}
+			assert.ElementsMatch(t, expectedEvicted, evicted)
+		})
+	}
+}
+
+func TestFairQueueEvictedWait(t *testing.T) {
+	q := NewFairQueue(nil)
+	low, _ := q.Enter("test", 2, 1)
+	_, ok := q.Enter("test", 0, 1)
+	assert.True(t, ok)
+
+	assert.ErrorIs(t, low.Wait(context.Background(), time.Second), ErrQueueEvicted)
+	assert.False(t, low.IsHead())
+
+	// 被挤出后离开队列不影响其他请求
+	low.Leave()
+	assert.Equal(t, 1, q.Len("test"))
+}
+
+func TestFairQueueLeave(t *testing.T) {
+	depths := make([]int, 0)
+	q := NewFairQueue(func(_ string, depth int) {
+		depths = append(depths, depth)
+	})
+
+	first, _ := q.Enter("test", 1, 10)
+	second, _ := q.Enter("test", 1, 10)
+	assert.True(t, first.IsHead())
+	assert.False(t, second.IsHead())
+
+	// 队首离开时唤醒下一个
+	first.Leave()
+	assert.True(t, second.IsHead())
+	assert.NoError(t, second.Wait(context.Background(), time.Second))
+
+	second.Leave()
+	assert.Equal(t, 0, q.Len("test"))
+	assert.Equal(t, []int{1, 2, 1, 0}, depths)
+
+	// 重复离开不再触发变化
+	second.Leave()
+	assert.Equal(t, []int{1, 2, 1, 0}, depths)
+}
+
+func TestFairQueueNotifyAll(t *testing.T) {
+	q := NewFairQueue(nil)
+	a, _ := q.Enter("a", 1, 10)
+	b, _ := q.Enter("b", 1, 10)
+	waiting, _ := q.Enter("b", 1, 10)
+
+	q.NotifyAll()
+	assert.NoError(t, a.Wait(context.Background(), time.Second))
+	assert.NoError(t, b.Wait(context.Background(), time.Second))
+
+	// 不是队首的请求只能等到超时
+	start := time.Now()
+	assert.NoError(t, waiting.Wait(context.Background(), 50*time.Millisecond))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
@@ -0,0 +1,47 @@
+-- KEYS 作为各计数器的 hash key，field 为桶编号，value 为桶内的计数
+-- ARGV[1] 作为当前时间戳(秒)
+-- ARGV[2] 作为是否检查上限(1 检查，0 只增加)
+-- 之后每个计数器依次占用 4 个参数：窗口大小(秒)、上限(0 为不限制)、增加的数量、计入的桶(0 为当前桶)
+
+local now = tonumber(ARGV[1])
+local check = tonumber(ARGV[2]) == 1
+local currents = {}
+
+-- 1. 清理窗口外的桶，统计窗口内的计数并检查上限
+for i, key in ipairs(KEYS) do
+  local window = tonumber(ARGV[i * 4 - 1])
+  local limit = tonumber(ARGV[i * 4])
+  local value = tonumber(ARGV[i * 4 + 1])
+  local bucket = tonumber(ARGV[i * 4 + 2])
+  local size = math.max(1, math.floor(window / 60))
+  local current = math.floor(now / size)
+  local oldest = current - math.ceil(window / size)
+  if bucket == 0 then
+    bucket = current
+  end
+  currents[i] = {bucket, window + size, value, oldest}
+
+  local used = 0
+  local data = redis.call('HGETALL', key)
+  for j = 1, #data, 2 do
+    if tonumber(data[j]) <= oldest then
+      redis.call('HDEL', key, data[j])
+    else
+      used = used + tonumber(data[j + 1])
+    end
+  end
+
+  if check and limit > 0 and used + value > limit then
+    return {i, used}
+  end
+end
+
+-- 2. 全部未超出上限时，在指定的桶中增加计数，并设置过期时间；撤销或修正时原来的桶已滚出窗口则跳过
+for i, key in ipairs(KEYS) do
+  if currents[i][3] ~= 0 and currents[i][1] > currents[i][4] then
+    redis.call('HINCRBY', key, currents[i][1], currents[i][3])
+    redis.call('EXPIRE', key, currents[i][2])
+  end
+end
+
+return {0, 0}
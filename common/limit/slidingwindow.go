@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"one-api/common/config"
 	"one-api/common/redis"
+	"one-api/common/utils"
+	"sync"
 	"time"
+
+	goredis "github.com/redis/go-redis/v9"
 )
 
 const (
@@ -28,14 +32,34 @@ type SlidingWindowLimiter struct {
 	rate   int           // 最大请求速率
 	rpm    int           // 系统设置的RPM阈值
 	window time.Duration // 窗口大小
+
+	// 未启用 Redis 时按数量记录的占用
+	mutex       sync.Mutex
+	memoryStore map[string]map[string]slidingWindowEntry
+}
+
+type slidingWindowEntry struct {
+	at    int64
+	value int
+}
+
+// SlidingWindowReservation 按数量占用的记录，用于之后按实际用量修正或释放
+type SlidingWindowReservation struct {
+	key    string
+	member string
+	value  int
 }
 
+// GlobalTPMLimiter 按 token 数统计的一分钟滑动窗口
+var GlobalTPMLimiter = NewSlidingWindowLimiter(0, 0, time.Minute)
+
 // NewSlidingWindowLimiter 创建新的滑动窗口限流器
 func NewSlidingWindowLimiter(rate int, rpm int, window time.Duration) *SlidingWindowLimiter {
 	return &SlidingWindowLimiter{
-		rate:   rate,
-		rpm:    rpm,
-		window: window,
+		rate:        rate,
+		rpm:         rpm,
+		window:      window,
+		memoryStore: make(map[string]map[string]slidingWindowEntry),
 	}
 }
 
@@ -113,3 +137,170 @@ func (l *SlidingWindowLimiter) reserveN(ctx context.Context, keyPrefix string, n
 
 	return allowed == 1
 }
+
+// Reserve 按数量占用，例如请求预估的 token 数，limit 为 0 时只记录不限制
+// 返回 false 表示超过上限，此时不会占用
+func (l *SlidingWindowLimiter) Reserve(keyPrefix string, limit int, n int) (*SlidingWindowReservation, bool, error) {
+	reservation := &SlidingWindowReservation{
+		key:    fmt.Sprintf(slidingWindowFormat, keyPrefix),
+		member: utils.GetRandomString(16),
+		value:  n,
+	}
+	nowSec := time.Now().Unix()
+
+	if !config.RedisEnabled {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		entries := l.memoryEntries(reservation.key, nowSec)
+		if limit > 0 && sumEntries(entries)+n > limit {
+			return nil, false, nil
+		}
+		entries[reservation.member] = slidingWindowEntry{at: nowSec, value: n}
+		l.memoryStore[reservation.key] = entries
+		return reservation, true, nil
+	}
+
+	result, err := redis.ScriptRunCtx(
+		context.Background(),
+		slidingWindowScript,
+		[]string{reservation.key},
+		limit,                   // ARGV[1]: 数量上限
+		int(l.window.Seconds()), // ARGV[2]: 窗口大小（秒）
+		nowSec,                  // ARGV[3]: 当前时间戳
+		n,                       // ARGV[4]: 占用的数量
+		reservation.member,      // ARGV[5]: 占用标识
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resultArray, ok := result.([]interface{})
+	if !ok || len(resultArray) < 1 {
+		return nil, false, fmt.Errorf("无法转换占用结果")
+	}
+
+	allowed, ok := resultArray[0].(int64)
+	if !ok {
+		return nil, false, fmt.Errorf("无法转换占用结果")
+	}
+	if allowed != 1 {
+		return nil, false, nil
+	}
+
+	return reservation, true, nil
+}
+
+// Adjust 按实际用量修正占用，实际用量记录在当前时间，actual 为 0 时相当于释放
+func (l *SlidingWindowLimiter) Adjust(reservation *SlidingWindowReservation, actual int) error {
+	if reservation == nil {
+		return nil
+	}
+
+	oldMember := fmt.Sprintf("%s:%d", reservation.member, reservation.value)
+	reservation.value = actual
+	nowSec := time.Now().Unix()
+
+	if !config.RedisEnabled {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		entries := l.memoryEntries(reservation.key, nowSec)
+		if actual == 0 {
+			delete(entries, reservation.member)
+		} else {
+			entries[reservation.member] = slidingWindowEntry{at: nowSec, value: actual}
+			l.memoryStore[reservation.key] = entries
+		}
+		return nil
+	}
+
+	ctx := context.Background()
+	pipe := redis.GetRedisClient().TxPipeline()
+	pipe.ZRem(ctx, reservation.key, oldMember)
+	if actual != 0 {
+		pipe.ZAdd(ctx, reservation.key, goredis.Z{
+			Score:  float64(nowSec),
+			Member: fmt.Sprintf("%s:%d", reservation.member, actual),
+		})
+		pipe.Expire(ctx, reservation.key, l.window*2)
+	}
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+// Release 释放占用
+func (l *SlidingWindowLimiter) Release(reservation *SlidingWindowReservation) error {
+	return l.Adjust(reservation, 0)
+}
+
+// Record 直接记录 n 的用量，不检查上限
+func (l *SlidingWindowLimiter) Record(keyPrefix string, n int) error {
+	_, _, err := l.Reserve(keyPrefix, 0, n)
+	return err
+}
+
+// GetCurrentUsage 获取窗口内按数量记录的用量
+func (l *SlidingWindowLimiter) GetCurrentUsage(keyPrefix string) (int, error) {
+	key := fmt.Sprintf(slidingWindowFormat, keyPrefix)
+	nowSec := time.Now().Unix()
+
+	if !config.RedisEnabled {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		return sumEntries(l.memoryEntries(key, nowSec)), nil
+	}
+
+	result, err := redis.ScriptRunCtx(
+		context.Background(),
+		slidingWindowGetScript,
+		[]string{key},
+		int(l.window.Seconds()), // ARGV[1]: 窗口大小（秒）
+		nowSec,                  // ARGV[2]: 当前时间戳
+		1,                       // ARGV[3]: 按数量统计
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	if result == nil {
+		return 0, nil
+	}
+
+	used, ok := result.(int64)
+	if !ok {
+		return 0, fmt.Errorf("无法转换用量结果")
+	}
+
+	return int(used), nil
+}
+
+// memoryEntries 返回 key 在窗口内的占用，并清理窗口外的记录，调用方需持有锁
+// 没有占用的 key 会被移除，新增占用后需要重新写回 memoryStore
+func (l *SlidingWindowLimiter) memoryEntries(key string, nowSec int64) map[string]slidingWindowEntry {
+	entries, ok := l.memoryStore[key]
+	if !ok {
+		return make(map[string]slidingWindowEntry)
+	}
+
+	windowStart := nowSec - int64(l.window.Seconds())
+	for member, entry := range entries {
+		if entry.at <= windowStart {
+			delete(entries, member)
+		}
+	}
+	if len(entries) == 0 {
+		delete(l.memoryStore, key)
+	}
+
+	return entries
+}
+
+func sumEntries(entries map[string]slidingWindowEntry) int {
+	used := 0
+	for _, entry := range entries {
+		used += entry.value
+	}
+	return used
+}
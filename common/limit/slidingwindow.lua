@@ -3,11 +3,31 @@
 -- ARGV[2] 作为窗口大小(秒)
 -- ARGV[3] 作为当前时间戳(秒)
 -- ARGV[4] 作为增加的数量
+-- ARGV[5] 作为占用标识(可选)，传入时按数量记录为一个 "标识:数量" 的成员，窗口内的数量之和作为用量
+--         用于按 token 数限流，超出 rate 时不占用，rate 为 0 时只记录
 
 -- 1. 移除窗口外的过期时间戳
 local windowStart = tonumber(ARGV[3]) - tonumber(ARGV[2])
 redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', windowStart)
 
+-- 按数量记录时，统计窗口内的用量，超出限制时不占用
+if ARGV[5] then
+  local used = 0
+  for _, member in ipairs(redis.call('ZRANGE', KEYS[1], 0, -1)) do
+    used = used + (tonumber(string.match(member, ':(%-?%d+)$')) or 0)
+  end
+
+  local rate = tonumber(ARGV[1])
+  local n = tonumber(ARGV[4])
+  if rate > 0 and used + n > rate then
+    return {0, used}
+  end
+
+  redis.call('ZADD', KEYS[1], ARGV[3], ARGV[5] .. ':' .. ARGV[4])
+  redis.call('EXPIRE', KEYS[1], tonumber(ARGV[2]) * 2)
+  return {1, used + n}
+end
+
 -- 2. 添加当前请求的时间戳(可以添加多个相同的时间戳来表示多个请求)
 for i=1,tonumber(ARGV[4]) do
   redis.call('ZADD', KEYS[1], ARGV[3], ARGV[3] .. ":" .. i .. ":" .. redis.call('TIME')[1])
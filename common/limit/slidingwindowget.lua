@@ -1,11 +1,21 @@
 -- KEYS[1] 作为存储请求时间戳的有序集合key
 -- ARGV[1] 作为窗口大小(秒)
 -- ARGV[2] 作为当前时间戳(秒)
+-- ARGV[3] 作为是否按数量统计(可选)，传入时返回窗口内成员记录的数量之和
 
 -- 1. 移除窗口外的过期时间戳
 local windowStart = tonumber(ARGV[2]) - tonumber(ARGV[1])
 redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', windowStart)
 
+-- 按数量记录时，返回窗口内的用量
+if ARGV[3] then
+  local used = 0
+  for _, member in ipairs(redis.call('ZRANGE', KEYS[1], 0, -1)) do
+    used = used + (tonumber(string.match(member, ':(%-?%d+)$')) or 0)
+  end
+  return used
+end
+
 -- 2. 获取窗口内的请求数量
 local count = redis.call('ZCARD', KEYS[1])
 
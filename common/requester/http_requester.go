@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"one-api/common"
 	"one-api/common/utils"
+	"one-api/tracing"
 	"one-api/types"
 	"strconv"
 	"strings"
@@ -26,6 +27,8 @@ type HTTPRequester struct {
 	proxyAddr         string
 	Context           context.Context
 	IsOpenAI          bool
+	// TraceContext 上游请求 span 的父级上下文，只用于链路追踪，不影响请求的取消
+	TraceContext context.Context
 }
 
 // NewHTTPRequester 创建一个新的 HTTPRequester 实例。
@@ -72,9 +75,23 @@ func (r *HTTPRequester) NewRequest(method, url string, setters ...requestOption)
 	return req, nil
 }
 
+// 发送请求并记录上游请求的 span
+func (r *HTTPRequester) do(req *http.Request) (*http.Response, error) {
+	traceCtx := req.Context()
+	if r.TraceContext != nil {
+		traceCtx = r.TraceContext
+	}
+
+	span := tracing.StartUpstream(traceCtx, req)
+	resp, err := HTTPClient.Do(req)
+	tracing.EndUpstream(span, resp, err)
+
+	return resp, err
+}
+
 // 发送请求
 func (r *HTTPRequester) SendRequest(req *http.Request, response any, outputResp bool) (*http.Response, *types.OpenAIErrorWithStatusCode) {
-	resp, err := HTTPClient.Do(req)
+	resp, err := r.do(req)
 	if err != nil {
 		return nil, common.ErrorWrapper(err, "http_request_failed", http.StatusInternalServerError)
 	}
@@ -114,7 +131,7 @@ func (r *HTTPRequester) SendRequest(req *http.Request, response any, outputResp
 // 发送请求 RAW
 func (r *HTTPRequester) SendRequestRaw(req *http.Request) (*http.Response, *types.OpenAIErrorWithStatusCode) {
 	// 发送请求
-	resp, err := HTTPClient.Do(req)
+	resp, err := r.do(req)
 	if err != nil {
 		return nil, common.ErrorWrapper(err, "http_request_failed", http.StatusInternalServerError)
 	}
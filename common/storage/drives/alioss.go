@@ -3,6 +3,8 @@ package drives
 import (
 	"bytes"
 	"fmt"
+	"strings"
+
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 )
 
@@ -54,3 +56,43 @@ func (a *AliOSSUpload) Upload(data []byte, fileName string) (string, error) {
 
 	return objectURL, nil
 }
+
+// Save 按指定的 key 长期保存文件，返回的是公共读地址，需要存储桶允许公共读
+func (a *AliOSSUpload) Save(key string, data []byte) (string, error) {
+	bucket, err := a.getBucket()
+	if err != nil {
+		return "", err
+	}
+
+	if err = bucket.PutObject(key, bytes.NewReader(data)); err != nil {
+		return "", fmt.Errorf("uploading file: %w", err)
+	}
+
+	host := strings.TrimPrefix(strings.TrimPrefix(a.Endpoint, "https://"), "http://")
+	return fmt.Sprintf("https://%s.%s/%s", a.BucketName, strings.TrimSuffix(host, "/"), key), nil
+}
+
+func (a *AliOSSUpload) Delete(key string) error {
+	bucket, err := a.getBucket()
+	if err != nil {
+		return err
+	}
+
+	if err = bucket.DeleteObject(key); err != nil {
+		return fmt.Errorf("deleting file: %w", err)
+	}
+	return nil
+}
+
+func (a *AliOSSUpload) getBucket() (*oss.Bucket, error) {
+	client, err := oss.New(a.Endpoint, a.AccessKeyId, a.AccessKeySecret)
+	if err != nil {
+		return nil, fmt.Errorf("creating OSS client: %w", err)
+	}
+
+	bucket, err := client.Bucket(a.BucketName)
+	if err != nil {
+		return nil, fmt.Errorf("getting bucket: %w", err)
+	}
+	return bucket, nil
+}
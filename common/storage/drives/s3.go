@@ -3,6 +3,7 @@ package drives
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -42,22 +43,11 @@ func (a *S3Upload) Name() string {
 func (a *S3Upload) Upload(data []byte, s3Key string) (string, error) {
 
 	// 创建 S3 会话
-	sess, err := session.NewSession(&aws.Config{
-		Credentials: credentials.NewStaticCredentials(
-			a.AccessKeyId,
-			a.AccessKeySecret,
-			"",
-		),
-		Endpoint:         aws.String(a.EndPoint),
-		Region:           aws.String("auto"),
-		S3ForcePathStyle: aws.Bool(true),
-	})
+	svc, err := a.newClient()
 	if err != nil {
-		return "", fmt.Errorf("failed to create session: %v", err)
+		return "", err
 	}
 
-	svc := s3.New(sess)
-
 	// 获取当前日期作为文件名前缀
 	now := time.Now()
 	datePrefix := fmt.Sprintf("%d-%02d-%02d/", now.Year(), now.Month(), now.Day())
@@ -100,3 +90,88 @@ func (a *S3Upload) Upload(data []byte, s3Key string) (string, error) {
 
 	return fmt.Sprintf("%s/%s", a.CustomDomain, datedKey), nil
 }
+
+func (a *S3Upload) newClient() (*s3.S3, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(
+			a.AccessKeyId,
+			a.AccessKeySecret,
+			"",
+		),
+		Endpoint:         aws.String(a.EndPoint),
+		Region:           aws.String("auto"),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+
+	return s3.New(sess), nil
+}
+
+// PutObject 按指定的 key 保存文件，不返回访问地址，用于不公开的内容
+func (a *S3Upload) PutObject(key string, data []byte) error {
+	svc, err := a.newClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(a.BucketName),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload file to S3: %v", err)
+	}
+
+	return nil
+}
+
+func (a *S3Upload) GetObject(key string) ([]byte, error) {
+	svc, err := a.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(a.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file from S3: %v", err)
+	}
+	defer output.Body.Close()
+
+	return io.ReadAll(output.Body)
+}
+
+func (a *S3Upload) DeleteObject(key string) error {
+	svc, err := a.newClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(a.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete file from S3: %v", err)
+	}
+
+	return nil
+}
+
+// Save 按指定的 key 长期保存文件，不设置过期时间，返回访问地址
+func (a *S3Upload) Save(key string, data []byte) (string, error) {
+	if err := a.PutObject(key, data); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/%s", a.CustomDomain, key), nil
+}
+
+func (a *S3Upload) Delete(key string) error {
+	return a.DeleteObject(key)
+}
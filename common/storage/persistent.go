@@ -0,0 +1,27 @@
+package storage
+
+// PersistentDrive 可以长期保存文件的存储，用于转存上游会过期的文件
+type PersistentDrive interface {
+	StorageDrive
+	Save(key string, data []byte) (string, error)
+	Delete(key string) error
+}
+
+// 未指定存储时按此顺序选择
+var persistentDriveNames = []string{"S3", "AliOSS"}
+
+// GetPersistentDrive 获取指定名称的长期存储，名称为空时按默认顺序选择，未配置时返回 nil
+func GetPersistentDrive(name string) PersistentDrive {
+	names := persistentDriveNames
+	if name != "" {
+		names = []string{name}
+	}
+
+	for _, driveName := range names {
+		if drive, ok := storageDrives.drives[driveName].(PersistentDrive); ok {
+			return drive
+		}
+	}
+
+	return nil
+}
@@ -10,6 +10,13 @@ type Storage struct {
 	drives map[string]StorageDrive
 }
 
+var s3Drive *drives.S3Upload
+
+// GetS3Drive 获取配置的 S3 存储，未配置时返回 nil
+func GetS3Drive() *drives.S3Upload {
+	return s3Drive
+}
+
 func InitStorage() {
 	InitImgurStorage()
 	InitSMStorage()
@@ -85,5 +92,6 @@ func InitS3Storage() {
 	expirationDays := viper.GetInt("storage.s3.expirationDays")
 
 	s3Upload := drives.NewS3Upload(endpoint, accessKeyId, accessKeySecret, bucketName, cdnurl, expirationDays)
+	s3Drive = s3Upload
 	AddStorageDrive(s3Upload)
 }
@@ -24,6 +24,10 @@ func GetChannelsList(c *gin.Context) {
 		common.APIRespondWithError(c, http.StatusOK, err)
 		return
 	}
+
+	for _, channel := range *channels.Data {
+		channel.CircuitBreakers = model.CircuitBreakers.GetByChannel(channel.Id)
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -31,6 +35,37 @@ func GetChannelsList(c *gin.Context) {
 	})
 }
 
+func GetChannelsStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    model.ChannelStats.GetAll(),
+	})
+}
+
+func GetCircuitBreakers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    model.CircuitBreakers.GetAll(),
+	})
+}
+
+func ResetChannelCircuitBreaker(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	model.CircuitBreakers.Reset(id)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
 func GetChannel(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -0,0 +1,291 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/controller/check_channel"
+	"one-api/model"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetChannelProbes(c *gin.Context) {
+	var params model.PaginationParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	channelId, _ := strconv.Atoi(c.Query("channel_id"))
+	probes, err := model.GetChannelProbesList(channelId, &params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    probes,
+	})
+}
+
+func validateChannelProbe(probe *model.ChannelProbe) error {
+	if probe.ChannelId <= 0 {
+		return errors.New("渠道不能为空")
+	}
+	if _, err := model.GetChannelById(probe.ChannelId); err != nil {
+		return errors.New("渠道不存在")
+	}
+	if strings.TrimSpace(probe.Models) == "" {
+		return errors.New("模型不能为空")
+	}
+	if probe.Interval < 1 {
+		return errors.New("执行间隔不能小于1分钟")
+	}
+
+	processes := strings.Split(probe.Processes, ",")
+	for _, process := range processes {
+		if !check_channel.IsValidProbeProcess(strings.TrimSpace(process)) {
+			return errors.New("无效的检测项: " + process)
+		}
+	}
+
+	return nil
+}
+
+func syncChannelProbeJobs() {
+	if config.IsMasterNode {
+		go check_channel.SyncProbeJobs()
+	}
+}
+
+func AddChannelProbe(c *gin.Context) {
+	probe := model.ChannelProbe{}
+	if err := c.ShouldBindJSON(&probe); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := validateChannelProbe(&probe); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := probe.Create(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	syncChannelProbeJobs()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    probe,
+	})
+}
+
+func UpdateChannelProbe(c *gin.Context) {
+	probe := model.ChannelProbe{}
+	if err := c.ShouldBindJSON(&probe); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := validateChannelProbe(&probe); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := probe.Update(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	syncChannelProbeJobs()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func DeleteChannelProbe(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	probe, err := model.GetChannelProbeById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := probe.Delete(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	syncChannelProbeJobs()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// RunChannelProbe 立即执行一次检测，结果写入检测记录
+func RunChannelProbe(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	if _, err := model.GetChannelProbeById(id); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	go check_channel.RunProbeById(id)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已开始检测",
+	})
+}
+
+func GetChannelProbeHistory(c *gin.Context) {
+	var params model.SearchChannelProbeHistoryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	histories, err := model.GetChannelProbeHistoryList(&params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    histories,
+	})
+}
+
+func GetChannelProbeTrend(c *gin.Context) {
+	channelId, _ := strconv.Atoi(c.Query("channel_id"))
+	if channelId <= 0 {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("渠道不能为空"))
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if days <= 0 || days > 90 {
+		days = 7
+	}
+
+	trends, err := model.GetChannelProbeTrend(channelId, c.Query("model"), days, check_channel.CheckStatusSuccess)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    trends,
+	})
+}
+
+type channelCapabilityRequest struct {
+	ChannelId  int    `json:"channel_id" binding:"required"`
+	Model      string `json:"model" binding:"required"`
+	Capability string `json:"capability" binding:"required"`
+	Disabled   bool   `json:"disabled"`
+}
+
+// UpdateChannelCapability 手动禁用或恢复渠道中某个模型的单项能力
+func UpdateChannelCapability(c *gin.Context) {
+	var params channelCapabilityRequest
+	if err := c.ShouldBindJSON(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	switch params.Capability {
+	case model.ChannelCapabilityTool, model.ChannelCapabilityJSON, model.ChannelCapabilityVision:
+	default:
+		common.APIRespondWithError(c, http.StatusOK, errors.New("无效的能力: "+params.Capability))
+		return
+	}
+
+	if _, err := model.SetChannelCapabilityDisabled(params.ChannelId, params.Model, params.Capability, params.Disabled); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func GetChannelFingerprints(c *gin.Context) {
+	channelId, _ := strconv.Atoi(c.Query("channel_id"))
+	fingerprints, err := model.GetChannelFingerprints(channelId)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    fingerprints,
+	})
+}
+
+// RunChannelFingerprint 对渠道的模型执行指纹检测，返回各模型的置信度
+func RunChannelFingerprint(c *gin.Context) {
+	var params checkChannelRequest
+	if err := c.ShouldBindJSON(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if strings.TrimSpace(params.Models) == "" {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("模型不能为空"))
+		return
+	}
+
+	fingerprints, err := check_channel.RunFingerprint(params.ID, params.Models)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    fingerprints,
+	})
+}
+
+// DeleteChannelFingerprint 清除指纹检测分数，渠道重新参与对应模型的分配
+func DeleteChannelFingerprint(c *gin.Context) {
+	channelId, _ := strconv.Atoi(c.Query("channel_id"))
+	if channelId <= 0 {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("渠道不能为空"))
+		return
+	}
+
+	if err := model.DeleteChannelFingerprint(channelId, c.Query("model")); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	model.ChannelGroup.Load()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
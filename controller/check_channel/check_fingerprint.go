@@ -0,0 +1,401 @@
+package check_channel
+
+import (
+	"fmt"
+	"math"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/types"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 指纹检测：判断渠道返回的是否为声称的模型
+// 通过 token 计数、logprobs、知识截止时间、自我识别、推理 token 等特征综合打分
+
+type fingerprintFamily struct {
+	Name     string
+	Prefixes []string
+	Keywords []string // 自我识别时应出现的关键字
+}
+
+var fingerprintFamilies = []*fingerprintFamily{
+	{Name: "openai", Prefixes: []string{"gpt-", "chatgpt-", "o1", "o3", "o4"}, Keywords: []string{"openai", "gpt"}},
+	{Name: "anthropic", Prefixes: []string{"claude-"}, Keywords: []string{"anthropic", "claude"}},
+	{Name: "google", Prefixes: []string{"gemini-", "gemma-"}, Keywords: []string{"google", "gemini", "gemma", "deepmind"}},
+	{Name: "deepseek", Prefixes: []string{"deepseek-"}, Keywords: []string{"deepseek", "深度求索"}},
+	{Name: "qwen", Prefixes: []string{"qwen", "qwq"}, Keywords: []string{"qwen", "alibaba", "tongyi", "通义", "阿里"}},
+	{Name: "moonshot", Prefixes: []string{"moonshot-", "kimi-"}, Keywords: []string{"moonshot", "kimi", "月之暗面"}},
+	{Name: "zhipu", Prefixes: []string{"glm-", "chatglm"}, Keywords: []string{"zhipu", "glm", "智谱"}},
+	{Name: "meta", Prefixes: []string{"llama", "meta-llama"}, Keywords: []string{"meta", "llama"}},
+	{Name: "mistral", Prefixes: []string{"mistral-", "mixtral-", "codestral-"}, Keywords: []string{"mistral"}},
+}
+
+// 官方公布的知识截止时间，模型自述明显早于该时间时，可能是旧模型冒充
+var fingerprintCutoffs = map[string]string{
+	"gpt-3.5":           "2021-09",
+	"gpt-4":             "2021-09",
+	"gpt-4-turbo":       "2023-12",
+	"gpt-4o":            "2023-10",
+	"chatgpt-4o":        "2023-10",
+	"gpt-4.1":           "2024-06",
+	"gpt-4.5":           "2023-10",
+	"gpt-5":             "2024-09",
+	"o1":                "2023-10",
+	"o3":                "2024-06",
+	"o3-mini":           "2023-10",
+	"o4-mini":           "2024-06",
+	"claude-3-":         "2023-08",
+	"claude-3-5":        "2024-04",
+	"claude-3-7":        "2024-10",
+	"claude-sonnet-4":   "2025-01",
+	"claude-opus-4":     "2025-01",
+	"gemini-1.5":        "2023-11",
+	"gemini-2.0":        "2024-06",
+	"gemini-2.5":        "2025-01",
+	"deepseek-chat":     "2024-07",
+	"deepseek-reasoner": "2024-07",
+}
+
+// 允许模型自述的截止时间比官方早的月数
+const fingerprintCutoffToleranceMonths = 6
+
+var (
+	fingerprintYearMonthPattern = regexp.MustCompile(`(20\d{2})\s*[-/.年]?\s*(\d{1,2})?`)
+	fingerprintPromptText       = "请把下面这段文字原样记在心里，不需要复述：The quick brown fox jumps over the lazy dog. 敏捷的棕色狐狸跳过了懒狗。🦊🐶 func main() { fmt.Println(\"hello, 世界\") } 1234567890 ①②③ Ünïcödé naïve café.\n\n"
+)
+
+func getFingerprintFamily(modelName string) *fingerprintFamily {
+	modelName = strings.ToLower(modelName)
+	for _, family := range fingerprintFamilies {
+		for _, prefix := range family.Prefixes {
+			if strings.HasPrefix(modelName, prefix) {
+				return family
+			}
+		}
+	}
+
+	return nil
+}
+
+func isFingerprintReasoningModel(modelName string) bool {
+	modelName = strings.ToLower(modelName)
+	if strings.HasPrefix(modelName, "gpt-5") {
+		return !strings.Contains(modelName, "-chat")
+	}
+
+	for _, prefix := range []string{"o1", "o3", "o4", "deepseek-reasoner", "deepseek-r1", "qwq"} {
+		if strings.HasPrefix(modelName, prefix) {
+			return true
+		}
+	}
+
+	return strings.Contains(modelName, "-thinking") || strings.Contains(modelName, "reasoner")
+}
+
+// getFingerprintCutoff 按最长前缀匹配官方知识截止时间
+func getFingerprintCutoff(modelName string) string {
+	modelName = strings.ToLower(modelName)
+	matched := ""
+	for prefix := range fingerprintCutoffs {
+		if strings.HasPrefix(modelName, prefix) && len(prefix) > len(matched) {
+			matched = prefix
+		}
+	}
+	if matched == "" {
+		return ""
+	}
+
+	return fingerprintCutoffs[matched]
+}
+
+// getFingerprintTokenModel 本地计算 token 时使用的模型，新模型都使用 o200k 编码
+func getFingerprintTokenModel(modelName string) string {
+	if strings.HasPrefix(modelName, "gpt-3.5") {
+		return modelName
+	}
+	if strings.HasPrefix(modelName, "gpt-4") && !strings.HasPrefix(modelName, "gpt-4o") && !strings.HasPrefix(modelName, "gpt-4.") {
+		return modelName
+	}
+
+	return "gpt-4o"
+}
+
+func fingerprintResponseFailed(openaiErr *types.OpenAIError, resp *types.ChatCompletionResponse) *CheckResult {
+	// 请求失败属于可用性问题，不作为模型被替换的依据
+	if openaiErr != nil {
+		return &CheckResult{Name: "响应", Status: CheckStatusUnknown, Remark: openaiErr.Message}
+	}
+	if resp == nil || len(resp.Choices) == 0 {
+		return &CheckResult{Name: "响应", Status: CheckStatusUnknown, Remark: "获取响应数据失败"}
+	}
+
+	return nil
+}
+
+type CheckFingerprintIdentityProcess struct {
+	ModelName string
+}
+
+func CreateCheckFingerprintIdentityProcess(modelName string) *CheckFingerprintIdentityProcess {
+	return &CheckFingerprintIdentityProcess{
+		ModelName: modelName,
+	}
+}
+
+func (c *CheckFingerprintIdentityProcess) GetName() string {
+	return "指纹-身份检测"
+}
+
+func (c *CheckFingerprintIdentityProcess) GetRequest() *types.ChatCompletionRequest {
+	req := &types.ChatCompletionRequest{
+		Model: c.ModelName,
+		Messages: []types.ChatCompletionMessage{
+			{
+				Role:    types.ChatMessageRoleUser,
+				Content: fingerprintPromptText + "Which AI model are you, and which company developed you? Answer in one short English sentence.",
+			},
+		},
+	}
+
+	if !isFingerprintReasoningModel(c.ModelName) {
+		req.MaxTokens = 64
+		family := getFingerprintFamily(c.ModelName)
+		if family != nil && family.Name == "openai" {
+			logProbs := true
+			req.LogProbs = &logProbs
+			req.TopLogProbs = 2
+		}
+	}
+
+	return req
+}
+
+func (c *CheckFingerprintIdentityProcess) Check(req *types.ChatCompletionRequest, resp *types.ChatCompletionResponse, openaiErr *types.OpenAIError) []*CheckResult {
+	if failed := fingerprintResponseFailed(openaiErr, resp); failed != nil {
+		return []*CheckResult{failed}
+	}
+
+	family := getFingerprintFamily(c.ModelName)
+	checkResults := make([]*CheckResult, 0)
+
+	if family != nil && family.Name == "openai" {
+		checkResults = append(checkResults, c.checkTokenCount(req, resp))
+		if req.LogProbs != nil {
+			checkResults = append(checkResults, c.checkLogProbs(resp))
+		}
+	}
+
+	checkResults = append(checkResults, c.checkIdentity(family, resp.Choices[0].Message.StringContent()))
+
+	return checkResults
+}
+
+// checkTokenCount 上游返回的 prompt_tokens 应与本地 tiktoken 计算结果一致，不同的分词器差异会很明显
+func (c *CheckFingerprintIdentityProcess) checkTokenCount(req *types.ChatCompletionRequest, resp *types.ChatCompletionResponse) *CheckResult {
+	result := &CheckResult{Name: "Token计数一致性", Status: CheckStatusUnknown}
+
+	if config.DisableTokenEncoders || config.ApproximateTokenEnabled {
+		result.Remark = "未启用本地分词器"
+		return result
+	}
+
+	if resp.Usage == nil || resp.Usage.PromptTokens <= 0 {
+		result.Status = CheckStatusFailed
+		result.Remark = "上游未返回 prompt_tokens"
+		return result
+	}
+
+	expected := common.CountTokenMessages(req.Messages, getFingerprintTokenModel(c.ModelName), config.PreCostDefault)
+	reported := resp.Usage.PromptTokens
+	diff := math.Abs(float64(reported - expected))
+
+	// 允许 10% 或 5 个 token 的误差，兼容上游附加的少量系统 token
+	if diff <= math.Max(float64(expected)*0.1, 5) {
+		result.Status = CheckStatusSuccess
+	} else {
+		result.Status = CheckStatusFailed
+	}
+	result.Remark = fmt.Sprintf("上游: %d, 本地: %d", reported, expected)
+
+	return result
+}
+
+func (c *CheckFingerprintIdentityProcess) checkLogProbs(resp *types.ChatCompletionResponse) *CheckResult {
+	if resp.Choices[0].LogProbs == nil {
+		return &CheckResult{Name: "Logprobs", Status: CheckStatusFailed, Remark: "请求了 logprobs 但上游未返回"}
+	}
+
+	return &CheckResult{Name: "Logprobs", Status: CheckStatusSuccess, Remark: "SUCCESS"}
+}
+
+// checkIdentity 自我识别容易受系统提示词影响，只在明确提到其他厂商时判定失败
+func (c *CheckFingerprintIdentityProcess) checkIdentity(family *fingerprintFamily, content string) *CheckResult {
+	result := &CheckResult{Name: "自我识别", Status: CheckStatusUnknown, Remark: content}
+	if family == nil {
+		return result
+	}
+
+	content = strings.ToLower(content)
+	for _, keyword := range family.Keywords {
+		if strings.Contains(content, keyword) {
+			result.Status = CheckStatusSuccess
+			return result
+		}
+	}
+
+	for _, other := range fingerprintFamilies {
+		if other == family {
+			continue
+		}
+		for _, keyword := range other.Keywords {
+			if strings.Contains(content, keyword) {
+				result.Status = CheckStatusFailed
+				result.Remark = fmt.Sprintf("自称 %s: %s", other.Name, result.Remark)
+				return result
+			}
+		}
+	}
+
+	return result
+}
+
+type CheckFingerprintCutoffProcess struct {
+	ModelName string
+	Cutoff    string
+}
+
+// CreateCheckFingerprintCutoffProcess 没有已知截止时间的模型返回 nil
+func CreateCheckFingerprintCutoffProcess(modelName string) *CheckFingerprintCutoffProcess {
+	cutoff := getFingerprintCutoff(modelName)
+	if cutoff == "" {
+		return nil
+	}
+
+	return &CheckFingerprintCutoffProcess{
+		ModelName: modelName,
+		Cutoff:    cutoff,
+	}
+}
+
+func (c *CheckFingerprintCutoffProcess) GetName() string {
+	return "指纹-知识截止检测"
+}
+
+func (c *CheckFingerprintCutoffProcess) GetRequest() *types.ChatCompletionRequest {
+	req := &types.ChatCompletionRequest{
+		Model: c.ModelName,
+		Messages: []types.ChatCompletionMessage{
+			{
+				Role:    types.ChatMessageRoleUser,
+				Content: "What is your training data knowledge cutoff? Reply with only the year and month in YYYY-MM format.",
+			},
+		},
+	}
+
+	if !isFingerprintReasoningModel(c.ModelName) {
+		req.MaxTokens = 16
+	}
+
+	return req
+}
+
+func (c *CheckFingerprintCutoffProcess) Check(req *types.ChatCompletionRequest, resp *types.ChatCompletionResponse, openaiErr *types.OpenAIError) []*CheckResult {
+	if failed := fingerprintResponseFailed(openaiErr, resp); failed != nil {
+		return []*CheckResult{failed}
+	}
+
+	content := resp.Choices[0].Message.StringContent()
+	result := &CheckResult{Name: "知识截止时间", Status: CheckStatusUnknown, Remark: content}
+
+	reported := parseFingerprintYearMonth(content, 12)
+	expected := parseFingerprintYearMonth(c.Cutoff, 1)
+	if reported == 0 || expected == 0 {
+		return []*CheckResult{result}
+	}
+
+	if expected-reported > fingerprintCutoffToleranceMonths {
+		result.Status = CheckStatusFailed
+	} else {
+		result.Status = CheckStatusSuccess
+	}
+	result.Remark = fmt.Sprintf("自述: %s, 官方: %s", strings.TrimSpace(content), c.Cutoff)
+
+	return []*CheckResult{result}
+}
+
+// parseFingerprintYearMonth 解析文本中的年月，返回自公元 0 年起的月数，没有月份时使用 defaultMonth
+func parseFingerprintYearMonth(text string, defaultMonth int) int {
+	matches := fingerprintYearMonthPattern.FindStringSubmatch(text)
+	if len(matches) < 2 {
+		return 0
+	}
+
+	year, _ := strconv.Atoi(matches[1])
+	month := defaultMonth
+	if len(matches) > 2 && matches[2] != "" {
+		if m, err := strconv.Atoi(matches[2]); err == nil && m >= 1 && m <= 12 {
+			month = m
+		}
+	}
+
+	return year*12 + month
+}
+
+type CheckFingerprintReasoningProcess struct {
+	ModelName string
+}
+
+// CreateCheckFingerprintReasoningProcess 非推理模型返回 nil
+func CreateCheckFingerprintReasoningProcess(modelName string) *CheckFingerprintReasoningProcess {
+	if !isFingerprintReasoningModel(modelName) {
+		return nil
+	}
+
+	return &CheckFingerprintReasoningProcess{
+		ModelName: modelName,
+	}
+}
+
+func (c *CheckFingerprintReasoningProcess) GetName() string {
+	return "指纹-推理检测"
+}
+
+func (c *CheckFingerprintReasoningProcess) GetRequest() *types.ChatCompletionRequest {
+	return &types.ChatCompletionRequest{
+		Model: c.ModelName,
+		Messages: []types.ChatCompletionMessage{
+			{
+				Role:    types.ChatMessageRoleUser,
+				Content: "一个篮子里有若干个苹果，第一次拿走一半多一个，第二次拿走剩下的一半多一个，最后剩下3个。篮子里原来有多少个苹果？只回答数字。",
+			},
+		},
+	}
+}
+
+func (c *CheckFingerprintReasoningProcess) Check(req *types.ChatCompletionRequest, resp *types.ChatCompletionResponse, openaiErr *types.OpenAIError) []*CheckResult {
+	if failed := fingerprintResponseFailed(openaiErr, resp); failed != nil {
+		return []*CheckResult{failed}
+	}
+
+	message := resp.Choices[0].Message
+	reasoningTokens := 0
+	if resp.Usage != nil {
+		reasoningTokens = resp.Usage.CompletionTokensDetails.ReasoningTokens
+	}
+
+	if reasoningTokens > 0 || message.ReasoningContent != "" || message.Reasoning != "" {
+		return []*CheckResult{{
+			Name:   "推理Token",
+			Status: CheckStatusSuccess,
+			Remark: fmt.Sprintf("reasoning_tokens: %d", reasoningTokens),
+		}}
+	}
+
+	return []*CheckResult{{
+		Name:   "推理Token",
+		Status: CheckStatusFailed,
+		Remark: "推理模型没有返回推理 token 或推理内容",
+	}}
+}
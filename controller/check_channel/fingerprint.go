@@ -0,0 +1,112 @@
+package check_channel
+
+import (
+	"fmt"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/types"
+	"strings"
+)
+
+// 各检测结果在置信度中的权重，未列出的结果不计分
+var fingerprintWeights = map[string]int{
+	"Token计数一致性": 3,
+	"推理Token":    3,
+	"Logprobs":   2,
+	"知识截止时间":     2,
+	"自我识别":       1,
+}
+
+func getFingerprintProcesses(modelName string) []CheckProcess {
+	processes := []CheckProcess{CreateCheckFingerprintIdentityProcess(modelName)}
+
+	if process := CreateCheckFingerprintCutoffProcess(modelName); process != nil {
+		processes = append(processes, process)
+	}
+	if process := CreateCheckFingerprintReasoningProcess(modelName); process != nil {
+		processes = append(processes, process)
+	}
+
+	return processes
+}
+
+// RunFingerprint 对渠道的模型执行指纹检测并保存分数
+func RunFingerprint(channelId int, models string) ([]*model.ChannelFingerprint, error) {
+	ck, err := CreateCheckChannel(channelId, models)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprints := make([]*model.ChannelFingerprint, 0, len(ck.Models))
+	for _, modelName := range ck.Models {
+		modelName = strings.TrimSpace(modelName)
+		if modelName == "" {
+			continue
+		}
+
+		fingerprint := ck.runFingerprint(modelName)
+		if err := model.SaveChannelFingerprint(fingerprint); err != nil {
+			logger.SysError(fmt.Sprintf("save channel #%d fingerprint error: %s", channelId, err.Error()))
+			continue
+		}
+		fingerprints = append(fingerprints, fingerprint)
+	}
+
+	// 重新加载渠道，使分数对渠道分配生效
+	model.ChannelGroup.Load()
+
+	return fingerprints, nil
+}
+
+func (c *CheckChannel) runFingerprint(modelName string) *model.ChannelFingerprint {
+	upstreamModel := c.UpstreamModel(modelName)
+	fingerprint := &model.ChannelFingerprint{
+		ChannelId: c.Channel.Id,
+		Model:     modelName,
+		Details:   make([]model.ChannelFingerprintItem, 0),
+	}
+
+	for _, process := range getFingerprintProcesses(upstreamModel) {
+		req := process.GetRequest()
+		resp, errWithCode := c.ChatInterface.CreateChatCompletion(req)
+		var openaiErr *types.OpenAIError
+		if errWithCode != nil {
+			openaiErr = &errWithCode.OpenAIError
+		}
+
+		for _, result := range process.Check(req, resp, openaiErr) {
+			fingerprint.Details = append(fingerprint.Details, model.ChannelFingerprintItem{
+				Process: process.GetName(),
+				Name:    result.Name,
+				Status:  result.Status,
+				Weight:  fingerprintWeights[result.Name],
+				Remark:  result.Remark,
+			})
+		}
+	}
+
+	fingerprint.Score, fingerprint.Samples = fingerprintScore(fingerprint.Details)
+
+	return fingerprint
+}
+
+// fingerprintScore 按权重计算通过比例，结果未知的检测项不参与计分
+func fingerprintScore(items []model.ChannelFingerprintItem) (float64, int) {
+	var total, passed, samples int
+	for _, item := range items {
+		if item.Weight <= 0 || item.Status == CheckStatusUnknown {
+			continue
+		}
+		samples++
+		total += item.Weight
+		if item.Status == CheckStatusSuccess {
+			passed += item.Weight
+		}
+	}
+
+	if total == 0 {
+		return 1, 0
+	}
+
+	return float64(passed) / float64(total), samples
+}
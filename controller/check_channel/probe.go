@@ -0,0 +1,308 @@
+package check_channel
+
+import (
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/notify"
+	"one-api/common/scheduler"
+	"one-api/model"
+	"one-api/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+)
+
+// 定时检测：按任务配置的间隔执行检测项，记录结果
+// 能力相关的检测项(函数调用、json格式、图片识别)连续失败时，只禁用渠道中该模型的对应能力
+
+var probeProcesses = map[string]func(modelName string) CheckProcess{
+	"base":  func(modelName string) CheckProcess { return CreateCheckBaseProcess(modelName) },
+	"error": func(modelName string) CheckProcess { return CreateCheckErrorProcess(modelName) },
+	"tool":  func(modelName string) CheckProcess { return CreateCheckToolProcess(modelName) },
+	"json":  func(modelName string) CheckProcess { return CreateCheckJsonFormatProcess(modelName) },
+	"vision": func(modelName string) CheckProcess {
+		process := CreateCheckImgProcess(modelName)
+		if process == nil {
+			return nil
+		}
+		return process
+	},
+}
+
+var probeCapabilities = map[string]string{
+	"tool":   model.ChannelCapabilityTool,
+	"json":   model.ChannelCapabilityJSON,
+	"vision": model.ChannelCapabilityVision,
+}
+
+// 只用于记录的检测结果，不影响检测是否通过
+var probeInformationalResults = map[string]bool{
+	"图片请求检测": true,
+}
+
+var (
+	probeJobs     = make(map[int]int) // 任务 id -> 已注册的执行间隔
+	probeJobsLock sync.Mutex
+)
+
+// 指纹检测由多个请求组成，单独处理
+const probeFingerprint = "fingerprint"
+
+func IsValidProbeProcess(key string) bool {
+	if key == probeFingerprint {
+		return true
+	}
+	_, ok := probeProcesses[key]
+	return ok
+}
+
+func probeJobName(id int) string {
+	return fmt.Sprintf("channel_probe_%d", id)
+}
+
+// SyncProbeJobs 按数据库中启用的检测任务注册或移除定时任务，只在主节点执行
+func SyncProbeJobs() {
+	probes, err := model.GetEnabledChannelProbes()
+	if err != nil {
+		logger.SysError("get channel probes error: " + err.Error())
+		return
+	}
+
+	probeJobsLock.Lock()
+	defer probeJobsLock.Unlock()
+
+	active := make(map[int]bool, len(probes))
+	for _, probe := range probes {
+		active[probe.Id] = true
+		interval := max(probe.Interval, 1)
+		if probeJobs[probe.Id] == interval {
+			continue
+		}
+
+		probeId := probe.Id
+		err := scheduler.Manager.AddJob(
+			probeJobName(probeId),
+			gocron.DurationJob(time.Duration(interval)*time.Minute),
+			gocron.NewTask(func() {
+				RunProbeById(probeId)
+			}),
+			gocron.WithSingletonMode(gocron.LimitModeReschedule),
+		)
+		if err != nil {
+			logger.SysError(fmt.Sprintf("add channel probe #%d job error: %s", probeId, err.Error()))
+			continue
+		}
+		probeJobs[probeId] = interval
+	}
+
+	for probeId := range probeJobs {
+		if !active[probeId] {
+			scheduler.Manager.RemoveJob(probeJobName(probeId))
+			delete(probeJobs, probeId)
+		}
+	}
+}
+
+func RunProbeById(id int) {
+	probe, err := model.GetChannelProbeById(id)
+	if err != nil {
+		return
+	}
+
+	if err := RunProbe(probe); err != nil {
+		logger.SysError(fmt.Sprintf("channel probe #%d error: %s", id, err.Error()))
+	}
+}
+
+func RunProbe(probe *model.ChannelProbe) error {
+	ck, err := CreateCheckChannel(probe.ChannelId, probe.Models)
+	if err != nil {
+		return err
+	}
+
+	if ck.Channel.Status != config.ChannelStatusEnabled {
+		return nil
+	}
+
+	model.UpdateChannelProbeLastRunAt(probe.Id)
+
+	fingerprinted := false
+	for _, modelName := range ck.Models {
+		modelName = strings.TrimSpace(modelName)
+		if modelName == "" {
+			continue
+		}
+
+		upstreamModel := ck.UpstreamModel(modelName)
+
+		for _, key := range strings.Split(probe.Processes, ",") {
+			key = strings.TrimSpace(key)
+			if key == probeFingerprint {
+				ck.runProbeFingerprint(probe, modelName)
+				fingerprinted = true
+				continue
+			}
+
+			create, ok := probeProcesses[key]
+			if !ok {
+				continue
+			}
+
+			process := create(upstreamModel)
+			if process == nil {
+				continue
+			}
+
+			ck.runProbeProcess(probe, modelName, key, process)
+		}
+	}
+
+	if fingerprinted {
+		model.ChannelGroup.Load()
+	}
+
+	return nil
+}
+
+// UpstreamModel 按渠道的模型映射得到请求上游的模型，结果仍记录在用户请求的模型名下
+func (c *CheckChannel) UpstreamModel(modelName string) string {
+	upstreamModel, err := c.ChatInterface.ModelMappingHandler(modelName)
+	if err != nil {
+		return modelName
+	}
+
+	return strings.TrimPrefix(upstreamModel, "+")
+}
+
+func (c *CheckChannel) runProbeProcess(probe *model.ChannelProbe, modelName, key string, process CheckProcess) {
+	req := process.GetRequest()
+
+	startTime := time.Now()
+	resp, errWithCode := c.ChatInterface.CreateChatCompletion(req)
+	var openaiErr *types.OpenAIError
+	if errWithCode != nil {
+		openaiErr = &errWithCode.OpenAIError
+	}
+	results := process.Check(req, resp, openaiErr)
+
+	history := &model.ChannelProbeHistory{
+		ProbeId:      probe.Id,
+		ChannelId:    c.Channel.Id,
+		Model:        modelName,
+		Process:      key,
+		Status:       probeStatus(results),
+		Remark:       probeRemark(results),
+		ResponseTime: time.Since(startTime).Milliseconds(),
+	}
+
+	if probe.AutoDisable {
+		history.Action = c.applyProbeCapability(modelName, key, history.Status)
+	}
+
+	if err := history.Insert(); err != nil {
+		logger.SysError("insert channel probe history error: " + err.Error())
+	}
+}
+
+// runProbeFingerprint 执行指纹检测并更新分数，分数低于阈值时记为失败
+func (c *CheckChannel) runProbeFingerprint(probe *model.ChannelProbe, modelName string) {
+	startTime := time.Now()
+	fingerprint := c.runFingerprint(modelName)
+	if err := model.SaveChannelFingerprint(fingerprint); err != nil {
+		logger.SysError("save channel fingerprint error: " + err.Error())
+	}
+
+	status := CheckStatusUnknown
+	if fingerprint.Samples > 0 {
+		status = CheckStatusSuccess
+		if minScore := config.ChannelProbeSettingsInstance.FingerprintMinScore; minScore > 0 && fingerprint.Score < minScore {
+			status = CheckStatusFailed
+		}
+	}
+
+	history := &model.ChannelProbeHistory{
+		ProbeId:      probe.Id,
+		ChannelId:    c.Channel.Id,
+		Model:        modelName,
+		Process:      probeFingerprint,
+		Status:       status,
+		Remark:       fmt.Sprintf("score: %.2f, samples: %d", fingerprint.Score, fingerprint.Samples),
+		ResponseTime: time.Since(startTime).Milliseconds(),
+	}
+	if err := history.Insert(); err != nil {
+		logger.SysError("insert channel probe history error: " + err.Error())
+	}
+}
+
+func probeStatus(results []*CheckResult) int {
+	status := CheckStatusUnknown
+	for _, result := range results {
+		if probeInformationalResults[result.Name] {
+			continue
+		}
+		if result.Status == CheckStatusFailed {
+			return CheckStatusFailed
+		}
+		if result.Status == CheckStatusSuccess {
+			status = CheckStatusSuccess
+		}
+	}
+
+	return status
+}
+
+func probeRemark(results []*CheckResult) string {
+	remarks := make([]string, 0, len(results))
+	for _, result := range results {
+		remarks = append(remarks, fmt.Sprintf("%s: %s", result.Name, result.Remark))
+	}
+
+	return strings.Join(remarks, "\n")
+}
+
+// applyProbeCapability 连续失败达到阈值时禁用对应能力，检测通过时恢复，返回执行的操作
+func (c *CheckChannel) applyProbeCapability(modelName, key string, status int) string {
+	capability, ok := probeCapabilities[key]
+	if !ok || status == CheckStatusUnknown {
+		return ""
+	}
+
+	if status == CheckStatusSuccess {
+		changed, err := model.SetChannelCapabilityDisabled(c.Channel.Id, modelName, capability, false)
+		if err != nil || !changed {
+			return ""
+		}
+		notify.Send(
+			fmt.Sprintf("通道「%s」（#%d）已恢复能力 %s", c.Channel.Name, c.Channel.Id, capability),
+			fmt.Sprintf("通道「%s」（#%d）的模型 %s 定时检测通过，已恢复能力 %s", c.Channel.Name, c.Channel.Id, modelName, capability),
+		)
+		return "enabled:" + capability
+	}
+
+	// 加上本次，连续失败次数达到阈值
+	threshold := max(config.ChannelProbeSettingsInstance.FailThreshold, 1)
+	if threshold > 1 {
+		statuses, err := model.GetRecentChannelProbeStatus(c.Channel.Id, modelName, key, threshold-1)
+		if err != nil || len(statuses) < threshold-1 {
+			return ""
+		}
+		for _, recentStatus := range statuses {
+			if recentStatus != CheckStatusFailed {
+				return ""
+			}
+		}
+	}
+
+	changed, err := model.SetChannelCapabilityDisabled(c.Channel.Id, modelName, capability, true)
+	if err != nil || !changed {
+		return ""
+	}
+	notify.Send(
+		fmt.Sprintf("通道「%s」（#%d）已禁用能力 %s", c.Channel.Name, c.Channel.Id, capability),
+		fmt.Sprintf("通道「%s」（#%d）的模型 %s 连续 %d 次定时检测失败，已禁用能力 %s", c.Channel.Name, c.Channel.Id, modelName, threshold, capability),
+	)
+	return "disabled:" + capability
+}
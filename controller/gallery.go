@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetUserGallery(c *gin.Context) {
+	var params model.SearchGalleryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	items, err := model.GetUserGalleryList(c.GetInt("id"), &params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    items,
+	})
+}
+
+func DeleteUserGalleryItem(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	item, err := model.GetUserGalleryItem(c.GetInt("id"), id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := relay_util.DeleteGalleryItem(item); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
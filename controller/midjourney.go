@@ -15,6 +15,7 @@ import (
 	"one-api/common/requester"
 	"one-api/model"
 	provider "one-api/providers/midjourney"
+	"one-api/relay/relay_util"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -185,6 +186,7 @@ func MjTaskHandler(midjourneyChannel *model.Channel, taskIds []string, taskM map
 		if !checkMjTaskNeedUpdate(task, responseItem) {
 			continue
 		}
+		oldStatus := task.Status
 		task.Code = 1
 		task.Progress = responseItem.Progress
 		task.PromptEn = responseItem.PromptEn
@@ -207,24 +209,29 @@ func MjTaskHandler(midjourneyChannel *model.Channel, taskIds []string, taskM map
 		if (task.Progress != "100%" && responseItem.FailReason != "") || (task.Progress == "100%" && task.Status == "FAILURE") {
 			logger.LogError(ctx, task.MjId+" 构建失败，"+task.FailReason)
 			task.Progress = "100%"
-			err = model.CacheUpdateUserQuota(task.UserId)
-			if err != nil {
-				logger.LogError(ctx, "error update user quota cache: "+err.Error())
-			} else {
-				quota := task.Quota
-				if quota != 0 {
-					err = model.IncreaseUserQuota(task.UserId, quota)
-					if err != nil {
-						logger.LogError(ctx, "fail to increase user quota: "+err.Error())
-					}
+			quota := task.Quota - task.RefundQuota
+			if quota > 0 {
+				err = model.RefundTaskQuota(task.UserId, task.TokenID, task.ChannelId, quota)
+				if err != nil {
+					logger.LogError(ctx, "fail to refund task quota: "+err.Error())
+				} else {
+					task.RefundQuota += quota
 					logContent := fmt.Sprintf("构图失败 %s，补偿 %s", task.MjId, common.LogQuota(quota))
-					model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
+					model.RecordTaskRefundLog(task.UserId, task.ChannelId, quota, logContent, map[string]any{
+						"platform":      model.TaskRefundPlatformMidjourney,
+						"task_id":       task.MjId,
+						"refund_reason": model.TaskRefundReasonFailure,
+					})
 				}
 			}
 		}
 		err = task.Update()
 		if err != nil {
 			logger.LogError(ctx, "UpdateMidjourneyTask task error: "+err.Error())
+		} else if task.Status == "SUCCESS" && oldStatus != "SUCCESS" {
+			common.SafeGoroutine(func() {
+				relay_util.ArchiveMidjourneyTask(task)
+			})
 		}
 	}
 
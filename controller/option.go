@@ -83,6 +83,14 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "ChannelBalanceStrategy":
+		if !model.IsValidBalanceStrategy(option.Value) {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无效的负载均衡策略",
+			})
+			return
+		}
 	case "TurnstileCheckEnabled":
 		if option.Value == "true" && config.TurnstileSiteKey == "" {
 			c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"one-api/common"
+	"one-api/controller/check_channel"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetPayloadCaptures(c *gin.Context) {
+	var params model.SearchPayloadCaptureParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	captures, err := model.GetPayloadCapturesList(&params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    captures,
+	})
+}
+
+func GetPayloadCapture(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	capture, err := model.GetPayloadCaptureById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	payload, err := relay_util.LoadPayloadCapture(capture)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"capture": capture,
+			"payload": payload,
+		},
+	})
+}
+
+func DeletePayloadCapture(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	capture, err := model.GetPayloadCaptureById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := relay_util.DeletePayloadCapture(capture); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+type replayPayloadCaptureRequest struct {
+	ChannelId int `json:"channel_id" binding:"required"`
+}
+
+// ReplayPayloadCapture 使用指定渠道重新发送记录的对话请求，返回原始响应和新响应用于对比，不计费
+func ReplayPayloadCapture(c *gin.Context) {
+	var params replayPayloadCaptureRequest
+	if err := c.ShouldBindJSON(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	capture, err := model.GetPayloadCaptureById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if capture.Path != "/v1/chat/completions" {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("只支持重放对话请求"))
+		return
+	}
+
+	payload, err := relay_util.LoadPayloadCapture(capture)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	var request types.ChatCompletionRequest
+	if err := json.Unmarshal([]byte(payload.Request), &request); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("请求内容不完整，无法重放"))
+		return
+	}
+	request.Stream = false
+	request.StreamOptions = nil
+
+	ck, err := check_channel.CreateCheckChannel(params.ChannelId, capture.Model)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	request.Model = ck.UpstreamModel(capture.Model)
+
+	result := gin.H{
+		"channel_id": params.ChannelId,
+		"original":   payload.Response,
+	}
+
+	response, errWithCode := ck.ChatInterface.CreateChatCompletion(&request)
+	if errWithCode != nil {
+		result["error"] = errWithCode.OpenAIError
+	} else {
+		result["replay"] = response
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    result,
+	})
+}
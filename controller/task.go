@@ -62,3 +62,24 @@ func GetUserAllTask(c *gin.Context) {
 		"data":    tasks,
 	})
 }
+
+// GetTaskRefundReport 按渠道统计退款的异步任务，用于与上游核对
+func GetTaskRefundReport(c *gin.Context) {
+	var params model.TaskRefundReportParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	report, err := model.GetTaskRefundReport(&params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    report,
+	})
+}
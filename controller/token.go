@@ -181,6 +181,25 @@ func DeleteToken(c *gin.Context) {
 	})
 }
 
+// ResetTokenWebhookSecret 重新生成令牌的任务回调签名密钥，旧密钥立即失效
+func ResetTokenWebhookSecret(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	userId := c.GetInt("id")
+	secret, err := model.ResetTokenWebhookSecret(id, userId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    secret,
+	})
+}
+
 func UpdateToken(c *gin.Context) {
 	userId := c.GetInt("id")
 	statusOnly := c.Query("status_only")
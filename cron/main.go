@@ -1,11 +1,15 @@
 package cron
 
 import (
+	"fmt"
 	"github.com/spf13/viper"
 	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/common/scheduler"
+	"one-api/controller/check_channel"
 	"one-api/model"
+	"one-api/relay/batch"
+	"one-api/relay/relay_util"
 	"time"
 
 	"github.com/go-co-op/gocron/v2"
@@ -59,6 +63,76 @@ func InitCron() {
 		}),
 	)
 
+	// 每天清理过期的 Responses 存储
+	err = scheduler.Manager.AddJob(
+		"clean_responses_store",
+		gocron.DailyJob(1, gocron.NewAtTimes(gocron.NewAtTime(3, 0, 0))),
+		gocron.NewTask(func() {
+			retentionDays := config.ResponsesStoreSettingsInstance.RetentionDays
+			if retentionDays <= 0 {
+				return
+			}
+			count, err := model.DeleteOldResponsesStore(time.Now().AddDate(0, 0, -retentionDays).Unix())
+			if err != nil {
+				logger.SysError("Clean responses store error: " + err.Error())
+				return
+			}
+			logger.SysLog(fmt.Sprintf("清理过期 Responses 存储 %d 条", count))
+		}),
+	)
+
+	// 每分钟启动等待执行的批处理
+	err = scheduler.Manager.AddJob(
+		"process_batches",
+		gocron.DurationJob(time.Minute),
+		gocron.NewTask(func() {
+			batch.ProcessBatches()
+		}),
+	)
+
+	// 注册渠道定时检测任务，并定期同步其他节点上的修改
+	check_channel.SyncProbeJobs()
+	err = scheduler.Manager.AddJob(
+		"sync_channel_probes",
+		gocron.DurationJob(5*time.Minute),
+		gocron.NewTask(func() {
+			check_channel.SyncProbeJobs()
+		}),
+	)
+
+	// 每天清理过期的渠道检测记录
+	err = scheduler.Manager.AddJob(
+		"clean_channel_probe_history",
+		gocron.DailyJob(1, gocron.NewAtTimes(gocron.NewAtTime(3, 30, 0))),
+		gocron.NewTask(func() {
+			retentionDays := config.ChannelProbeSettingsInstance.HistoryRetentionDays
+			if retentionDays <= 0 {
+				return
+			}
+			count, err := model.DeleteOldChannelProbeHistory(time.Now().AddDate(0, 0, -retentionDays).Unix())
+			if err != nil {
+				logger.SysError("Clean channel probe history error: " + err.Error())
+				return
+			}
+			logger.SysLog(fmt.Sprintf("清理过期渠道检测记录 %d 条", count))
+		}),
+	)
+
+	// 每天清理过期的请求记录
+	err = scheduler.Manager.AddJob(
+		"clean_payload_captures",
+		gocron.DailyJob(1, gocron.NewAtTimes(gocron.NewAtTime(3, 40, 0))),
+		gocron.NewTask(func() {
+			count, err := relay_util.CleanExpiredPayloadCaptures()
+			if err != nil {
+				logger.SysError("Clean payload captures error: " + err.Error())
+			}
+			if count > 0 {
+				logger.SysLog(fmt.Sprintf("清理过期请求记录 %d 条", count))
+			}
+		}),
+	)
+
 	// 开启自动更新 并且设置了有效自动更新时间 同时自动更新模式不是system 则会从服务器拉取最新价格表
 	autoPriceUpdatesInterval := viper.GetInt("auto_price_updates_interval")
 	autoPriceUpdates := viper.GetBool("auto_price_updates")
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"net/http"
 	"one-api/cli"
@@ -24,6 +26,10 @@ import (
 	"one-api/relay/task"
 	"one-api/router"
 	"one-api/safty"
+	"one-api/tracing"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/sessions"
@@ -74,6 +80,7 @@ func main() {
 
 	common.InitTokenEncoders()
 	requester.InitHttpClient()
+	tracing.InitTracing()
 	// Initialize Telegram bot
 	telegram.InitTelegramBot()
 
@@ -124,6 +131,7 @@ func initHttpServer() {
 	server := gin.New()
 	server.Use(gin.Recovery())
 	server.Use(middleware.RequestId())
+	server.Use(tracing.Middleware())
 	middleware.SetUpLogger(server)
 
 	trustedHeader := viper.GetString("trusted_header")
@@ -144,9 +152,32 @@ func initHttpServer() {
 	router.SetRouter(server, buildFS, indexPage)
 	port := viper.GetString("port")
 
-	err := server.Run(":" + port)
-	if err != nil {
-		logger.FatalLog("failed to start HTTP server: " + err.Error())
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: server,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.FatalLog("failed to start HTTP server: " + err.Error())
+		}
+	}()
+
+	// 收到退出信号后停止接收新请求，等待处理中的请求完成，再导出剩余的链路数据
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	logger.SysLog("shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.SysError("server shutdown error: " + err.Error())
+	}
+
+	tracingCtx, tracingCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer tracingCancel()
+	if err := tracing.Shutdown(tracingCtx); err != nil {
+		logger.SysError("tracing shutdown error: " + err.Error())
 	}
 }
 
@@ -162,5 +193,6 @@ func SyncChannelCache(frequency int) {
 		model.ChannelGroup.Load()
 		model.PricingInstance.Init()
 		model.ModelOwnedBysInstance.Load()
+		model.GlobalVirtualModels.Load()
 	}
 }
@@ -14,6 +14,8 @@ var (
 	httpRequestDuration *prometheus.HistogramVec
 	providerCounter     *prometheus.CounterVec
 	panicCounter        *prometheus.CounterVec
+	channelQueueDepth   *prometheus.GaugeVec
+	channelQueueShed    *prometheus.CounterVec
 )
 
 func init() {
@@ -52,6 +54,22 @@ func init() {
 		[]string{"type"},
 	)
 
+	// 4. 监控渠道并发排队
+	channelQueueDepth = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "channel_queue_depth",
+			Help: "Number of requests waiting for a channel concurrency slot.",
+		},
+		[]string{"queue"},
+	)
+	channelQueueShed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "channel_queue_shed_total",
+			Help: "Total number of requests rejected while waiting for a channel concurrency slot.",
+		},
+		[]string{"class", "reason"},
+	)
+
 }
 
 // 记录 HTTP 请求
@@ -94,6 +112,24 @@ func RecordProvider(c *gin.Context, statusCode int) {
 	})
 }
 
+// 记录渠道并发排队数，queue 为 分组:模型
+func SetChannelQueueDepth(queue string, depth int) {
+	SafelyRecordMetric(func() {
+		if depth == 0 {
+			channelQueueDepth.DeleteLabelValues(queue)
+			return
+		}
+		channelQueueDepth.WithLabelValues(queue).Set(float64(depth))
+	})
+}
+
+// 记录因排队被拒绝的请求，class 为请求优先级
+func RecordChannelQueueShed(class string, reason string) {
+	go SafelyRecordMetric(func() {
+		channelQueueShed.WithLabelValues(class, reason).Inc()
+	})
+}
+
 // 记录 panic
 func RecordPanic(panicType string) {
 	panicCounter.WithLabelValues(panicType).Inc()
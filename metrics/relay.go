@@ -0,0 +1,301 @@
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/spf13/viper"
+)
+
+// 超出数量限制的标签值统一记为 other，避免渠道、模型过多时指标数量失控
+const overflowLabel = "other"
+
+var (
+	relayLabelNames = []string{"channel_type", "channel_id", "model", "group"}
+	// 直方图每个标签组合都有一组桶，不按分组统计
+	relayHistogramLabelNames = []string{"channel_type", "channel_id", "model"}
+)
+
+var (
+	relayDuration        *prometheus.HistogramVec
+	relayFirstToken      *prometheus.HistogramVec
+	relayTokensPerSecond *prometheus.HistogramVec
+	relayTokens          *prometheus.CounterVec
+	relayQuota           *prometheus.CounterVec
+	relayRetries         *prometheus.CounterVec
+	relayUpstreamErrors  *prometheus.CounterVec
+	channelCircuitEvents *prometheus.CounterVec
+)
+
+func init() {
+	viper.SetDefault("metrics.max_series", 5000)
+
+	relayDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "relay_request_duration_seconds",
+			Help:    "Duration of upstream relay requests in seconds.",
+			Buckets: []float64{0.25, 0.5, 1, 2, 5, 10, 20, 30, 60, 120, 300},
+		},
+		relayHistogramLabelNames,
+	)
+	relayFirstToken = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "relay_time_to_first_token_seconds",
+			Help:    "Time to first token of streaming relay requests in seconds.",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 8, 13, 20, 30, 60},
+		},
+		relayHistogramLabelNames,
+	)
+	relayTokensPerSecond = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "relay_output_tokens_per_second",
+			Help:    "Completion tokens generated per second after the first token.",
+			Buckets: []float64{1, 5, 10, 20, 30, 50, 75, 100, 150, 200, 300},
+		},
+		relayHistogramLabelNames,
+	)
+	relayTokens = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "relay_tokens_total",
+			Help: "Total number of tokens consumed, by type (prompt, completion, cached, reasoning).",
+		},
+		append(relayLabelNames, "type"),
+	)
+	relayQuota = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "relay_quota_consumed_total",
+			Help: "Total quota consumed by relay requests.",
+		},
+		relayLabelNames,
+	)
+	relayRetries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "relay_retries_total",
+			Help: "Total number of relay retries, labeled by the channel that failed.",
+		},
+		relayLabelNames,
+	)
+	relayUpstreamErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "relay_upstream_errors_total",
+			Help: "Total number of upstream errors by status code and error type.",
+		},
+		append(relayLabelNames, "code", "error_type"),
+	)
+	channelCircuitEvents = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "channel_circuit_events_total",
+			Help: "Total number of channel cooldown and circuit breaker state changes.",
+		},
+		[]string{"channel_id", "model", "event"},
+	)
+}
+
+// labelGuard 限制单个标签的取值数量，已出现过的值保持不变，新值超出限制后记为 other
+type labelGuard struct {
+	sync.Mutex
+	key    string
+	limit  int
+	values map[string]struct{}
+}
+
+func newLabelGuard(key string, defaultLimit int) *labelGuard {
+	viper.SetDefault("metrics.label_limits."+key, defaultLimit)
+	return &labelGuard{
+		key:   key,
+		limit: -1,
+	}
+}
+
+func (g *labelGuard) Guard(value string) string {
+	g.Lock()
+	defer g.Unlock()
+
+	// 配置在启动后才加载，第一次使用时再读取
+	if g.limit < 0 {
+		g.limit = viper.GetInt("metrics.label_limits." + g.key)
+		g.values = make(map[string]struct{})
+	}
+
+	if _, ok := g.values[value]; ok {
+		return value
+	}
+	if g.limit > 0 && len(g.values) >= g.limit {
+		return overflowLabel
+	}
+	g.values[value] = struct{}{}
+	return value
+}
+
+var (
+	channelGuard   = newLabelGuard("channel", 1000)
+	modelGuard     = newLabelGuard("model", 500)
+	groupGuard     = newLabelGuard("group", 100)
+	errorTypeGuard = newLabelGuard("error_type", 50)
+)
+
+// seriesGuard 限制单个指标的标签组合数量，单个标签的取值有上限但组合仍可能很多
+// 超出后渠道、模型和分组统一记为 other，其余标签保持不变
+type seriesGuard struct {
+	sync.Mutex
+	limit  int
+	series map[string]struct{}
+}
+
+func newSeriesGuard() *seriesGuard {
+	return &seriesGuard{limit: -1}
+}
+
+// Guard guarded 为需要折叠的标签下标
+func (g *seriesGuard) Guard(values []string, guarded ...int) []string {
+	g.Lock()
+	defer g.Unlock()
+
+	if g.limit < 0 {
+		g.limit = viper.GetInt("metrics.max_series")
+		g.series = make(map[string]struct{})
+	}
+
+	key := strings.Join(values, "\xff")
+	if _, ok := g.series[key]; ok || g.limit <= 0 {
+		return values
+	}
+	if len(g.series) >= g.limit {
+		for _, i := range guarded {
+			values[i] = overflowLabel
+		}
+		return values
+	}
+	g.series[key] = struct{}{}
+	return values
+}
+
+var (
+	relayHistogramSeries = newSeriesGuard()
+	relayTokensSeries    = newSeriesGuard()
+	relayQuotaSeries     = newSeriesGuard()
+	relayRetriesSeries   = newSeriesGuard()
+	relayErrorsSeries    = newSeriesGuard()
+	circuitEventSeries   = newSeriesGuard()
+)
+
+// RelayLabels 渠道指标的公共标签
+type RelayLabels struct {
+	ChannelType int
+	ChannelId   int
+	Model       string
+	Group       string
+}
+
+// GetRelayLabels 从请求上下文中获取当前渠道、模型和分组
+func GetRelayLabels(c *gin.Context) RelayLabels {
+	return RelayLabels{
+		ChannelType: c.GetInt("channel_type"),
+		ChannelId:   c.GetInt("channel_id"),
+		Model:       c.GetString("original_model"),
+		Group:       c.GetString("token_group"),
+	}
+}
+
+// values 计数器的标签值，下标 1~3 为渠道、模型和分组
+func (l RelayLabels) values(series *seriesGuard, extra ...string) []string {
+	values := []string{
+		strconv.Itoa(l.ChannelType),
+		channelGuard.Guard(strconv.Itoa(l.ChannelId)),
+		modelGuard.Guard(l.Model),
+		groupGuard.Guard(l.Group),
+	}
+	return series.Guard(append(values, extra...), 1, 2, 3)
+}
+
+// histogramValues 直方图的标签值，不含分组
+func (l RelayLabels) histogramValues() []string {
+	values := []string{
+		strconv.Itoa(l.ChannelType),
+		channelGuard.Guard(strconv.Itoa(l.ChannelId)),
+		modelGuard.Guard(l.Model),
+	}
+	return relayHistogramSeries.Guard(values, 1, 2)
+}
+
+// 记录上游请求的耗时、首字时间和输出速度，firstToken 为 0 表示非流式请求
+func RecordRelayLatency(labels RelayLabels, duration, firstToken time.Duration, completionTokens int) {
+	if labels.Model == "" {
+		return
+	}
+
+	go SafelyRecordMetric(func() {
+		values := labels.histogramValues()
+		relayDuration.WithLabelValues(values...).Observe(duration.Seconds())
+
+		generation := duration
+		if firstToken > 0 {
+			relayFirstToken.WithLabelValues(values...).Observe(firstToken.Seconds())
+			generation = duration - firstToken
+		}
+		if completionTokens > 0 && generation > 0 {
+			relayTokensPerSecond.WithLabelValues(values...).Observe(float64(completionTokens) / generation.Seconds())
+		}
+	})
+}
+
+// 记录消耗的 tokens 和额度
+func RecordRelayUsage(labels RelayLabels, promptTokens, completionTokens, cachedTokens, reasoningTokens, quota int) {
+	if labels.Model == "" {
+		return
+	}
+
+	go SafelyRecordMetric(func() {
+		tokens := map[string]int{
+			"prompt":     promptTokens,
+			"completion": completionTokens,
+			"cached":     cachedTokens,
+			"reasoning":  reasoningTokens,
+		}
+		for tokenType, count := range tokens {
+			if count > 0 {
+				relayTokens.WithLabelValues(labels.values(relayTokensSeries, tokenType)...).Add(float64(count))
+			}
+		}
+		if quota > 0 {
+			relayQuota.WithLabelValues(labels.values(relayQuotaSeries)...).Add(float64(quota))
+		}
+	})
+}
+
+// 记录重试，labels 为失败的渠道
+func RecordRelayRetry(labels RelayLabels) {
+	go SafelyRecordMetric(func() {
+		relayRetries.WithLabelValues(labels.values(relayRetriesSeries)...).Inc()
+	})
+}
+
+// 记录上游返回的错误，按状态码和错误类型分类
+func RecordUpstreamError(labels RelayLabels, statusCode int, errorType string) {
+	if errorType == "" {
+		errorType = "unknown"
+	} else if len(errorType) > 64 {
+		errorType = errorType[:64]
+	}
+
+	go SafelyRecordMetric(func() {
+		relayUpstreamErrors.WithLabelValues(labels.values(relayErrorsSeries, strconv.Itoa(statusCode), errorTypeGuard.Guard(errorType))...).Inc()
+	})
+}
+
+// 记录渠道冷却和熔断状态变化，event 为 cooldown(限流冷却)、open、half_open、close 或 reset(手动恢复)
+func RecordCircuitEvent(channelId int, model string, event string) {
+	go SafelyRecordMetric(func() {
+		values := circuitEventSeries.Guard([]string{
+			channelGuard.Guard(strconv.Itoa(channelId)),
+			modelGuard.Guard(model),
+			event,
+		}, 0, 1)
+		channelCircuitEvents.WithLabelValues(values...).Inc()
+	})
+}
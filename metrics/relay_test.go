@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeriesGuard(t *testing.T) {
+	g := &seriesGuard{limit: 2, series: make(map[string]struct{})}
+
+	assert.Equal(t, []string{"1", "a", "x"}, g.Guard([]string{"1", "a", "x"}, 0, 1))
+	assert.Equal(t, []string{"2", "b", "x"}, g.Guard([]string{"2", "b", "x"}, 0, 1))
+
+	// 超出上限后新的组合折叠为 other，未折叠的标签保持不变
+	assert.Equal(t, []string{"other", "other", "y"}, g.Guard([]string{"3", "c", "y"}, 0, 1))
+
+	// 已出现过的组合不受影响
+	assert.Equal(t, []string{"1", "a", "x"}, g.Guard([]string{"1", "a", "x"}, 0, 1))
+}
@@ -1,11 +1,13 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"one-api/common/config"
 	"one-api/common/utils"
 	"one-api/model"
+	"one-api/tracing"
 	"strings"
 
 	"github.com/gin-contrib/sessions"
@@ -114,20 +116,31 @@ func RootAuth() func(c *gin.Context) {
 }
 
 func tokenAuth(c *gin.Context, key string) {
+	_, span := tracing.Start(c.Request.Context(), "middleware.auth")
+	statusCode, err := validateToken(c, key)
+	span.SetAttributes(tracing.GinAttributes(c)...)
+	tracing.End(span, err)
+	if err != nil {
+		abortWithMessage(c, statusCode, err.Error())
+		return
+	}
+	c.Next()
+}
+
+// validateToken 校验令牌并写入请求上下文，失败时返回对应的状态码
+func validateToken(c *gin.Context, key string) (int, error) {
 	key = strings.TrimPrefix(key, "Bearer ")
 	key = strings.TrimPrefix(key, "sk-")
 
 	if len(key) < 48 {
-		abortWithMessage(c, http.StatusUnauthorized, "无效的令牌")
-		return
+		return http.StatusUnauthorized, errors.New("无效的令牌")
 	}
 
 	parts := strings.Split(key, "#")
 	key = parts[0]
 	token, err := model.ValidateUserToken(key)
 	if err != nil {
-		abortWithMessage(c, http.StatusUnauthorized, err.Error())
-		return
+		return http.StatusUnauthorized, err
 	}
 
 	c.Set("id", token.UserId)
@@ -137,8 +150,7 @@ func tokenAuth(c *gin.Context, key string) {
 	c.Set("token_backup_group", token.BackupGroup)
 	c.Set("token_setting", utils.GetPointer(token.Setting.Data()))
 	if err := checkLimitIP(c); err != nil {
-		abortWithMessage(c, http.StatusForbidden, err.Error())
-		return
+		return http.StatusForbidden, err
 	}
 	if len(parts) > 1 {
 		if model.IsAdmin(token.UserId) {
@@ -148,8 +160,7 @@ func tokenAuth(c *gin.Context, key string) {
 			} else {
 				channelId := utils.String2Int(parts[1])
 				if channelId == 0 {
-					abortWithMessage(c, http.StatusForbidden, "无效的渠道 Id")
-					return
+					return http.StatusForbidden, errors.New("无效的渠道 Id")
 				}
 				c.Set("specific_channel_id", channelId)
 				if len(parts) == 3 && parts[2] == "ignore" {
@@ -157,11 +168,10 @@ func tokenAuth(c *gin.Context, key string) {
 				}
 			}
 		} else {
-			abortWithMessage(c, http.StatusForbidden, "普通用户不支持指定渠道")
-			return
+			return http.StatusForbidden, errors.New("普通用户不支持指定渠道")
 		}
 	}
-	c.Next()
+	return http.StatusOK, nil
 }
 
 // 检测是否IP白名单
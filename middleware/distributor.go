@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"net/http"
 	"one-api/model"
+	"one-api/relay/relay_util"
+	"one-api/tracing"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // GroupDistributor 统一分组分发逻辑
@@ -60,10 +63,16 @@ func (gd *GroupDistributor) setGroupRatio(group string) error {
 
 func Distribute() func(c *gin.Context) {
 	return func(c *gin.Context) {
+		_, span := tracing.Start(c.Request.Context(), "middleware.distribute", tracing.GinAttributes(c)...)
 		distributor := NewGroupDistributor(c)
-		if err := distributor.SetupGroups(); err != nil {
+		err := distributor.SetupGroups()
+		span.SetAttributes(attribute.String("group", c.GetString("token_group")))
+		tracing.End(span, err)
+		if err != nil {
 			return
 		}
+		// 请求结束时释放占用的渠道并发名额
+		defer relay_util.ReleaseChannelSlots(c)
 		c.Next()
 	}
 }
@@ -3,10 +3,11 @@ package model
 import (
 	"errors"
 	"fmt"
-	"math/rand"
 	"one-api/common/config"
+	"one-api/common/limit"
 	"one-api/common/logger"
 	"one-api/common/utils"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -14,17 +15,17 @@ import (
 )
 
 type ChannelChoice struct {
-	Channel       *Channel
-	CooldownsTime int64
-	Disable       bool
+	Channel           *Channel
+	CooldownsTime     int64
+	Disable           bool
+	FingerprintScores map[string]float64 // 模型 -> 指纹检测分数
 }
 
 type ChannelsChooser struct {
 	sync.RWMutex
-	Channels  map[int]*ChannelChoice
-	Rule      map[string]map[string][][]int // group -> model -> priority -> channelIds
-	Match     []string
-	Cooldowns sync.Map
+	Channels map[int]*ChannelChoice
+	Rule     map[string]map[string][][]int // group -> model -> priority -> channelIds
+	Match    []string
 
 	ModelGroup map[string]map[string]bool
 }
@@ -55,52 +56,90 @@ func FilterDisabledStream(modelName string) ChannelsFilterFunc {
 	}
 }
 
-func init() {
-	// 每小时清理一次过期的冷却时间
-	go func() {
-		ticker := time.NewTicker(1 * time.Hour)
-		for range ticker.C {
-			ChannelGroup.CleanupExpiredCooldowns()
+func FilterDisabledCapabilities(modelName string, capabilities []string) ChannelsFilterFunc {
+	return func(_ int, choice *ChannelChoice) bool {
+		for _, capability := range capabilities {
+			if !choice.Channel.AllowCapability(modelName, capability) {
+				return true
+			}
 		}
-	}()
+		return false
+	}
 }
 
-func (cc *ChannelsChooser) SetCooldowns(channelId int, modelName string) bool {
-	if channelId == 0 || modelName == "" || config.RetryCooldownSeconds == 0 {
-		return false
+// FilterChannelFingerprint 跳过指纹检测分数低于阈值的渠道，未检测过的渠道不受影响
+func FilterChannelFingerprint(modelName string, minScore float64) ChannelsFilterFunc {
+	return func(_ int, choice *ChannelChoice) bool {
+		score, ok := choice.FingerprintScores[modelName]
+		return ok && score < minScore
 	}
+}
+
+// ChannelTPMKey 渠道 TPM 统计的 key
+func ChannelTPMKey(channelId int) string {
+	return fmt.Sprintf("channel_tpm:%d", channelId)
+}
 
-	key := fmt.Sprintf("%d:%s", channelId, modelName)
-	nowTime := time.Now().Unix()
+// FilterChannelTPM 跳过当前分钟已用满上游 TPM 的渠道，避免上游返回 429
+// onSaturated 在跳过时调用，用于判断是否需要排队
+func FilterChannelTPM(onSaturated func()) ChannelsFilterFunc {
+	return func(channelId int, choice *ChannelChoice) bool {
+		if choice.Channel.TPM <= 0 {
+			return false
+		}
 
-	cooldownTime, exists := cc.Cooldowns.Load(key)
-	if exists && nowTime < cooldownTime.(int64) {
-		return true
+		used, err := limit.GlobalTPMLimiter.GetCurrentUsage(ChannelTPMKey(channelId))
+		if err != nil {
+			return false
+		}
+
+		if used >= choice.Channel.TPM {
+			if onSaturated != nil {
+				onSaturated()
+			}
+			return true
+		}
+		return false
 	}
+}
 
-	cc.Cooldowns.LoadOrStore(key, nowTime+int64(config.RetryCooldownSeconds))
-	return true
+// ChannelConcurrencyKey 渠道并发名额的 key
+func ChannelConcurrencyKey(channelId int) string {
+	return fmt.Sprintf("channel_concurrency:%d", channelId)
 }
 
-func (cc *ChannelsChooser) IsInCooldown(channelId int, modelName string) bool {
-	key := fmt.Sprintf("%d:%s", channelId, modelName)
+// FilterChannelConcurrency 跳过并发已用满的渠道，onSaturated 在跳过时调用，用于判断是否需要排队
+func FilterChannelConcurrency(onSaturated func()) ChannelsFilterFunc {
+	return func(channelId int, choice *ChannelChoice) bool {
+		if choice.Channel.MaxConcurrency <= 0 {
+			return false
+		}
+
+		count, err := limit.GlobalConcurrencyLimiter.Count(ChannelConcurrencyKey(channelId))
+		if err != nil {
+			return false
+		}
 
-	cooldownTime, exists := cc.Cooldowns.Load(key)
-	if !exists {
+		if count >= choice.Channel.MaxConcurrency {
+			if onSaturated != nil {
+				onSaturated()
+			}
+			return true
+		}
 		return false
 	}
-
-	return time.Now().Unix() < cooldownTime.(int64)
 }
 
-func (cc *ChannelsChooser) CleanupExpiredCooldowns() {
-	now := time.Now().Unix()
-	cc.Cooldowns.Range(func(key, value interface{}) bool {
-		if now >= value.(int64) {
-			cc.Cooldowns.Delete(key)
+func init() {
+	// 每小时清理一次长时间未使用的熔断器
+	go func() {
+		ticker := time.NewTicker(1 * time.Hour)
+		for range ticker.C {
+			CircuitBreakers.CleanupExpired()
 		}
-		return true
-	})
+	}()
+
+	go SyncCircuitBreakers()
 }
 
 func (cc *ChannelsChooser) Disable(channelId int) {
@@ -131,9 +170,8 @@ func (cc *ChannelsChooser) ChangeStatus(channelId int, status bool) {
 	}
 }
 
-func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFunc, modelName string) *Channel {
-	totalWeight := 0
-
+// acquire 为 false 时只查看会选中的渠道，不占用半开熔断器的探测名额
+func (cc *ChannelsChooser) balancer(group string, channelIds []int, filters []ChannelsFilterFunc, modelName string, acquire bool) *Channel {
 	validChannels := make([]*ChannelChoice, 0, len(channelIds))
 	for _, channelId := range channelIds {
 		choice, ok := cc.Channels[channelId]
@@ -141,7 +179,7 @@ func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFu
 			continue
 		}
 
-		if cc.IsInCooldown(channelId, modelName) {
+		if !CircuitBreakers.Available(channelId, modelName) {
 			continue
 		}
 
@@ -156,8 +194,6 @@ func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFu
 			continue
 		}
 
-		weight := int(*choice.Channel.Weight)
-		totalWeight += weight
 		validChannels = append(validChannels, choice)
 	}
 
@@ -165,23 +201,36 @@ func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFu
 		return nil
 	}
 
-	if len(validChannels) == 1 {
-		return validChannels[0].Channel
-	}
+	for len(validChannels) > 0 {
+		channel := pickBalanceChannel(group, validChannels, modelName)
+		if !acquire {
+			return channel
+		}
 
-	choiceWeight := rand.Intn(totalWeight)
-	for _, choice := range validChannels {
-		weight := int(*choice.Channel.Weight)
-		choiceWeight -= weight
-		if choiceWeight < 0 {
-			return choice.Channel
+		// 半开状态的熔断器探测名额可能已被其他请求占用，换一个渠道
+		if CircuitBreakers.Acquire(channel.Id, modelName) {
+			return channel
 		}
+
+		validChannels = slices.DeleteFunc(validChannels, func(choice *ChannelChoice) bool {
+			return choice.Channel.Id == channel.Id
+		})
 	}
 
 	return nil
 }
 
+// Next 选择一个渠道，选中半开状态的渠道时占用探测名额，请求结束后需要记录结果或释放
 func (cc *ChannelsChooser) Next(group, modelName string, filters ...ChannelsFilterFunc) (*Channel, error) {
+	return cc.next(group, modelName, true, filters)
+}
+
+// Peek 查看会选中的渠道，不占用探测名额，用于不会向渠道发送请求的查询
+func (cc *ChannelsChooser) Peek(group, modelName string, filters ...ChannelsFilterFunc) (*Channel, error) {
+	return cc.next(group, modelName, false, filters)
+}
+
+func (cc *ChannelsChooser) next(group, modelName string, acquire bool, filters []ChannelsFilterFunc) (*Channel, error) {
 	cc.RLock()
 	defer cc.RUnlock()
 	if _, ok := cc.Rule[group]; !ok {
@@ -202,7 +251,7 @@ func (cc *ChannelsChooser) Next(group, modelName string, filters ...ChannelsFilt
 	}
 
 	for _, priority := range channelsPriority {
-		channel := cc.balancer(priority, filters, modelName)
+		channel := cc.balancer(group, priority, filters, modelName, acquire)
 		if channel != nil {
 			return channel, nil
 		}
@@ -250,6 +299,7 @@ var ChannelGroup = ChannelsChooser{}
 func (cc *ChannelsChooser) Load() {
 	var channels []*Channel
 	DB.Where("status = ?", config.ChannelStatusEnabled).Find(&channels)
+	fingerprintScores := getChannelFingerprintScores()
 
 	newGroup := make(map[string]map[string][][]int)
 	newChannels := make(map[int]*ChannelChoice)
@@ -269,9 +319,10 @@ func (cc *ChannelsChooser) Load() {
 			channel.Weight = &config.DefaultChannelWeight
 		}
 		newChannels[channel.Id] = &ChannelChoice{
-			Channel:       channel,
-			CooldownsTime: 0,
-			Disable:       false,
+			Channel:           channel,
+			CooldownsTime:     0,
+			Disable:           false,
+			FingerprintScores: fingerprintScores[channel.Id],
 		}
 
 		// 处理groups和models
@@ -0,0 +1,291 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"sync"
+)
+
+const (
+	BalanceStrategyWeighted      = "weighted"       // 按权重随机
+	BalanceStrategyLatency       = "latency"        // 按延迟 EWMA 加权
+	BalanceStrategyLeastInFlight = "least_inflight" // 最少进行中请求
+	BalanceStrategySuccessRate   = "success_rate"   // 按成功率加权
+)
+
+// 没有统计数据时使用的默认延迟，单位毫秒
+const defaultBalanceLatency = 3000.0
+
+// 成功率最低按该值计算，避免渠道永远拿不到流量而无法恢复
+const minBalanceSuccessRate = 0.05
+
+type BalanceStrategy interface {
+	Pick(choices []*ChannelChoice, modelName string) *Channel
+}
+
+var balanceStrategies = map[string]BalanceStrategy{
+	BalanceStrategyWeighted:      &weightedStrategy{},
+	BalanceStrategyLatency:       &latencyStrategy{},
+	BalanceStrategyLeastInFlight: &leastInFlightStrategy{},
+	BalanceStrategySuccessRate:   &successRateStrategy{},
+}
+
+func IsValidBalanceStrategy(name string) bool {
+	if name == "" {
+		return true
+	}
+	_, ok := balanceStrategies[name]
+	return ok
+}
+
+func GetBalanceStrategy(name string) BalanceStrategy {
+	if strategy, ok := balanceStrategies[name]; ok {
+		return strategy
+	}
+
+	return balanceStrategies[BalanceStrategyWeighted]
+}
+
+var (
+	balanceStrategyTags     = make(map[string]string)
+	balanceStrategyTagsLock sync.RWMutex
+)
+
+func BalanceStrategyTags2JSONString() string {
+	balanceStrategyTagsLock.RLock()
+	defer balanceStrategyTagsLock.RUnlock()
+
+	jsonBytes, err := json.Marshal(balanceStrategyTags)
+	if err != nil {
+		logger.SysError("error marshalling balance strategy tags: " + err.Error())
+		return "{}"
+	}
+	return string(jsonBytes)
+}
+
+func UpdateBalanceStrategyTagsByJSONString(jsonStr string) error {
+	newTags := make(map[string]string)
+	if jsonStr != "" {
+		if err := json.Unmarshal([]byte(jsonStr), &newTags); err != nil {
+			return err
+		}
+	}
+	for tag, name := range newTags {
+		if !IsValidBalanceStrategy(name) {
+			return fmt.Errorf("标签 %s 的负载均衡策略 %s 不存在", tag, name)
+		}
+	}
+
+	balanceStrategyTagsLock.Lock()
+	defer balanceStrategyTagsLock.Unlock()
+	balanceStrategyTags = newTags
+	return nil
+}
+
+func getBalanceStrategyByTag(tag string) string {
+	balanceStrategyTagsLock.RLock()
+	defer balanceStrategyTagsLock.RUnlock()
+
+	return balanceStrategyTags[tag]
+}
+
+// 优先级：渠道标签 > 用户分组 > 全局设置
+func defaultBalanceStrategy(group string) BalanceStrategy {
+	if userGroup := GlobalUserGroupRatio.GetBySymbol(group); userGroup != nil && userGroup.BalanceStrategy != "" {
+		return GetBalanceStrategy(userGroup.BalanceStrategy)
+	}
+
+	return GetBalanceStrategy(config.ChannelBalanceStrategy)
+}
+
+type balanceSubset struct {
+	strategy BalanceStrategy
+	choices  []*ChannelChoice
+}
+
+// splitBalanceSubsets 按标签拆分同一优先级下的渠道，设置了策略的标签单独成组，其余渠道使用分组或全局策略
+func splitBalanceSubsets(group string, choices []*ChannelChoice) []*balanceSubset {
+	subsets := make([]*balanceSubset, 0, 1)
+	tagSubsets := make(map[string]*balanceSubset)
+	var defaultSubset *balanceSubset
+
+	for _, choice := range choices {
+		tag := choice.Channel.Tag
+		name := ""
+		if tag != "" {
+			name = getBalanceStrategyByTag(tag)
+		}
+
+		if name == "" {
+			if defaultSubset == nil {
+				defaultSubset = &balanceSubset{strategy: defaultBalanceStrategy(group)}
+				subsets = append(subsets, defaultSubset)
+			}
+			defaultSubset.choices = append(defaultSubset.choices, choice)
+			continue
+		}
+
+		subset, ok := tagSubsets[tag]
+		if !ok {
+			subset = &balanceSubset{strategy: GetBalanceStrategy(name)}
+			tagSubsets[tag] = subset
+			subsets = append(subsets, subset)
+		}
+		subset.choices = append(subset.choices, choice)
+	}
+
+	return subsets
+}
+
+// pickBalanceChannel 先按权重之和选出一个标签组，再用该组的策略选出渠道
+func pickBalanceChannel(group string, choices []*ChannelChoice, modelName string) *Channel {
+	if len(choices) == 1 {
+		return choices[0].Channel
+	}
+
+	subsets := splitBalanceSubsets(group, choices)
+	subset := subsets[0]
+	if len(subsets) > 1 {
+		weights := make([]float64, len(subsets))
+		total := 0.0
+		for i, item := range subsets {
+			for _, choice := range item.choices {
+				weights[i] += channelWeight(choice)
+			}
+			total += weights[i]
+		}
+
+		point := rand.Float64() * total
+		for i, weight := range weights {
+			point -= weight
+			if point < 0 {
+				subset = subsets[i]
+				break
+			}
+		}
+	}
+
+	if len(subset.choices) == 1 {
+		return subset.choices[0].Channel
+	}
+	return subset.strategy.Pick(subset.choices, modelName)
+}
+
+func channelWeight(choice *ChannelChoice) float64 {
+	if choice.Channel.Weight == nil || *choice.Channel.Weight == 0 {
+		return float64(config.DefaultChannelWeight)
+	}
+	return float64(*choice.Channel.Weight)
+}
+
+func pickByScore(choices []*ChannelChoice, scores []float64) *Channel {
+	total := 0.0
+	for _, score := range scores {
+		total += score
+	}
+
+	if total <= 0 {
+		return choices[rand.Intn(len(choices))].Channel
+	}
+
+	point := rand.Float64() * total
+	for i, score := range scores {
+		point -= score
+		if point < 0 {
+			return choices[i].Channel
+		}
+	}
+
+	return choices[len(choices)-1].Channel
+}
+
+type weightedStrategy struct{}
+
+func (s *weightedStrategy) Pick(choices []*ChannelChoice, _ string) *Channel {
+	scores := make([]float64, len(choices))
+	for i, choice := range choices {
+		scores[i] = channelWeight(choice)
+	}
+
+	return pickByScore(choices, scores)
+}
+
+// latencyStrategy 流量与延迟成反比，流式请求更关心首字时间，因此优先使用首字耗时
+type latencyStrategy struct{}
+
+func (s *latencyStrategy) Pick(choices []*ChannelChoice, modelName string) *Channel {
+	latencies := make([]float64, len(choices))
+	known := 0.0
+	knownCount := 0
+	for i, choice := range choices {
+		stats := ChannelStats.Get(choice.Channel.Id, modelName)
+		latency := stats.FirstResponse
+		if latency == 0 {
+			latency = stats.Latency
+		}
+		if latency == 0 && choice.Channel.ResponseTime > 0 {
+			latency = float64(choice.Channel.ResponseTime)
+		}
+		if latency > 0 {
+			known += latency
+			knownCount++
+		}
+		latencies[i] = latency
+	}
+
+	// 没有数据的渠道按平均值计算，让它有机会被探测到
+	fallback := defaultBalanceLatency
+	if knownCount > 0 {
+		fallback = known / float64(knownCount)
+	}
+
+	scores := make([]float64, len(choices))
+	for i, choice := range choices {
+		latency := latencies[i]
+		if latency <= 0 {
+			latency = fallback
+		}
+		scores[i] = channelWeight(choice) / latency
+	}
+
+	return pickByScore(choices, scores)
+}
+
+type leastInFlightStrategy struct{}
+
+func (s *leastInFlightStrategy) Pick(choices []*ChannelChoice, modelName string) *Channel {
+	var candidates []*ChannelChoice
+	minLoad := -1.0
+	for _, choice := range choices {
+		stats := ChannelStats.Get(choice.Channel.Id, modelName)
+		// 按权重归一化，权重越高能承受的并发越多
+		load := float64(stats.InFlight) / channelWeight(choice)
+		switch {
+		case minLoad < 0 || load < minLoad:
+			minLoad = load
+			candidates = []*ChannelChoice{choice}
+		case load == minLoad:
+			candidates = append(candidates, choice)
+		}
+	}
+
+	return candidates[rand.Intn(len(candidates))].Channel
+}
+
+type successRateStrategy struct{}
+
+func (s *successRateStrategy) Pick(choices []*ChannelChoice, modelName string) *Channel {
+	scores := make([]float64, len(choices))
+	for i, choice := range choices {
+		rate := ChannelStats.Get(choice.Channel.Id, modelName).SuccessRate
+		if rate < minBalanceSuccessRate {
+			rate = minBalanceSuccessRate
+		}
+		scores[i] = channelWeight(choice) * rate * rate
+	}
+
+	return pickByScore(choices, scores)
+}
@@ -0,0 +1,150 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestChoice(id int, tag string, weight uint) *ChannelChoice {
+	return &ChannelChoice{
+		Channel: &Channel{
+			Id:     id,
+			Tag:    tag,
+			Weight: &weight,
+		},
+	}
+}
+
+func TestSplitBalanceSubsets(t *testing.T) {
+	assert.NoError(t, UpdateBalanceStrategyTagsByJSONString(`{"fast":"latency","busy":"least_inflight"}`))
+	defer UpdateBalanceStrategyTagsByJSONString("")
+
+	cases := []struct {
+		name     string
+		choices  []*ChannelChoice
+		expected [][]int
+	}{
+		{
+			name:     "没有标签",
+			choices:  []*ChannelChoice{newTestChoice(1, "", 1), newTestChoice(2, "", 1)},
+			expected: [][]int{{1, 2}},
+		},
+		{
+			name:     "同一标签",
+			choices:  []*ChannelChoice{newTestChoice(1, "fast", 1), newTestChoice(2, "fast", 1)},
+			expected: [][]int{{1, 2}},
+		},
+		{
+			name: "混合标签",
+			choices: []*ChannelChoice{
+				newTestChoice(1, "fast", 1),
+				newTestChoice(2, "", 1),
+				newTestChoice(3, "busy", 1),
+				newTestChoice(4, "fast", 1),
+				newTestChoice(5, "other", 1),
+			},
+			expected: [][]int{{1, 4}, {2, 5}, {3}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			subsets := splitBalanceSubsets("", c.choices)
+			actual := make([][]int, 0, len(subsets))
+			for _, subset := range subsets {
+				ids := make([]int, 0, len(subset.choices))
+				for _, choice := range subset.choices {
+					ids = append(ids, choice.Channel.Id)
+				}
+				actual = append(actual, ids)
+			}
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+}
+
+func TestSplitBalanceSubsetsStrategy(t *testing.T) {
+	assert.NoError(t, UpdateBalanceStrategyTagsByJSONString(`{"fast":"latency"}`))
+	defer UpdateBalanceStrategyTagsByJSONString("")
+
+	subsets := splitBalanceSubsets("", []*ChannelChoice{newTestChoice(1, "fast", 1), newTestChoice(2, "", 1)})
+	assert.Len(t, subsets, 2)
+	assert.IsType(t, &latencyStrategy{}, subsets[0].strategy)
+	assert.IsType(t, &weightedStrategy{}, subsets[1].strategy)
+}
+
+func TestUpdateBalanceStrategyTagsInvalid(t *testing.T) {
+	assert.Error(t, UpdateBalanceStrategyTagsByJSONString(`{"fast":"unknown"}`))
+	assert.Error(t, UpdateBalanceStrategyTagsByJSONString(`not json`))
+	assert.NoError(t, UpdateBalanceStrategyTagsByJSONString(""))
+}
+
+func TestPickByScore(t *testing.T) {
+	choices := []*ChannelChoice{newTestChoice(1, "", 1), newTestChoice(2, "", 1), newTestChoice(3, "", 1)}
+
+	cases := []struct {
+		name     string
+		scores   []float64
+		expected int
+	}{
+		{"只有第一个", []float64{1, 0, 0}, 1},
+		{"只有中间", []float64{0, 5, 0}, 2},
+		{"只有最后", []float64{0, 0, 0.1}, 3},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				assert.Equal(t, c.expected, pickByScore(choices, c.scores).Id)
+			}
+		})
+	}
+}
+
+func TestLeastInFlightStrategy(t *testing.T) {
+	const modelName = "test-least-inflight"
+	choices := []*ChannelChoice{newTestChoice(101, "", 1), newTestChoice(102, "", 1), newTestChoice(103, "", 2)}
+
+	releases := []func(){
+		ChannelStats.Acquire(101, modelName),
+		ChannelStats.Acquire(101, modelName),
+		ChannelStats.Acquire(102, modelName),
+		ChannelStats.Acquire(103, modelName),
+	}
+	defer func() {
+		for _, release := range releases {
+			release()
+		}
+	}()
+
+	// 103 的权重是 2，归一化后负载为 0.5，低于 101(2) 和 102(1)
+	strategy := &leastInFlightStrategy{}
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, 103, strategy.Pick(choices, modelName).Id)
+	}
+}
+
+func TestSuccessRateStrategy(t *testing.T) {
+	const modelName = "test-success-rate"
+	choices := []*ChannelChoice{newTestChoice(201, "", 1), newTestChoice(202, "", 1)}
+
+	for i := 0; i < 50; i++ {
+		ChannelStats.Record(201, modelName, ChannelOutcome{Success: false, Latency: time.Second})
+		ChannelStats.Record(202, modelName, ChannelOutcome{Success: true, Latency: time.Second})
+	}
+
+	strategy := &successRateStrategy{}
+	picked := map[int]int{}
+	for i := 0; i < 1000; i++ {
+		picked[strategy.Pick(choices, modelName).Id]++
+	}
+	// 失败的渠道成功率按最低值计算，只会拿到极少的流量
+	assert.Greater(t, picked[202], 950)
+}
+
+func TestPickBalanceChannelSingle(t *testing.T) {
+	choices := []*ChannelChoice{newTestChoice(301, "", 1)}
+	assert.Equal(t, 301, pickBalanceChannel("", choices, "test-single").Id)
+}
@@ -0,0 +1,200 @@
+package model
+
+import (
+	"errors"
+	"one-api/common/utils"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	BatchFilePurposeBatch       = "batch"
+	BatchFilePurposeBatchOutput = "batch_output"
+)
+
+const (
+	BatchStatusValidating = "validating"
+	BatchStatusFailed     = "failed"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+// BatchFile 批处理的输入输出文件，内容包含用户的请求和结果，只保存在数据库中
+type BatchFile struct {
+	ID        int64  `json:"-" gorm:"primary_key;AUTO_INCREMENT"`
+	FileID    string `json:"id" gorm:"type:varchar(50);uniqueIndex"`
+	UserId    int    `json:"-" gorm:"index"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose" gorm:"type:varchar(30);index"`
+	Bytes     int    `json:"bytes"`
+	CreatedAt int64  `json:"created_at" gorm:"index"`
+}
+
+// BatchFileChunk 批处理文件的内容，按块保存避免单行数据超出数据库的包大小限制
+type BatchFileChunk struct {
+	ID     int64  `gorm:"primary_key;AUTO_INCREMENT"`
+	FileID string `gorm:"type:varchar(50);index"`
+	Seq    int
+	Data   []byte
+}
+
+const batchFileChunkSize = 4 << 20
+
+// Insert 保存文件信息和内容
+func (f *BatchFile) Insert(data []byte) error {
+	if f.CreatedAt == 0 {
+		f.CreatedAt = utils.GetTimestamp()
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(f).Error; err != nil {
+			return err
+		}
+
+		for seq := 0; seq*batchFileChunkSize < len(data); seq++ {
+			end := min((seq+1)*batchFileChunkSize, len(data))
+			chunk := &BatchFileChunk{
+				FileID: f.FileID,
+				Seq:    seq,
+				Data:   data[seq*batchFileChunkSize : end],
+			}
+			if err := tx.Create(chunk).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetBatchFileContent 读取文件内容
+func GetBatchFileContent(fileId string) ([]byte, error) {
+	var chunks []*BatchFileChunk
+	if err := DB.Where("file_id = ?", fileId).Order("seq").Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+
+	size := 0
+	for _, chunk := range chunks {
+		size += len(chunk.Data)
+	}
+	data := make([]byte, 0, size)
+	for _, chunk := range chunks {
+		data = append(data, chunk.Data...)
+	}
+
+	return data, nil
+}
+
+func GetBatchFile(userId int, fileId string) (*BatchFile, error) {
+	file := &BatchFile{}
+	err := DB.Where("user_id = ? AND file_id = ?", userId, fileId).First(file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return file, err
+}
+
+func GetUserBatchFiles(userId int, purpose string, limit int) (files []*BatchFile, err error) {
+	tx := DB.Where("user_id = ?", userId)
+	if purpose != "" {
+		tx = tx.Where("purpose = ?", purpose)
+	}
+	err = tx.Order("id desc").Limit(limit).Find(&files).Error
+
+	return
+}
+
+func DeleteBatchFile(userId int, fileId string) (deleted bool, err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND file_id = ?", userId, fileId).Delete(&BatchFile{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		deleted = true
+
+		return tx.Where("file_id = ?", fileId).Delete(&BatchFileChunk{}).Error
+	})
+
+	return
+}
+
+// Batch 本地执行的批处理任务
+type Batch struct {
+	ID               int64          `json:"-" gorm:"primary_key;AUTO_INCREMENT"`
+	BatchID          string         `json:"id" gorm:"type:varchar(50);uniqueIndex"`
+	UserId           int            `json:"-" gorm:"index"`
+	TokenId          int            `json:"-" gorm:"index"`
+	Endpoint         string         `json:"endpoint" gorm:"type:varchar(50)"`
+	InputFileID      string         `json:"input_file_id" gorm:"type:varchar(50)"`
+	OutputFileID     string         `json:"output_file_id,omitempty" gorm:"type:varchar(50)"`
+	ErrorFileID      string         `json:"error_file_id,omitempty" gorm:"type:varchar(50)"`
+	CompletionWindow string         `json:"completion_window" gorm:"type:varchar(20)"`
+	Status           string         `json:"status" gorm:"type:varchar(20);index"`
+	Errors           datatypes.JSON `json:"errors,omitempty" gorm:"type:json"`
+	Metadata         datatypes.JSON `json:"metadata,omitempty" gorm:"type:json"`
+	Total            int            `json:"-"`
+	Completed        int            `json:"-"`
+	Failed           int            `json:"-"`
+	CreatedAt        int64          `json:"created_at" gorm:"index"`
+	InProgressAt     int64          `json:"in_progress_at,omitempty"`
+	ExpiresAt        int64          `json:"expires_at,omitempty"`
+	FinalizingAt     int64          `json:"finalizing_at,omitempty"`
+	CompletedAt      int64          `json:"completed_at,omitempty"`
+	FailedAt         int64          `json:"failed_at,omitempty"`
+	ExpiredAt        int64          `json:"expired_at,omitempty"`
+	CancellingAt     int64          `json:"cancelling_at,omitempty"`
+	CancelledAt      int64          `json:"cancelled_at,omitempty"`
+}
+
+func (b *Batch) Insert() error {
+	if b.CreatedAt == 0 {
+		b.CreatedAt = utils.GetTimestamp()
+	}
+	return DB.Create(b).Error
+}
+
+func GetBatch(userId int, batchId string) (*Batch, error) {
+	batch := &Batch{}
+	err := DB.Where("user_id = ? AND batch_id = ?", userId, batchId).First(batch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return batch, err
+}
+
+func GetUserBatches(userId int, afterId string, limit int) (batches []*Batch, err error) {
+	tx := DB.Where("user_id = ?", userId)
+	if afterId != "" {
+		var after Batch
+		if err = DB.Select("id").Where("user_id = ? AND batch_id = ?", userId, afterId).First(&after).Error; err == nil {
+			tx = tx.Where("id < ?", after.ID)
+		}
+	}
+	err = tx.Order("id desc").Limit(limit).Find(&batches).Error
+
+	return
+}
+
+func GetBatchesByStatus(status string, limit int) (batches []*Batch, err error) {
+	err = DB.Where("status = ?", status).Order("id asc").Limit(limit).Find(&batches).Error
+	return
+}
+
+func GetBatchStatus(batchId string) (string, error) {
+	var batch Batch
+	err := DB.Select("status").Where("batch_id = ?", batchId).First(&batch).Error
+	return batch.Status, err
+}
+
+// UpdateBatchStatus 只有当前状态为 fromStatus 时才更新，用于抢占任务和状态流转
+func UpdateBatchStatus(batchId, fromStatus string, updates map[string]any) (bool, error) {
+	result := DB.Model(&Batch{}).Where("batch_id = ? AND status = ?", batchId, fromStatus).Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
@@ -39,12 +39,70 @@ type Channel struct {
 	TestModel          string  `json:"test_model" form:"test_model" gorm:"type:varchar(50);default:''"`
 	OnlyChat           bool    `json:"only_chat" form:"only_chat" gorm:"default:false"`
 	PreCost            int     `json:"pre_cost" form:"pre_cost" gorm:"default:1"`
+	TPM                int     `json:"tpm" form:"tpm" gorm:"default:0"`                         // 上游每分钟允许的 token 数，为 0 则不限制
+	MaxConcurrency     int     `json:"max_concurrency" form:"max_concurrency" gorm:"default:0"` // 同时发往上游的最大请求数，为 0 则不限制
 	CompatibleResponse bool    `json:"compatible_response" gorm:"default:false"`
 
 	DisabledStream *datatypes.JSONSlice[string] `json:"disabled_stream,omitempty" gorm:"type:json"`
+	// 探测失败后按模型禁用的能力，如 {"gpt-4o": ["tool", "vision"]}
+	DisabledCapabilities *datatypes.JSONType[map[string][]string] `json:"disabled_capabilities,omitempty" gorm:"type:json"`
 
 	Plugin    *datatypes.JSONType[PluginType] `json:"plugin" form:"plugin" gorm:"type:json"`
 	DeletedAt gorm.DeletedAt                  `json:"-" gorm:"index"`
+
+	CircuitBreakers []CircuitBreakerSnapshot `json:"circuit_breakers,omitempty" gorm:"-"`
+}
+
+const (
+	ChannelCapabilityTool   = "tool"
+	ChannelCapabilityJSON   = "json"
+	ChannelCapabilityVision = "vision"
+)
+
+func (c *Channel) AllowCapability(modelName, capability string) bool {
+	if c.DisabledCapabilities == nil {
+		return true
+	}
+
+	return !slices.Contains(c.DisabledCapabilities.Data()[modelName], capability)
+}
+
+// SetChannelCapabilityDisabled 禁用或恢复渠道中某个模型的单项能力，返回是否有变化
+func SetChannelCapabilityDisabled(channelId int, modelName, capability string, disabled bool) (bool, error) {
+	channel, err := GetChannelById(channelId)
+	if err != nil {
+		return false, err
+	}
+
+	if channel.AllowCapability(modelName, capability) != disabled {
+		return false, nil
+	}
+
+	capabilities := make(map[string][]string)
+	if channel.DisabledCapabilities != nil {
+		for name, list := range channel.DisabledCapabilities.Data() {
+			capabilities[name] = slices.Clone(list)
+		}
+	}
+
+	if disabled {
+		capabilities[modelName] = append(capabilities[modelName], capability)
+	} else {
+		capabilities[modelName] = slices.DeleteFunc(capabilities[modelName], func(item string) bool {
+			return item == capability
+		})
+		if len(capabilities[modelName]) == 0 {
+			delete(capabilities, modelName)
+		}
+	}
+
+	err = DB.Model(&Channel{}).Where("id = ?", channelId).Update("disabled_capabilities", datatypes.NewJSONType(capabilities)).Error
+	if err != nil {
+		return false, err
+	}
+	ChannelGroup.Load()
+
+	return true, nil
 }
 
 func (c *Channel) AllowStream(modelName string) bool {
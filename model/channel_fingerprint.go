@@ -0,0 +1,79 @@
+package model
+
+import (
+	"errors"
+	"one-api/common/utils"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ChannelFingerprint 渠道中模型的指纹检测结果，分数越低越可能是被替换的模型
+type ChannelFingerprint struct {
+	Id        int                                         `json:"id"`
+	ChannelId int                                         `json:"channel_id" gorm:"uniqueIndex:idx_channel_fingerprint"`
+	Model     string                                      `json:"model" gorm:"type:varchar(100);uniqueIndex:idx_channel_fingerprint"`
+	Score     float64                                     `json:"score"`   // 0-1 的置信度
+	Samples   int                                         `json:"samples"` // 参与计分的检测项数量，为 0 时分数无意义
+	Details   datatypes.JSONSlice[ChannelFingerprintItem] `json:"details" gorm:"type:json"`
+	UpdatedAt int64                                       `json:"updated_at" gorm:"bigint"`
+}
+
+type ChannelFingerprintItem struct {
+	Process string `json:"process"`
+	Name    string `json:"name"`
+	Status  int    `json:"status"`
+	Weight  int    `json:"weight"`
+	Remark  string `json:"remark"`
+}
+
+func GetChannelFingerprints(channelId int) ([]*ChannelFingerprint, error) {
+	var fingerprints []*ChannelFingerprint
+	db := DB
+	if channelId > 0 {
+		db = db.Where("channel_id = ?", channelId)
+	}
+	err := db.Order("channel_id asc, model asc").Find(&fingerprints).Error
+	return fingerprints, err
+}
+
+// SaveChannelFingerprint 按渠道和模型保存检测结果，已存在时覆盖
+func SaveChannelFingerprint(fingerprint *ChannelFingerprint) error {
+	fingerprint.UpdatedAt = utils.GetTimestamp()
+
+	var exist ChannelFingerprint
+	err := DB.Where("channel_id = ? AND model = ?", fingerprint.ChannelId, fingerprint.Model).First(&exist).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DB.Create(fingerprint).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	fingerprint.Id = exist.Id
+	return DB.Select("score", "samples", "details", "updated_at").Updates(fingerprint).Error
+}
+
+func DeleteChannelFingerprint(channelId int, modelName string) error {
+	db := DB.Where("channel_id = ?", channelId)
+	if modelName != "" {
+		db = db.Where("model = ?", modelName)
+	}
+	return db.Delete(&ChannelFingerprint{}).Error
+}
+
+// getChannelFingerprintScores 获取有效的指纹分数，channelId -> model -> score
+func getChannelFingerprintScores() map[int]map[string]float64 {
+	var fingerprints []*ChannelFingerprint
+	DB.Select("channel_id", "model", "score").Where("samples > ?", 0).Find(&fingerprints)
+
+	scores := make(map[int]map[string]float64)
+	for _, fingerprint := range fingerprints {
+		if _, ok := scores[fingerprint.ChannelId]; !ok {
+			scores[fingerprint.ChannelId] = make(map[string]float64)
+		}
+		scores[fingerprint.ChannelId][fingerprint.Model] = fingerprint.Score
+	}
+
+	return scores
+}
@@ -0,0 +1,178 @@
+package model
+
+import (
+	"one-api/common/utils"
+	"time"
+)
+
+// ChannelProbe 定时对渠道的模型执行检测
+type ChannelProbe struct {
+	Id          int    `json:"id"`
+	ChannelId   int    `json:"channel_id" gorm:"index"`
+	Models      string `json:"models" gorm:"type:varchar(1024)"`                   // 逗号分隔
+	Processes   string `json:"processes" gorm:"type:varchar(255)"`                 // 逗号分隔的检测项，如 base,tool,json,vision
+	Interval    int    `json:"interval" gorm:"column:interval_minutes;default:60"` // 执行间隔，单位分钟
+	AutoDisable bool   `json:"auto_disable"`                                       // 检测失败时是否自动禁用对应能力
+	Enabled     bool   `json:"enabled"`
+	LastRunAt   int64  `json:"last_run_at" gorm:"bigint"`
+	CreatedAt   int64  `json:"created_at" gorm:"bigint"`
+}
+
+// ChannelProbeHistory 检测结果记录
+type ChannelProbeHistory struct {
+	Id           int    `json:"id"`
+	ProbeId      int    `json:"probe_id" gorm:"index"`
+	ChannelId    int    `json:"channel_id" gorm:"index:idx_probe_history_channel_model"`
+	Model        string `json:"model" gorm:"type:varchar(100);index:idx_probe_history_channel_model"`
+	Process      string `json:"process" gorm:"type:varchar(32)"`
+	Status       int    `json:"status"`
+	Remark       string `json:"remark" gorm:"type:text"`
+	Action       string `json:"action" gorm:"type:varchar(32);default:''"` // 自动执行的操作，如 disabled:tool
+	ResponseTime int64  `json:"response_time"`                             // 毫秒
+	CreatedAt    int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+type SearchChannelProbeHistoryParams struct {
+	ChannelId int    `form:"channel_id"`
+	Model     string `form:"model"`
+	Process   string `form:"process"`
+	Status    *int   `form:"status"`
+	PaginationParams
+}
+
+var allowedChannelProbeOrderFields = map[string]bool{
+	"id":          true,
+	"channel_id":  true,
+	"last_run_at": true,
+}
+
+var allowedChannelProbeHistoryOrderFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+}
+
+func GetChannelProbesList(channelId int, params *PaginationParams) (*DataResult[ChannelProbe], error) {
+	var probes []*ChannelProbe
+	db := DB
+
+	if channelId > 0 {
+		db = db.Where("channel_id = ?", channelId)
+	}
+
+	return PaginateAndOrder(db, params, &probes, allowedChannelProbeOrderFields)
+}
+
+func GetChannelProbeById(id int) (*ChannelProbe, error) {
+	var probe ChannelProbe
+	err := DB.Where("id = ?", id).First(&probe).Error
+	return &probe, err
+}
+
+func GetEnabledChannelProbes() ([]*ChannelProbe, error) {
+	var probes []*ChannelProbe
+	err := DB.Where("enabled = ?", true).Find(&probes).Error
+	return probes, err
+}
+
+func (p *ChannelProbe) Create() error {
+	p.CreatedAt = utils.GetTimestamp()
+	return DB.Create(p).Error
+}
+
+func (p *ChannelProbe) Update() error {
+	return DB.Select("channel_id", "models", "processes", "interval_minutes", "auto_disable", "enabled").Updates(p).Error
+}
+
+func (p *ChannelProbe) Delete() error {
+	return DB.Delete(p).Error
+}
+
+func UpdateChannelProbeLastRunAt(id int) {
+	DB.Model(&ChannelProbe{}).Where("id = ?", id).Update("last_run_at", utils.GetTimestamp())
+}
+
+func (h *ChannelProbeHistory) Insert() error {
+	if h.CreatedAt == 0 {
+		h.CreatedAt = utils.GetTimestamp()
+	}
+	return DB.Create(h).Error
+}
+
+func GetChannelProbeHistoryList(params *SearchChannelProbeHistoryParams) (*DataResult[ChannelProbeHistory], error) {
+	var histories []*ChannelProbeHistory
+	db := DB
+
+	if params.ChannelId > 0 {
+		db = db.Where("channel_id = ?", params.ChannelId)
+	}
+	if params.Model != "" {
+		db = db.Where("model = ?", params.Model)
+	}
+	if params.Process != "" {
+		db = db.Where("process = ?", params.Process)
+	}
+	if params.Status != nil {
+		db = db.Where("status = ?", *params.Status)
+	}
+
+	return PaginateAndOrder(db, &params.PaginationParams, &histories, allowedChannelProbeHistoryOrderFields)
+}
+
+// GetRecentChannelProbeStatus 获取最近 n 次检测结果的状态，最新的在前
+func GetRecentChannelProbeStatus(channelId int, modelName, process string, n int) ([]int, error) {
+	var statuses []int
+	err := DB.Model(&ChannelProbeHistory{}).
+		Where("channel_id = ? AND model = ? AND process = ?", channelId, modelName, process).
+		Order("id desc").Limit(n).Pluck("status", &statuses).Error
+	return statuses, err
+}
+
+type ChannelProbeTrend struct {
+	Date     string  `json:"date"`
+	Model    string  `json:"model"`
+	Process  string  `json:"process"`
+	Total    int     `json:"total"`
+	Passed   int     `json:"passed"`
+	PassRate float64 `json:"pass_rate"`
+}
+
+// GetChannelProbeTrend 按天统计检测通过率
+func GetChannelProbeTrend(channelId int, modelName string, days int, passedStatus int) ([]*ChannelProbeTrend, error) {
+	var histories []*ChannelProbeHistory
+	db := DB.Select("model", "process", "status", "created_at").
+		Where("channel_id = ? AND created_at >= ?", channelId, time.Now().AddDate(0, 0, -days).Unix())
+	if modelName != "" {
+		db = db.Where("model = ?", modelName)
+	}
+	if err := db.Order("created_at asc").Find(&histories).Error; err != nil {
+		return nil, err
+	}
+
+	trends := make([]*ChannelProbeTrend, 0)
+	index := make(map[string]*ChannelProbeTrend)
+	for _, history := range histories {
+		date := time.Unix(history.CreatedAt, 0).Format("2006-01-02")
+		key := date + "|" + history.Model + "|" + history.Process
+		trend, ok := index[key]
+		if !ok {
+			trend = &ChannelProbeTrend{Date: date, Model: history.Model, Process: history.Process}
+			index[key] = trend
+			trends = append(trends, trend)
+		}
+		trend.Total++
+		if history.Status == passedStatus {
+			trend.Passed++
+		}
+	}
+
+	for _, trend := range trends {
+		trend.PassRate = float64(trend.Passed) / float64(trend.Total)
+	}
+
+	return trends, nil
+}
+
+func DeleteOldChannelProbeHistory(timestamp int64) (int64, error) {
+	result := DB.Where("created_at < ?", timestamp).Delete(&ChannelProbeHistory{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,172 @@
+package model
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 指数加权平均的平滑系数，越大越偏向最近的请求
+const channelStatsEWMAAlpha = 0.2
+
+// 统计数据超过该时间未更新则视为过期，重新按默认值计算
+const channelStatsStaleSeconds = 30 * 60
+
+type ChannelOutcome struct {
+	Success       bool
+	Latency       time.Duration
+	FirstResponse time.Duration
+}
+
+type ChannelModelStats struct {
+	sync.Mutex
+	Latency       float64 // 总耗时 EWMA，单位毫秒
+	FirstResponse float64 // 首字耗时 EWMA，单位毫秒
+	SuccessRate   float64 // 成功率 EWMA，0~1
+	Requests      int64
+	UpdatedAt     int64
+
+	inFlight atomic.Int64
+}
+
+type ChannelModelStatsSnapshot struct {
+	Latency       float64 `json:"latency"`
+	FirstResponse float64 `json:"first_response"`
+	SuccessRate   float64 `json:"success_rate"`
+	Requests      int64   `json:"requests"`
+	InFlight      int64   `json:"in_flight"`
+	UpdatedAt     int64   `json:"updated_at"`
+}
+
+type ChannelStatsStore struct {
+	stats sync.Map // channelId:modelName -> *ChannelModelStats
+}
+
+var ChannelStats = &ChannelStatsStore{}
+
+func channelStatsKey(channelId int, modelName string) string {
+	return fmt.Sprintf("%d:%s", channelId, modelName)
+}
+
+func (s *ChannelStatsStore) get(channelId int, modelName string) *ChannelModelStats {
+	key := channelStatsKey(channelId, modelName)
+	if stats, ok := s.stats.Load(key); ok {
+		return stats.(*ChannelModelStats)
+	}
+
+	stats, _ := s.stats.LoadOrStore(key, &ChannelModelStats{SuccessRate: 1})
+	return stats.(*ChannelModelStats)
+}
+
+// Acquire 标记一个请求开始，返回的函数用于在请求结束时释放
+func (s *ChannelStatsStore) Acquire(channelId int, modelName string) func() {
+	if channelId == 0 || modelName == "" {
+		return func() {}
+	}
+
+	stats := s.get(channelId, modelName)
+	stats.inFlight.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			stats.inFlight.Add(-1)
+		})
+	}
+}
+
+// Record 记录一次请求结果
+func (s *ChannelStatsStore) Record(channelId int, modelName string, outcome ChannelOutcome) {
+	if channelId == 0 || modelName == "" {
+		return
+	}
+
+	stats := s.get(channelId, modelName)
+	stats.Lock()
+	defer stats.Unlock()
+
+	now := time.Now().Unix()
+	fresh := stats.Requests == 0 || now-stats.UpdatedAt > channelStatsStaleSeconds
+
+	success := 0.0
+	if outcome.Success {
+		success = 1
+	}
+
+	if fresh {
+		stats.SuccessRate = success
+		stats.Latency = 0
+		stats.FirstResponse = 0
+	} else {
+		stats.SuccessRate = ewma(stats.SuccessRate, success)
+	}
+
+	// 失败的请求耗时没有参考意义，只记录成功的
+	if outcome.Success {
+		if latency := float64(outcome.Latency.Milliseconds()); latency > 0 {
+			stats.Latency = ewmaOrInit(stats.Latency, latency)
+		}
+		if firstResponse := float64(outcome.FirstResponse.Milliseconds()); firstResponse > 0 {
+			stats.FirstResponse = ewmaOrInit(stats.FirstResponse, firstResponse)
+		}
+	}
+
+	stats.Requests++
+	stats.UpdatedAt = now
+}
+
+func (s *ChannelStatsStore) Get(channelId int, modelName string) ChannelModelStatsSnapshot {
+	stats := s.get(channelId, modelName)
+	stats.Lock()
+	defer stats.Unlock()
+
+	snapshot := ChannelModelStatsSnapshot{
+		SuccessRate: 1,
+		InFlight:    stats.inFlight.Load(),
+	}
+
+	if stats.Requests == 0 || time.Now().Unix()-stats.UpdatedAt > channelStatsStaleSeconds {
+		return snapshot
+	}
+
+	snapshot.Latency = stats.Latency
+	snapshot.FirstResponse = stats.FirstResponse
+	snapshot.SuccessRate = stats.SuccessRate
+	snapshot.Requests = stats.Requests
+	snapshot.UpdatedAt = stats.UpdatedAt
+
+	return snapshot
+}
+
+// GetAll 返回所有渠道/模型的统计数据，key 为 channelId:modelName
+func (s *ChannelStatsStore) GetAll() map[string]ChannelModelStatsSnapshot {
+	all := make(map[string]ChannelModelStatsSnapshot)
+	s.stats.Range(func(key, value any) bool {
+		stats := value.(*ChannelModelStats)
+		stats.Lock()
+		all[key.(string)] = ChannelModelStatsSnapshot{
+			Latency:       stats.Latency,
+			FirstResponse: stats.FirstResponse,
+			SuccessRate:   stats.SuccessRate,
+			Requests:      stats.Requests,
+			InFlight:      stats.inFlight.Load(),
+			UpdatedAt:     stats.UpdatedAt,
+		}
+		stats.Unlock()
+		return true
+	})
+
+	return all
+}
+
+func ewma(old, value float64) float64 {
+	return old*(1-channelStatsEWMAAlpha) + value*channelStatsEWMAAlpha
+}
+
+func ewmaOrInit(old, value float64) float64 {
+	if old == 0 {
+		return value
+	}
+	return ewma(old, value)
+}
@@ -0,0 +1,557 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/redis"
+	"one-api/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+func (s CircuitState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s *CircuitState) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	switch str {
+	case "open":
+		*s = CircuitOpen
+	case "half_open":
+		*s = CircuitHalfOpen
+	default:
+		*s = CircuitClosed
+	}
+	return nil
+}
+
+type CircuitFailureKind int
+
+const (
+	CircuitFailureError       CircuitFailureKind = iota // 一般的上游错误，如 5xx、超时
+	CircuitFailureRateLimited                           // 上游限流，直接熔断
+)
+
+const (
+	// 所有节点的熔断状态保存在同一个 hash 中，field 为 渠道id:模型
+	CircuitBreakerCacheKey = "circuit_breakers"
+	circuitSyncInterval    = 10 * time.Second
+	// 半开状态下的探测请求超过该时间未返回结果，则释放探测名额
+	circuitProbeTimeout = 60 * time.Second
+)
+
+type CircuitBreakerSnapshot struct {
+	ChannelId           int          `json:"channel_id"`
+	Model               string       `json:"model"`
+	State               CircuitState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	Trips               int          `json:"trips"`
+	OpenedAt            int64        `json:"opened_at"`
+	OpenUntil           int64        `json:"open_until"`
+	UpdatedAt           int64        `json:"updated_at"`
+}
+
+type circuitBreaker struct {
+	CircuitBreakerSnapshot
+
+	windowStart    int64
+	windowRequests int
+	windowFailures int
+	probes         int
+	probeStartedAt time.Time
+}
+
+type CircuitBreakerManager struct {
+	sync.Mutex
+	breakers map[string]*circuitBreaker // channelId:modelName
+}
+
+var CircuitBreakers = NewCircuitBreakerManager()
+
+func NewCircuitBreakerManager() *CircuitBreakerManager {
+	return &CircuitBreakerManager{
+		breakers: make(map[string]*circuitBreaker),
+	}
+}
+
+func circuitBreakerEnabled() bool {
+	return config.RetryCooldownSeconds > 0
+}
+
+func (m *CircuitBreakerManager) getLocked(channelId int, modelName string) *circuitBreaker {
+	key := channelStatsKey(channelId, modelName)
+	breaker, ok := m.breakers[key]
+	if !ok {
+		breaker = &circuitBreaker{
+			CircuitBreakerSnapshot: CircuitBreakerSnapshot{
+				ChannelId: channelId,
+				Model:     modelName,
+			},
+		}
+		m.breakers[key] = breaker
+	}
+	return breaker
+}
+
+// advanceLocked 打开状态到期后转为半开状态
+func (m *CircuitBreakerManager) advanceLocked(breaker *circuitBreaker, now time.Time) {
+	if breaker.State == CircuitOpen && now.Unix() >= breaker.OpenUntil {
+		breaker.State = CircuitHalfOpen
+		breaker.probes = 0
+		breaker.UpdatedAt = now.Unix()
+		metrics.RecordCircuitEvent(breaker.ChannelId, breaker.Model, "half_open")
+	}
+
+	if breaker.State == CircuitHalfOpen && breaker.probes > 0 && now.Sub(breaker.probeStartedAt) > circuitProbeTimeout {
+		breaker.probes = 0
+	}
+}
+
+// Available 判断渠道是否可以接收请求，半开状态下仅在还有探测名额时可用
+func (m *CircuitBreakerManager) Available(channelId int, modelName string) bool {
+	if !circuitBreakerEnabled() {
+		return true
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	breaker, ok := m.breakers[channelStatsKey(channelId, modelName)]
+	if !ok {
+		return true
+	}
+
+	m.advanceLocked(breaker, time.Now())
+
+	switch breaker.State {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		return breaker.probes < config.CircuitBreakerHalfOpenProbes
+	}
+	return true
+}
+
+// Acquire 在渠道被选中后调用，半开状态下占用一个探测名额
+func (m *CircuitBreakerManager) Acquire(channelId int, modelName string) bool {
+	if !circuitBreakerEnabled() {
+		return true
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	breaker, ok := m.breakers[channelStatsKey(channelId, modelName)]
+	if !ok {
+		return true
+	}
+
+	now := time.Now()
+	m.advanceLocked(breaker, now)
+
+	switch breaker.State {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if breaker.probes >= config.CircuitBreakerHalfOpenProbes {
+			return false
+		}
+		breaker.probes++
+		breaker.probeStartedAt = now
+	}
+	return true
+}
+
+func (m *CircuitBreakerManager) RecordSuccess(channelId int, modelName string) {
+	if !circuitBreakerEnabled() || channelId == 0 || modelName == "" {
+		return
+	}
+
+	m.Lock()
+	breaker, ok := m.breakers[channelStatsKey(channelId, modelName)]
+	if !ok {
+		m.Unlock()
+		return
+	}
+
+	now := time.Now()
+	m.advanceLocked(breaker, now)
+	m.countLocked(breaker, now, false)
+	breaker.ConsecutiveFailures = 0
+
+	var closed bool
+	if breaker.State == CircuitHalfOpen {
+		m.closeLocked(breaker, now)
+		closed = true
+	}
+	m.Unlock()
+
+	if closed {
+		metrics.RecordCircuitEvent(channelId, modelName, "close")
+		logger.SysLog(fmt.Sprintf("circuit breaker closed: channel #%d model %s", channelId, modelName))
+		go deleteCircuitBreakerCache(channelId, modelName)
+	}
+}
+
+func (m *CircuitBreakerManager) RecordFailure(channelId int, modelName string, kind CircuitFailureKind) {
+	if !circuitBreakerEnabled() || channelId == 0 || modelName == "" {
+		return
+	}
+
+	m.Lock()
+	breaker := m.getLocked(channelId, modelName)
+	now := time.Now()
+	m.advanceLocked(breaker, now)
+	m.countLocked(breaker, now, true)
+	breaker.ConsecutiveFailures++
+	breaker.UpdatedAt = now.Unix()
+
+	shouldTrip := false
+	event := "open"
+	switch {
+	case breaker.State == CircuitOpen:
+		// 已经熔断的渠道，仍在途的请求失败不再重复计算
+	case breaker.State == CircuitHalfOpen:
+		shouldTrip = true
+	case kind == CircuitFailureRateLimited:
+		// 限流或强制熔断，渠道进入冷却
+		shouldTrip = true
+		event = "cooldown"
+	case config.CircuitBreakerFailureThreshold > 0 && breaker.ConsecutiveFailures >= config.CircuitBreakerFailureThreshold:
+		shouldTrip = true
+	case config.CircuitBreakerErrorRate > 0 &&
+		breaker.windowRequests >= config.CircuitBreakerMinRequests &&
+		float64(breaker.windowFailures)/float64(breaker.windowRequests) >= config.CircuitBreakerErrorRate:
+		shouldTrip = true
+	}
+
+	var snapshot CircuitBreakerSnapshot
+	if shouldTrip {
+		m.tripLocked(breaker, now)
+		snapshot = breaker.CircuitBreakerSnapshot
+	}
+	m.Unlock()
+
+	if shouldTrip {
+		metrics.RecordCircuitEvent(channelId, modelName, event)
+		logger.SysLog(fmt.Sprintf("circuit breaker opened: channel #%d model %s, trips %d, open until %s",
+			channelId, modelName, snapshot.Trips, time.Unix(snapshot.OpenUntil, 0).Format(time.DateTime)))
+		go saveCircuitBreakerCache(snapshot)
+	}
+}
+
+// Release 请求失败但不是渠道的问题（如参数错误）时调用，释放半开状态下占用的探测名额
+func (m *CircuitBreakerManager) Release(channelId int, modelName string) {
+	if !circuitBreakerEnabled() || channelId == 0 || modelName == "" {
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	breaker, ok := m.breakers[channelStatsKey(channelId, modelName)]
+	if !ok {
+		return
+	}
+	if breaker.State == CircuitHalfOpen && breaker.probes > 0 {
+		breaker.probes--
+	}
+}
+
+// IsCircuitFailure 判断错误是否说明渠道不可用，参数错误等客户端问题不计入熔断
+func IsCircuitFailure(statusCode int, localError bool) bool {
+	if localError {
+		return false
+	}
+
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusRequestTimeout:
+		return true
+	}
+
+	return statusCode >= 500 || statusCode < 400
+}
+
+// RecordOutcome 按请求结果更新熔断器，失败时按错误的状态码判断
+// 本地错误和请求参数错误不能说明渠道是否可用，只释放探测名额；上游限流直接熔断
+func (m *CircuitBreakerManager) RecordOutcome(channelId int, modelName string, success bool, statusCode int, localError bool) {
+	if success {
+		m.RecordSuccess(channelId, modelName)
+		return
+	}
+
+	if !IsCircuitFailure(statusCode, localError) {
+		m.Release(channelId, modelName)
+		return
+	}
+
+	kind := CircuitFailureError
+	if statusCode == http.StatusTooManyRequests {
+		kind = CircuitFailureRateLimited
+	}
+	m.RecordFailure(channelId, modelName, kind)
+}
+
+// Trip 强制熔断
+func (m *CircuitBreakerManager) Trip(channelId int, modelName string) {
+	m.RecordFailure(channelId, modelName, CircuitFailureRateLimited)
+}
+
+// Reset 手动恢复渠道的所有熔断器
+func (m *CircuitBreakerManager) Reset(channelId int) {
+	m.Lock()
+	var models []string
+	for key, breaker := range m.breakers {
+		if breaker.ChannelId == channelId {
+			models = append(models, breaker.Model)
+			delete(m.breakers, key)
+		}
+	}
+	m.Unlock()
+
+	for _, modelName := range models {
+		metrics.RecordCircuitEvent(channelId, modelName, "reset")
+		deleteCircuitBreakerCache(channelId, modelName)
+	}
+}
+
+func (m *CircuitBreakerManager) countLocked(breaker *circuitBreaker, now time.Time, failed bool) {
+	windowSeconds := int64(config.CircuitBreakerWindowSeconds)
+	if windowSeconds <= 0 || now.Unix()-breaker.windowStart >= windowSeconds {
+		breaker.windowStart = now.Unix()
+		breaker.windowRequests = 0
+		breaker.windowFailures = 0
+	}
+
+	breaker.windowRequests++
+	if failed {
+		breaker.windowFailures++
+	}
+}
+
+// tripLocked 打开熔断器，熔断时长按连续熔断次数指数增长
+func (m *CircuitBreakerManager) tripLocked(breaker *circuitBreaker, now time.Time) {
+	openSeconds := int64(config.RetryCooldownSeconds)
+	for i := 0; i < breaker.Trips && openSeconds < int64(config.CircuitBreakerMaxOpenSeconds); i++ {
+		openSeconds *= 2
+	}
+	if config.CircuitBreakerMaxOpenSeconds > 0 && openSeconds > int64(config.CircuitBreakerMaxOpenSeconds) {
+		openSeconds = int64(config.CircuitBreakerMaxOpenSeconds)
+	}
+
+	breaker.State = CircuitOpen
+	breaker.Trips++
+	breaker.OpenedAt = now.Unix()
+	breaker.OpenUntil = now.Unix() + openSeconds
+	breaker.UpdatedAt = now.Unix()
+	breaker.probes = 0
+}
+
+func (m *CircuitBreakerManager) closeLocked(breaker *circuitBreaker, now time.Time) {
+	breaker.State = CircuitClosed
+	breaker.Trips = 0
+	breaker.ConsecutiveFailures = 0
+	breaker.OpenedAt = 0
+	breaker.OpenUntil = 0
+	breaker.UpdatedAt = now.Unix()
+	breaker.probes = 0
+	breaker.windowStart = now.Unix()
+	breaker.windowRequests = 0
+	breaker.windowFailures = 0
+}
+
+// GetByChannel 获取渠道下所有非关闭状态的熔断器
+func (m *CircuitBreakerManager) GetByChannel(channelId int) []CircuitBreakerSnapshot {
+	m.Lock()
+	defer m.Unlock()
+
+	now := time.Now()
+	snapshots := make([]CircuitBreakerSnapshot, 0)
+	for _, breaker := range m.breakers {
+		if breaker.ChannelId != channelId {
+			continue
+		}
+		m.advanceLocked(breaker, now)
+		if breaker.State == CircuitClosed {
+			continue
+		}
+		snapshots = append(snapshots, breaker.CircuitBreakerSnapshot)
+	}
+
+	return snapshots
+}
+
+func (m *CircuitBreakerManager) GetAll() []CircuitBreakerSnapshot {
+	m.Lock()
+	defer m.Unlock()
+
+	now := time.Now()
+	snapshots := make([]CircuitBreakerSnapshot, 0, len(m.breakers))
+	for _, breaker := range m.breakers {
+		m.advanceLocked(breaker, now)
+		if breaker.State == CircuitClosed {
+			continue
+		}
+		snapshots = append(snapshots, breaker.CircuitBreakerSnapshot)
+	}
+
+	return snapshots
+}
+
+// CleanupExpired 清理长时间处于关闭状态的熔断器
+func (m *CircuitBreakerManager) CleanupExpired() {
+	m.Lock()
+	defer m.Unlock()
+
+	expired := time.Now().Add(-time.Hour).Unix()
+	for key, breaker := range m.breakers {
+		if breaker.State == CircuitClosed && breaker.UpdatedAt < expired && breaker.windowStart < expired {
+			delete(m.breakers, key)
+		}
+	}
+}
+
+// applyRemote 合并其他节点的熔断状态
+func (m *CircuitBreakerManager) applyRemote(remotes map[string]CircuitBreakerSnapshot) {
+	m.Lock()
+	defer m.Unlock()
+
+	now := time.Now()
+	for key, remote := range remotes {
+		breaker := m.getLocked(remote.ChannelId, remote.Model)
+		if remote.UpdatedAt <= breaker.UpdatedAt {
+			continue
+		}
+		breaker.CircuitBreakerSnapshot = remote
+		breaker.probes = 0
+		m.advanceLocked(breaker, now)
+		m.breakers[key] = breaker
+	}
+
+	// 其他节点已经恢复的熔断器，本地同步恢复
+	syncedBefore := now.Add(-2 * circuitSyncInterval).Unix()
+	for key, breaker := range m.breakers {
+		if breaker.State == CircuitClosed || breaker.UpdatedAt > syncedBefore {
+			continue
+		}
+		if _, ok := remotes[key]; !ok {
+			m.closeLocked(breaker, now)
+		}
+	}
+}
+
+func circuitBreakerCacheField(channelId int, modelName string) string {
+	return fmt.Sprintf("%d:%s", channelId, modelName)
+}
+
+func saveCircuitBreakerCache(snapshot CircuitBreakerSnapshot) {
+	if !config.RedisEnabled {
+		return
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return
+	}
+
+	field := circuitBreakerCacheField(snapshot.ChannelId, snapshot.Model)
+	if err := redis.GetRedisClient().HSet(context.Background(), CircuitBreakerCacheKey, field, string(data)).Err(); err != nil {
+		logger.SysError("failed to save circuit breaker: " + err.Error())
+	}
+}
+
+func deleteCircuitBreakerCache(channelId int, modelName string) {
+	if !config.RedisEnabled {
+		return
+	}
+
+	field := circuitBreakerCacheField(channelId, modelName)
+	if err := redis.GetRedisClient().HDel(context.Background(), CircuitBreakerCacheKey, field).Err(); err != nil {
+		logger.SysError("failed to delete circuit breaker: " + err.Error())
+	}
+}
+
+func loadCircuitBreakerCache() (map[string]CircuitBreakerSnapshot, error) {
+	ctx := context.Background()
+	remotes := make(map[string]CircuitBreakerSnapshot)
+
+	values, err := redis.GetRedisClient().HGetAll(ctx, CircuitBreakerCacheKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	// 保留到半开状态结束之后，方便其他节点得知连续熔断次数，之后清理掉
+	expired := time.Now().Unix() - int64(config.CircuitBreakerMaxOpenSeconds)
+	var expiredFields []string
+	for field, data := range values {
+		var snapshot CircuitBreakerSnapshot
+		if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+			expiredFields = append(expiredFields, field)
+			continue
+		}
+		if _, err := strconv.Atoi(strings.SplitN(field, ":", 2)[0]); err != nil {
+			continue
+		}
+		if snapshot.OpenUntil < expired {
+			expiredFields = append(expiredFields, field)
+			continue
+		}
+		remotes[field] = snapshot
+	}
+
+	if len(expiredFields) > 0 {
+		redis.GetRedisClient().HDel(ctx, CircuitBreakerCacheKey, expiredFields...)
+	}
+
+	return remotes, nil
+}
+
+// SyncCircuitBreakers 定时从 Redis 同步其他节点的熔断状态
+func SyncCircuitBreakers() {
+	ticker := time.NewTicker(circuitSyncInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !config.RedisEnabled || !circuitBreakerEnabled() {
+			continue
+		}
+
+		remotes, err := loadCircuitBreakerCache()
+		if err != nil {
+			logger.SysError("failed to sync circuit breakers: " + err.Error())
+			continue
+		}
+
+		CircuitBreakers.applyRemote(remotes)
+	}
+}
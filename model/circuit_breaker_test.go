@@ -0,0 +1,263 @@
+package model
+
+import (
+	"net/http"
+	"one-api/common/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testBreakerModel = "test-model"
+
+// expireBreaker 将熔断器的打开时间提前到已过期，模拟冷却结束
+func expireBreaker(m *CircuitBreakerManager, channelId int) {
+	m.Lock()
+	defer m.Unlock()
+	breaker := m.breakers[channelStatsKey(channelId, testBreakerModel)]
+	breaker.OpenUntil = time.Now().Unix() - 1
+}
+
+func breakerState(m *CircuitBreakerManager, channelId int) CircuitState {
+	m.Lock()
+	defer m.Unlock()
+	breaker, ok := m.breakers[channelStatsKey(channelId, testBreakerModel)]
+	if !ok {
+		return CircuitClosed
+	}
+	m.advanceLocked(breaker, time.Now())
+	return breaker.State
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	config.RetryCooldownSeconds = 5
+	config.CircuitBreakerFailureThreshold = 3
+	config.CircuitBreakerErrorRate = 0
+	config.CircuitBreakerHalfOpenProbes = 1
+
+	type step struct {
+		action string // fail, ratelimit, success, expire, acquire, release
+		ok     bool   // acquire 的期望结果
+		state  CircuitState
+	}
+
+	cases := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "连续失败达到阈值后熔断",
+			steps: []step{
+				{action: "fail", state: CircuitClosed},
+				{action: "fail", state: CircuitClosed},
+				{action: "fail", state: CircuitOpen},
+				{action: "acquire", ok: false, state: CircuitOpen},
+			},
+		},
+		{
+			name: "成功会重置连续失败次数",
+			steps: []step{
+				{action: "fail", state: CircuitClosed},
+				{action: "fail", state: CircuitClosed},
+				{action: "success", state: CircuitClosed},
+				{action: "fail", state: CircuitClosed},
+				{action: "fail", state: CircuitClosed},
+			},
+		},
+		{
+			name: "限流直接熔断",
+			steps: []step{
+				{action: "ratelimit", state: CircuitOpen},
+			},
+		},
+		{
+			name: "冷却结束后半开，探测成功关闭",
+			steps: []step{
+				{action: "ratelimit", state: CircuitOpen},
+				{action: "expire", state: CircuitHalfOpen},
+				{action: "acquire", ok: true, state: CircuitHalfOpen},
+				{action: "acquire", ok: false, state: CircuitHalfOpen},
+				{action: "success", state: CircuitClosed},
+				{action: "acquire", ok: true, state: CircuitClosed},
+			},
+		},
+		{
+			name: "半开探测失败重新熔断",
+			steps: []step{
+				{action: "ratelimit", state: CircuitOpen},
+				{action: "expire", state: CircuitHalfOpen},
+				{action: "acquire", ok: true, state: CircuitHalfOpen},
+				{action: "fail", state: CircuitOpen},
+			},
+		},
+		{
+			name: "释放探测名额后可以再次探测",
+			steps: []step{
+				{action: "ratelimit", state: CircuitOpen},
+				{action: "expire", state: CircuitHalfOpen},
+				{action: "acquire", ok: true, state: CircuitHalfOpen},
+				{action: "release", state: CircuitHalfOpen},
+				{action: "acquire", ok: true, state: CircuitHalfOpen},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := NewCircuitBreakerManager()
+			const channelId = 1
+			for i, s := range c.steps {
+				switch s.action {
+				case "fail":
+					m.RecordFailure(channelId, testBreakerModel, CircuitFailureError)
+				case "ratelimit":
+					m.RecordFailure(channelId, testBreakerModel, CircuitFailureRateLimited)
+				case "success":
+					m.RecordSuccess(channelId, testBreakerModel)
+				case "expire":
+					expireBreaker(m, channelId)
+				case "release":
+					m.Release(channelId, testBreakerModel)
+				case "acquire":
+					assert.Equal(t, s.ok, m.Acquire(channelId, testBreakerModel), "step %d", i)
+				}
+				assert.Equal(t, s.state, breakerState(m, channelId), "step %d", i)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerOpenDuration(t *testing.T) {
+	config.RetryCooldownSeconds = 5
+	config.CircuitBreakerMaxOpenSeconds = 15
+
+	m := NewCircuitBreakerManager()
+	expected := []int64{5, 10, 15, 15}
+	for i, seconds := range expected {
+		m.Trip(1, testBreakerModel)
+		snapshot := m.GetByChannel(1)[0]
+		assert.Equal(t, seconds, snapshot.OpenUntil-snapshot.OpenedAt, "trip %d", i+1)
+		expireBreaker(m, 1)
+		m.Acquire(1, testBreakerModel)
+	}
+}
+
+func TestCircuitBreakerErrorRate(t *testing.T) {
+	config.RetryCooldownSeconds = 5
+	config.CircuitBreakerFailureThreshold = 0
+	config.CircuitBreakerErrorRate = 0.5
+	config.CircuitBreakerMinRequests = 4
+	config.CircuitBreakerWindowSeconds = 60
+	defer func() {
+		config.CircuitBreakerFailureThreshold = 5
+		config.CircuitBreakerMinRequests = 20
+	}()
+
+	m := NewCircuitBreakerManager()
+	m.RecordFailure(1, testBreakerModel, CircuitFailureError)
+	m.RecordSuccess(1, testBreakerModel)
+	m.RecordSuccess(1, testBreakerModel)
+	assert.Equal(t, CircuitClosed, breakerState(m, 1))
+
+	// 第 4 个请求失败，错误率 2/4 达到 0.5
+	m.RecordFailure(1, testBreakerModel, CircuitFailureError)
+	assert.Equal(t, CircuitOpen, breakerState(m, 1))
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	config.RetryCooldownSeconds = 0
+	defer func() { config.RetryCooldownSeconds = 5 }()
+
+	m := NewCircuitBreakerManager()
+	m.Trip(1, testBreakerModel)
+	assert.True(t, m.Available(1, testBreakerModel))
+	assert.True(t, m.Acquire(1, testBreakerModel))
+}
+
+func TestIsCircuitFailure(t *testing.T) {
+	cases := []struct {
+		statusCode int
+		localError bool
+		expected   bool
+	}{
+		{http.StatusInternalServerError, false, true},
+		{http.StatusBadGateway, false, true},
+		{http.StatusTooManyRequests, false, true},
+		{http.StatusRequestTimeout, false, true},
+		{http.StatusOK, false, true},
+		{http.StatusBadRequest, false, false},
+		{http.StatusUnauthorized, false, false},
+		{http.StatusNotFound, false, false},
+		{http.StatusInternalServerError, true, false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, IsCircuitFailure(c.statusCode, c.localError), "status %d local %v", c.statusCode, c.localError)
+	}
+}
+
+func TestCircuitBreakerRecordOutcome(t *testing.T) {
+	config.RetryCooldownSeconds = 5
+	config.CircuitBreakerFailureThreshold = 1
+	config.CircuitBreakerErrorRate = 0
+	config.CircuitBreakerHalfOpenProbes = 1
+	defer func() { config.CircuitBreakerFailureThreshold = 3 }()
+
+	cases := []struct {
+		name       string
+		success    bool
+		statusCode int
+		localError bool
+		expected   CircuitState
+	}{
+		{"成功", true, 0, false, CircuitClosed},
+		{"上游错误", false, http.StatusInternalServerError, false, CircuitOpen},
+		{"上游限流", false, http.StatusTooManyRequests, false, CircuitOpen},
+		{"请求参数错误", false, http.StatusBadRequest, false, CircuitClosed},
+		{"本地错误", false, http.StatusInternalServerError, true, CircuitClosed},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := NewCircuitBreakerManager()
+			m.RecordOutcome(1, testBreakerModel, c.success, c.statusCode, c.localError)
+			assert.Equal(t, c.expected, breakerState(m, 1))
+		})
+	}
+}
+
+func TestChannelPickHalfOpenProbe(t *testing.T) {
+	config.RetryCooldownSeconds = 5
+	config.CircuitBreakerHalfOpenProbes = 1
+
+	breakers := CircuitBreakers
+	CircuitBreakers = NewCircuitBreakerManager()
+	defer func() { CircuitBreakers = breakers }()
+
+	weight := uint(1)
+	chooser := &ChannelsChooser{
+		Channels: map[int]*ChannelChoice{1: {Channel: &Channel{Id: 1, Weight: &weight}}},
+		Rule:     map[string]map[string][][]int{"default": {testBreakerModel: {{1}}}},
+	}
+
+	CircuitBreakers.Trip(1, testBreakerModel)
+	expireBreaker(CircuitBreakers, 1)
+
+	cases := []struct {
+		name     string
+		pick     func(group, modelName string, filters ...ChannelsFilterFunc) (*Channel, error)
+		expected bool
+	}{
+		{"查看不占用探测名额", chooser.Peek, true},
+		{"再次查看", chooser.Peek, true},
+		{"真正选择时占用探测名额", chooser.Next, true},
+		{"探测名额已被占用", chooser.Next, false},
+		{"探测名额被占用时查看也跳过", chooser.Peek, false},
+	}
+
+	for _, c := range cases {
+		channel, err := c.pick("default", testBreakerModel)
+		assert.Equal(t, c.expected, err == nil && channel != nil, c.name)
+	}
+}
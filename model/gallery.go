@@ -0,0 +1,78 @@
+package model
+
+import (
+	"errors"
+	"one-api/common/utils"
+
+	"gorm.io/gorm"
+)
+
+// GalleryItem 用户作品集中的一项，来自完成的 Midjourney、Kling、Suno 等任务
+type GalleryItem struct {
+	Id         int    `json:"id"`
+	UserId     int    `json:"user_id" gorm:"index"`
+	Platform   string `json:"platform" gorm:"type:varchar(30);index"`
+	TaskId     string `json:"task_id" gorm:"type:varchar(50);index"`
+	Type       string `json:"type" gorm:"type:varchar(20);index"` // image、video、audio
+	Prompt     string `json:"prompt" gorm:"type:text"`
+	URL        string `json:"url" gorm:"type:text"` // 转存后的地址，未转存时为上游地址
+	SourceURL  string `json:"source_url" gorm:"type:text"`
+	Drive      string `json:"-" gorm:"type:varchar(20);default:''"` // 转存使用的存储，未转存时为空
+	StorageKey string `json:"-" gorm:"type:varchar(255);default:''"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+type SearchGalleryParams struct {
+	Prompt   string `form:"prompt"`
+	Platform string `form:"platform"`
+	Type     string `form:"type"`
+	PaginationParams
+}
+
+var allowedGalleryOrderFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"platform":   true,
+}
+
+func GetUserGalleryList(userId int, params *SearchGalleryParams) (*DataResult[GalleryItem], error) {
+	var items []*GalleryItem
+	db := DB.Where("user_id = ?", userId)
+
+	if params.Prompt != "" {
+		db = db.Where("prompt LIKE ?", "%"+params.Prompt+"%")
+	}
+	if params.Platform != "" {
+		db = db.Where("platform = ?", params.Platform)
+	}
+	if params.Type != "" {
+		db = db.Where("type = ?", params.Type)
+	}
+
+	return PaginateAndOrder(db, &params.PaginationParams, &items, allowedGalleryOrderFields)
+}
+
+func GetUserGalleryItem(userId int, id int) (*GalleryItem, error) {
+	var item GalleryItem
+	err := DB.Where("id = ? AND user_id = ?", id, userId).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("作品不存在")
+	}
+	return &item, err
+}
+
+func CreateGalleryItems(items []*GalleryItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	now := utils.GetTimestamp()
+	for _, item := range items {
+		item.CreatedAt = now
+	}
+	return DB.Create(&items).Error
+}
+
+func (g *GalleryItem) Delete() error {
+	return DB.Delete(g).Error
+}
@@ -79,6 +79,29 @@ func RecordLog(userId int, logType int, content string) {
 	}
 }
 
+// RecordSafetyLog 记录内容审查命中的日志，类别写入 metadata
+func RecordSafetyLog(ctx context.Context, userId int, tokenName string, modelName string, channelId int, category string, content string) {
+	username, _ := CacheGetUsername(userId)
+
+	log := &Log{
+		UserId:    userId,
+		Username:  username,
+		TokenName: tokenName,
+		ModelName: modelName,
+		ChannelId: channelId,
+		CreatedAt: utils.GetTimestamp(),
+		Type:      LogTypeSystem,
+		Content:   content,
+		Metadata: datatypes.NewJSONType(map[string]any{
+			"safety_category": category,
+		}),
+	}
+	err := DB.Create(log).Error
+	if err != nil {
+		logger.LogError(ctx, "failed to record safety log: "+err.Error())
+	}
+}
+
 func RecordConsumeLog(
 	ctx context.Context,
 	userId int,
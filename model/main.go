@@ -27,6 +27,7 @@ func SetupDB() {
 	}
 	ChannelGroup.Load()
 	GlobalUserGroupRatio.Load()
+	GlobalVirtualModels.Load()
 	config.RootUserEmail = GetRootUserEmail()
 	NewModelOwnedBys()
 
@@ -189,6 +190,56 @@ func InitDB() (err error) {
 			return err
 		}
 
+		err = db.AutoMigrate(&ResponsesStore{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&BatchFile{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&BatchFileChunk{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&Batch{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&ChannelProbe{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&ChannelProbeHistory{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&ChannelFingerprint{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&PayloadCapture{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&VirtualModel{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&GalleryItem{})
+		if err != nil {
+			return err
+		}
+
 		if config.UserInvoiceMonth {
 			err = db.AutoMigrate(&StatisticsMonthGeneratedHistory{})
 			if err != nil {
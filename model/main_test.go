@@ -0,0 +1,14 @@
+package model
+
+import (
+	"one-api/common/logger"
+	"os"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
@@ -24,28 +24,30 @@
 package model
 
 type Midjourney struct {
-	Id          int    `json:"id"`
-	Code        int    `json:"code"`
-	UserId      int    `json:"user_id" gorm:"index"`
-	Action      string `json:"action" gorm:"type:varchar(40);index"`
-	MjId        string `json:"mj_id" gorm:"index"`
-	Prompt      string `json:"prompt"`
-	PromptEn    string `json:"prompt_en"`
-	Description string `json:"description"`
-	State       string `json:"state"`
-	SubmitTime  int64  `json:"submit_time" gorm:"index"`
-	StartTime   int64  `json:"start_time" gorm:"index"`
-	FinishTime  int64  `json:"finish_time" gorm:"index"`
-	ImageUrl    string `json:"image_url"`
-	Status      string `json:"status" gorm:"type:varchar(20);index"`
-	Progress    string `json:"progress" gorm:"type:varchar(30);index"`
-	FailReason  string `json:"fail_reason"`
-	ChannelId   int    `json:"channel_id"`
-	Quota       int    `json:"quota"`
-	Buttons     string `json:"buttons"`
-	Properties  string `json:"properties"`
-	Mode        string `json:"mode,omitempty"`
-	TokenID     int    `json:"token_id" gorm:"default:0"`
+	Id             int    `json:"id"`
+	Code           int    `json:"code"`
+	UserId         int    `json:"user_id" gorm:"index"`
+	Action         string `json:"action" gorm:"type:varchar(40);index"`
+	MjId           string `json:"mj_id" gorm:"index"`
+	Prompt         string `json:"prompt"`
+	PromptEn       string `json:"prompt_en"`
+	Description    string `json:"description"`
+	State          string `json:"state"`
+	SubmitTime     int64  `json:"submit_time" gorm:"index"`
+	StartTime      int64  `json:"start_time" gorm:"index"`
+	FinishTime     int64  `json:"finish_time" gorm:"index"`
+	ImageUrl       string `json:"image_url"`
+	Status         string `json:"status" gorm:"type:varchar(20);index"`
+	Progress       string `json:"progress" gorm:"type:varchar(30);index"`
+	FailReason     string `json:"fail_reason"`
+	ChannelId      int    `json:"channel_id"`
+	Quota          int    `json:"quota"`
+	RefundQuota    int    `json:"refund_quota" gorm:"default:0"` // 已退还的额度
+	Buttons        string `json:"buttons"`
+	Properties     string `json:"properties"`
+	Mode           string `json:"mode,omitempty"`
+	TokenID        int    `json:"token_id" gorm:"default:0"`
+	StoredImageUrl string `json:"stored_image_url" gorm:"type:text"` // 转存后的图片地址
 }
 
 // TaskQueryParams 用于包含所有搜索条件的结构体，可以根据需求添加更多字段
@@ -163,6 +165,17 @@ func (midjourney *Midjourney) Update() error {
 	return DB.Save(midjourney).Error
 }
 
+func UpdateMidjourneyStoredImageUrl(id int, storedImageUrl string) error {
+	return DB.Model(&Midjourney{}).Where("id = ?", id).Update("stored_image_url", storedImageUrl).Error
+}
+
+// ClearMidjourneyStoredImageUrl 转存文件被删除后清除转存地址，获取图片时回退到上游地址
+func ClearMidjourneyStoredImageUrl(userId int, mjId string, storedImageUrl string) error {
+	return DB.Model(&Midjourney{}).
+		Where("user_id = ? and mj_id = ? and stored_image_url = ?", userId, mjId, storedImageUrl).
+		Update("stored_image_url", "").Error
+}
+
 func MjBulkUpdate(mjIds []string, params map[string]any) error {
 	return DB.Model(&Midjourney{}).
 		Where("mj_id in (?)", mjIds).
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/logger"
@@ -88,6 +89,18 @@ func InitOptionMap() {
 	config.GlobalOption.RegisterFloat("QuotaPerUnit", &config.QuotaPerUnit)
 	config.GlobalOption.RegisterInt("RetryTimes", &config.RetryTimes)
 	config.GlobalOption.RegisterInt("RetryCooldownSeconds", &config.RetryCooldownSeconds)
+	config.GlobalOption.RegisterInt("CircuitBreakerMaxOpenSeconds", &config.CircuitBreakerMaxOpenSeconds)
+	config.GlobalOption.RegisterInt("CircuitBreakerFailureThreshold", &config.CircuitBreakerFailureThreshold)
+	config.GlobalOption.RegisterFloat("CircuitBreakerErrorRate", &config.CircuitBreakerErrorRate)
+	config.GlobalOption.RegisterInt("CircuitBreakerMinRequests", &config.CircuitBreakerMinRequests)
+	config.GlobalOption.RegisterInt("CircuitBreakerWindowSeconds", &config.CircuitBreakerWindowSeconds)
+	config.GlobalOption.RegisterInt("CircuitBreakerHalfOpenProbes", &config.CircuitBreakerHalfOpenProbes)
+	config.GlobalOption.RegisterString("ChannelBalanceStrategy", &config.ChannelBalanceStrategy)
+	config.GlobalOption.RegisterCustom("ChannelBalanceStrategyTags", func() string {
+		return BalanceStrategyTags2JSONString()
+	}, func(value string) error {
+		return UpdateBalanceStrategyTagsByJSONString(value)
+	}, "")
 
 	config.GlobalOption.RegisterBool("MjNotifyEnabled", &config.MjNotifyEnabled)
 	config.GlobalOption.RegisterString("ChatImageRequestProxy", &config.ChatImageRequestProxy)
@@ -127,6 +140,41 @@ func InitOptionMap() {
 		config.SafeKeyWords = strings.Split(value, "\n")
 		return nil
 	}, "")
+	config.GlobalOption.RegisterString("SafeRedactMode", &config.SafeRedactMode)
+	config.GlobalOption.RegisterCustom("SafeRedactCategories", func() string {
+		return strings.Join(config.SafeRedactCategories, ",")
+	}, func(value string) error {
+		categories := make([]string, 0)
+		for _, category := range strings.Split(value, ",") {
+			if category = strings.TrimSpace(category); category != "" {
+				categories = append(categories, category)
+			}
+		}
+		config.SafeRedactCategories = categories
+		return nil
+	}, "")
+	config.GlobalOption.RegisterBool("SafeOutputEnabled", &config.SafeOutputEnabled)
+	config.GlobalOption.RegisterString("SafeOutputMode", &config.SafeOutputMode)
+	config.GlobalOption.RegisterString("SafeOutputToolName", &config.SafeOutputToolName)
+	config.GlobalOption.RegisterInt("SafeOutputWindow", &config.SafeOutputWindow)
+	config.GlobalOption.RegisterInt("SafeOutputCheckInterval", &config.SafeOutputCheckInterval)
+	config.GlobalOption.RegisterString("SafeModerationModel", &config.SafeModerationModel)
+	config.GlobalOption.RegisterString("SafeModerationGroup", &config.SafeModerationGroup)
+	config.GlobalOption.RegisterCustom("SafeModerationThresholds", func() string {
+		jsonBytes, _ := json.Marshal(config.SafeModerationThresholds)
+		return string(jsonBytes)
+	}, func(value string) error {
+		thresholds := make(map[string]float64)
+		if strings.TrimSpace(value) != "" {
+			if err := json.Unmarshal([]byte(value), &thresholds); err != nil {
+				return err
+			}
+		}
+		config.SafeModerationThresholds = thresholds
+		return nil
+	}, "")
+	config.GlobalOption.RegisterInt("SafeModerationCacheTTL", &config.SafeModerationCacheTTL)
+	config.GlobalOption.RegisterBool("SafeModerationFailClosed", &config.SafeModerationFailClosed)
 
 	loadOptionsFromDatabase()
 }
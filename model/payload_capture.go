@@ -0,0 +1,100 @@
+package model
+
+import (
+	"one-api/common/utils"
+
+	"gorm.io/datatypes"
+)
+
+// PayloadCapture 完整的请求和响应记录，内容按配置保存在数据库、本地文件或 S3
+type PayloadCapture struct {
+	Id               int            `json:"id"`
+	UserId           int            `json:"user_id" gorm:"index"`
+	TokenId          int            `json:"token_id" gorm:"index"`
+	TokenName        string         `json:"token_name" gorm:"type:varchar(100);default:''"`
+	ChannelId        int            `json:"channel_id" gorm:"index"`
+	Model            string         `json:"model" gorm:"type:varchar(100);index"`
+	Path             string         `json:"path" gorm:"type:varchar(255)"`
+	IsStream         bool           `json:"is_stream"`
+	StatusCode       int            `json:"status_code"`
+	PromptTokens     int            `json:"prompt_tokens"`
+	CompletionTokens int            `json:"completion_tokens"`
+	RequestId        string         `json:"request_id" gorm:"type:varchar(64);default:''"`
+	Sink             string         `json:"sink" gorm:"type:varchar(16)"`
+	Location         string         `json:"-" gorm:"type:varchar(512);default:''"` // 文件路径或 S3 key
+	Payload          datatypes.JSON `json:"-" gorm:"type:json"`                    // 保存在数据库时的内容
+	CreatedAt        int64          `json:"created_at" gorm:"bigint;index"`
+}
+
+type SearchPayloadCaptureParams struct {
+	UserId    int    `form:"user_id"`
+	TokenId   int    `form:"token_id"`
+	ChannelId int    `form:"channel_id"`
+	Model     string `form:"model"`
+	StartTime int64  `form:"start_timestamp"`
+	EndTime   int64  `form:"end_timestamp"`
+	PaginationParams
+}
+
+var allowedPayloadCaptureOrderFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+}
+
+func (p *PayloadCapture) Insert() error {
+	if p.CreatedAt == 0 {
+		p.CreatedAt = utils.GetTimestamp()
+	}
+	return DB.Create(p).Error
+}
+
+func GetPayloadCaptureById(id int) (*PayloadCapture, error) {
+	var capture PayloadCapture
+	err := DB.Where("id = ?", id).First(&capture).Error
+	return &capture, err
+}
+
+func GetPayloadCapturesList(params *SearchPayloadCaptureParams) (*DataResult[PayloadCapture], error) {
+	var captures []*PayloadCapture
+	db := DB.Omit("payload")
+
+	if params.UserId > 0 {
+		db = db.Where("user_id = ?", params.UserId)
+	}
+	if params.TokenId > 0 {
+		db = db.Where("token_id = ?", params.TokenId)
+	}
+	if params.ChannelId > 0 {
+		db = db.Where("channel_id = ?", params.ChannelId)
+	}
+	if params.Model != "" {
+		db = db.Where("model = ?", params.Model)
+	}
+	if params.StartTime != 0 {
+		db = db.Where("created_at >= ?", params.StartTime)
+	}
+	if params.EndTime != 0 {
+		db = db.Where("created_at <= ?", params.EndTime)
+	}
+
+	return PaginateAndOrder(db, &params.PaginationParams, &captures, allowedPayloadCaptureOrderFields)
+}
+
+func (p *PayloadCapture) Delete() error {
+	return DB.Delete(p).Error
+}
+
+// GetExpiredPayloadCaptures 获取过期的记录，每次最多 limit 条
+func GetExpiredPayloadCaptures(timestamp int64, limit int) ([]*PayloadCapture, error) {
+	var captures []*PayloadCapture
+	err := DB.Select("id", "sink", "location").Where("created_at < ?", timestamp).
+		Order("id asc").Limit(limit).Find(&captures).Error
+	return captures, err
+}
+
+func DeletePayloadCapturesByIds(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return DB.Where("id IN ?", ids).Delete(&PayloadCapture{}).Error
+}
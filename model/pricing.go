@@ -117,6 +117,13 @@ func (p *Pricing) GetPrice(modelName string) *Price {
 		return price
 	}
 
+	// 虚拟模型未单独定价时，按权重最高的目标模型计费
+	if virtualModel := GlobalVirtualModels.Get(modelName); virtualModel != nil {
+		if price, ok := p.Prices[virtualModel.PrimaryModel()]; ok {
+			return price
+		}
+	}
+
 	return &Price{
 		Type:        TokensPriceType,
 		ChannelType: config.ChannelTypeUnknown,
@@ -125,6 +132,15 @@ func (p *Pricing) GetPrice(modelName string) *Price {
 	}
 }
 
+// HasPrice 模型是否单独设置了价格，不包括通配符匹配
+func (p *Pricing) HasPrice(modelName string) bool {
+	p.RLock()
+	defer p.RUnlock()
+
+	_, ok := p.Prices[modelName]
+	return ok
+}
+
 func (p *Pricing) GetAllPrices() map[string]*Price {
 	return p.Prices
 }
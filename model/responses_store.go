@@ -0,0 +1,108 @@
+package model
+
+import (
+	"errors"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ResponsesStore Responses API 在 store=true 时保存的响应，用于 previous_response_id 和查询接口
+type ResponsesStore struct {
+	ID                 int64          `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	ResponseID         string         `json:"response_id" gorm:"type:varchar(100);uniqueIndex"`
+	UserId             int            `json:"user_id" gorm:"index"`
+	TokenId            int            `json:"token_id" gorm:"index"`
+	Model              string         `json:"model" gorm:"type:varchar(100)"`
+	PreviousResponseID string         `json:"previous_response_id" gorm:"type:varchar(100)"`
+	Input              datatypes.JSON `json:"input" gorm:"type:json"`    // 本轮请求的输入项
+	Response           datatypes.JSON `json:"response" gorm:"type:json"` // 完整的响应
+	CreatedAt          int64          `json:"created_at" gorm:"index"`
+}
+
+func (r *ResponsesStore) Insert() error {
+	if r.CreatedAt == 0 {
+		r.CreatedAt = utils.GetTimestamp()
+	}
+
+	if err := DB.Create(r).Error; err != nil {
+		return err
+	}
+
+	trimUserResponses(r.UserId)
+	return nil
+}
+
+// trimUserResponses 超过每个用户的保留数量时，删除最早的记录
+func trimUserResponses(userId int) {
+	maxPerUser := config.ResponsesStoreSettingsInstance.MaxPerUser
+	if maxPerUser <= 0 {
+		return
+	}
+
+	var ids []int64
+	err := DB.Model(&ResponsesStore{}).Where("user_id = ?", userId).
+		Order("id desc").Offset(maxPerUser).Limit(1).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return
+	}
+
+	if err := DB.Where("user_id = ? AND id <= ?", userId, ids[0]).Delete(&ResponsesStore{}).Error; err != nil {
+		logger.SysError("trim responses store error: " + err.Error())
+	}
+}
+
+func GetResponsesStore(userId, tokenId int, responseId string) (*ResponsesStore, error) {
+	store := &ResponsesStore{}
+	err := DB.Where("user_id = ? AND token_id = ? AND response_id = ?", userId, tokenId, responseId).First(store).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return store, err
+}
+
+// GetResponsesStoreChain 从 responseId 开始沿 previous_response_id 回溯，按时间正序返回
+// 中间的记录已被清理时，链条在该处截断
+func GetResponsesStoreChain(userId, tokenId int, responseId string) ([]*ResponsesStore, error) {
+	maxDepth := config.ResponsesStoreSettingsInstance.MaxChainDepth
+	chain := make([]*ResponsesStore, 0)
+	visited := make(map[string]bool)
+
+	for responseId != "" && !visited[responseId] {
+		if maxDepth > 0 && len(chain) >= maxDepth {
+			break
+		}
+		visited[responseId] = true
+
+		store, err := GetResponsesStore(userId, tokenId, responseId)
+		if err != nil {
+			return nil, err
+		}
+		if store == nil {
+			break
+		}
+
+		chain = append(chain, store)
+		responseId = store.PreviousResponseID
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+
+	return chain, nil
+}
+
+func DeleteResponsesStore(userId, tokenId int, responseId string) (bool, error) {
+	result := DB.Where("user_id = ? AND token_id = ? AND response_id = ?", userId, tokenId, responseId).Delete(&ResponsesStore{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func DeleteOldResponsesStore(targetTimestamp int64) (int64, error) {
+	result := DB.Where("created_at < ?", targetTimestamp).Delete(&ResponsesStore{})
+	return result.RowsAffected, result.Error
+}
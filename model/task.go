@@ -25,25 +25,28 @@ const (
 )
 
 type Task struct {
-	ID         int64          `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
-	CreatedAt  int64          `json:"created_at" gorm:"index"`
-	UpdatedAt  int64          `json:"updated_at"`
-	TaskID     string         `json:"task_id" gorm:"type:varchar(50);index"`  // 第三方id，不一定有/ song id\ Task id
-	Platform   string         `json:"platform" gorm:"type:varchar(30);index"` // 平台
-	UserId     int            `json:"user_id" gorm:"index"`
-	ChannelId  int            `json:"channel_id" gorm:"index"`
-	Quota      int            `json:"quota"`
-	Action     string         `json:"action" gorm:"type:varchar(40);index"` // 任务类型, song, lyrics, description-mode
-	Status     TaskStatus     `json:"status" gorm:"type:varchar(20);index"` // 任务状态
-	FailReason string         `json:"fail_reason"`
-	SubmitTime int64          `json:"submit_time" gorm:"index"`
-	StartTime  int64          `json:"start_time" gorm:"index"`
-	FinishTime int64          `json:"finish_time" gorm:"index"`
-	Progress   int            `json:"progress"`
-	Properties datatypes.JSON `json:"properties" gorm:"type:json"`
-	Data       datatypes.JSON `json:"data" gorm:"type:json"`
-	NotifyHook string         `json:"notify_hook"`
-	TokenID    int            `json:"token_id" gorm:"default:0"`
+	ID          int64                       `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	CreatedAt   int64                       `json:"created_at" gorm:"index"`
+	UpdatedAt   int64                       `json:"updated_at"`
+	TaskID      string                      `json:"task_id" gorm:"type:varchar(50);index"`  // 第三方id，不一定有/ song id\ Task id
+	Platform    string                      `json:"platform" gorm:"type:varchar(30);index"` // 平台
+	UserId      int                         `json:"user_id" gorm:"index"`
+	ChannelId   int                         `json:"channel_id" gorm:"index"`
+	Quota       int                         `json:"quota"`
+	RefundQuota int                         `json:"refund_quota" gorm:"default:0"`        // 已退还的额度
+	Action      string                      `json:"action" gorm:"type:varchar(40);index"` // 任务类型, song, lyrics, description-mode
+	Status      TaskStatus                  `json:"status" gorm:"type:varchar(20);index"` // 任务状态
+	FailReason  string                      `json:"fail_reason"`
+	SubmitTime  int64                       `json:"submit_time" gorm:"index"`
+	StartTime   int64                       `json:"start_time" gorm:"index"`
+	FinishTime  int64                       `json:"finish_time" gorm:"index"`
+	Progress    int                         `json:"progress"`
+	Properties  datatypes.JSON              `json:"properties" gorm:"type:json"`
+	Data        datatypes.JSON              `json:"data" gorm:"type:json"`
+	NotifyHook  string                      `json:"notify_hook"`
+	TokenID     int                         `json:"token_id" gorm:"default:0"`
+	Prompt      string                      `json:"prompt" gorm:"type:text"`
+	StoredUrls  datatypes.JSONSlice[string] `json:"stored_urls" gorm:"type:json"` // 转存后的产出地址，与平台返回的产出一一对应
 }
 
 func GetTaskByTaskIds(platform string, userId int, taskIds []string) (task []*Task, err error) {
@@ -71,6 +74,18 @@ func GetTaskByTaskId(platform string, userId int, taskId string) (task *Task, er
 	return
 }
 
+// GetUserTaskByTaskId 不区分平台查询用户的任务
+func GetUserTaskByTaskId(userId int, taskId string) (task *Task, err error) {
+	task = &Task{}
+	err = DB.Omit("channel_id").Where("user_id = ? and task_id = ?", userId, taskId).Order("id desc").First(task).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return
+}
+
 func (Task *Task) Insert() error {
 	return DB.Create(Task).Error
 }
@@ -79,6 +94,36 @@ func (Task *Task) Update() error {
 	return DB.Save(Task).Error
 }
 
+func UpdateTaskRefundQuota(id int64, refundQuota int) error {
+	return DB.Model(&Task{}).Where("id = ?", id).Update("refund_quota", refundQuota).Error
+}
+
+func UpdateTaskStoredUrls(id int64, storedUrls []string) error {
+	return DB.Model(&Task{}).Where("id = ?", id).Update("stored_urls", datatypes.JSONSlice[string](storedUrls)).Error
+}
+
+// ClearTaskStoredUrl 转存文件被删除后清除任务中对应的地址，查询任务时回退到上游地址
+func ClearTaskStoredUrl(platform string, userId int, taskId string, storedUrl string) error {
+	task, err := GetTaskByTaskId(platform, userId, taskId)
+	if err != nil || task == nil {
+		return err
+	}
+
+	changed := false
+	storedUrls := []string(task.StoredUrls)
+	for i, url := range storedUrls {
+		if url == storedUrl {
+			storedUrls[i] = ""
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	return UpdateTaskStoredUrls(task.ID, storedUrls)
+}
+
 func TaskBulkUpdate(TaskIds []string, params map[string]any) error {
 	if len(TaskIds) == 0 {
 		return nil
@@ -128,6 +173,7 @@ type TaskQueryParams struct {
 	EndTimestamp   int64  `form:"end_timestamp"`
 	UserIDs        []int  `form:"user_ids"`
 	TokenID        int    `form:"token_id"`
+	Refunded       bool   `form:"refunded"`
 }
 
 var allowedTaskOrderFields = map[string]bool{
@@ -171,6 +217,9 @@ func GetAllTasks(params *TaskQueryParams) (*DataResult[Task], error) {
 	if params.EndTimestamp != 0 {
 		tx = tx.Where("submit_time <= ?", params.EndTimestamp)
 	}
+	if params.Refunded {
+		tx = tx.Where("refund_quota > 0")
+	}
 
 	return PaginateAndOrder(tx, &params.PaginationParams, &tasks, allowedTaskOrderFields)
 }
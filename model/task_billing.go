@@ -0,0 +1,158 @@
+package model
+
+import (
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	TaskRefundReasonFailure = "failure" // 任务失败，全额退款
+	TaskRefundReasonPartial = "partial" // 部分产出失败，按比例退款
+
+	TaskRefundPlatformMidjourney = "midjourney"
+)
+
+// RefundTaskQuota 退还异步任务已扣除的额度，同时扣减用户和渠道的已用额度
+// 令牌已删除时只退还到用户额度
+func RefundTaskQuota(userId int, tokenId int, channelId int, quota int) error {
+	if quota <= 0 {
+		return nil
+	}
+
+	if err := IncreaseUserQuota(userId, quota); err != nil {
+		return err
+	}
+
+	if token, err := GetTokenById(tokenId); err == nil && !token.UnlimitedQuota {
+		if err := IncreaseTokenQuota(tokenId, quota); err != nil {
+			logger.SysError("failed to refund token quota: " + err.Error())
+		}
+	}
+
+	if err := CacheUpdateUserQuota(userId); err != nil {
+		logger.SysError("failed to update user quota cache: " + err.Error())
+	}
+
+	if config.BatchUpdateEnabled {
+		addNewRecord(BatchUpdateTypeUsedQuota, userId, -quota)
+	} else {
+		updateUserUsedQuota(userId, -quota)
+	}
+	UpdateChannelUsedQuota(channelId, -quota)
+
+	return nil
+}
+
+// RecordTaskRefundLog 记录异步任务的退款，平台、任务 ID 和退款原因写入 metadata
+func RecordTaskRefundLog(userId int, channelId int, quota int, content string, metadata map[string]any) {
+	username, _ := CacheGetUsername(userId)
+
+	log := &Log{
+		UserId:    userId,
+		Username:  username,
+		ChannelId: channelId,
+		Quota:     quota,
+		CreatedAt: utils.GetTimestamp(),
+		Type:      LogTypeSystem,
+		Content:   content,
+		Metadata:  datatypes.NewJSONType(metadata),
+	}
+	err := DB.Create(log).Error
+	if err != nil {
+		logger.SysError("failed to record log: " + err.Error())
+	}
+}
+
+type TaskRefundReportParams struct {
+	Platform       string `form:"platform"`
+	ChannelId      int    `form:"channel_id"`
+	StartTimestamp int64  `form:"start_timestamp"`
+	EndTimestamp   int64  `form:"end_timestamp"`
+}
+
+// TaskRefundReportItem 按渠道和平台汇总的退款
+type TaskRefundReportItem struct {
+	ChannelId   int    `json:"channel_id"`
+	ChannelName string `json:"channel_name" gorm:"-"`
+	Platform    string `json:"platform"`
+	TaskCount   int64  `json:"task_count"`
+	Quota       int64  `json:"quota"`
+	RefundQuota int64  `json:"refund_quota"`
+}
+
+// GetTaskRefundReport 统计各渠道退款的任务，包括 Midjourney 任务
+func GetTaskRefundReport(params *TaskRefundReportParams) ([]*TaskRefundReportItem, error) {
+	items := make([]*TaskRefundReportItem, 0)
+
+	if params.Platform != TaskRefundPlatformMidjourney {
+		tx := DB.Model(&Task{}).
+			Select("channel_id, platform, count(*) as task_count, sum(quota) as quota, sum(refund_quota) as refund_quota").
+			Where("refund_quota > 0")
+		if params.Platform != "" {
+			tx = tx.Where("platform = ?", params.Platform)
+		}
+		if params.ChannelId > 0 {
+			tx = tx.Where("channel_id = ?", params.ChannelId)
+		}
+		if params.StartTimestamp != 0 {
+			tx = tx.Where("submit_time >= ?", params.StartTimestamp)
+		}
+		if params.EndTimestamp != 0 {
+			tx = tx.Where("submit_time <= ?", params.EndTimestamp)
+		}
+
+		var taskItems []*TaskRefundReportItem
+		if err := tx.Group("channel_id, platform").Scan(&taskItems).Error; err != nil {
+			return nil, err
+		}
+		items = append(items, taskItems...)
+	}
+
+	if params.Platform == "" || params.Platform == TaskRefundPlatformMidjourney {
+		// Midjourney 任务的提交时间为毫秒
+		tx := DB.Model(&Midjourney{}).
+			Select("channel_id, count(*) as task_count, sum(quota) as quota, sum(refund_quota) as refund_quota").
+			Where("refund_quota > 0")
+		if params.ChannelId > 0 {
+			tx = tx.Where("channel_id = ?", params.ChannelId)
+		}
+		if params.StartTimestamp != 0 {
+			tx = tx.Where("submit_time >= ?", params.StartTimestamp*1000)
+		}
+		if params.EndTimestamp != 0 {
+			tx = tx.Where("submit_time <= ?", params.EndTimestamp*1000)
+		}
+
+		var mjItems []*TaskRefundReportItem
+		if err := tx.Group("channel_id").Scan(&mjItems).Error; err != nil {
+			return nil, err
+		}
+		for _, item := range mjItems {
+			item.Platform = TaskRefundPlatformMidjourney
+		}
+		items = append(items, mjItems...)
+	}
+
+	channelIds := make([]int, 0, len(items))
+	for _, item := range items {
+		channelIds = append(channelIds, item.ChannelId)
+	}
+	if len(channelIds) > 0 {
+		var channels []*Channel
+		if err := DB.Select("id, name").Where("id in (?)", channelIds).Find(&channels).Error; err != nil {
+			return nil, err
+		}
+		names := make(map[int]string, len(channels))
+		for _, channel := range channels {
+			names[channel.Id] = channel.Name
+		}
+		for _, item := range items {
+			item.ChannelName = names[item.ChannelId]
+		}
+	}
+
+	return items, nil
+}
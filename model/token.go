@@ -1,6 +1,8 @@
 package model
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"one-api/common"
@@ -37,6 +39,7 @@ type Token struct {
 	UsedQuota      int            `json:"used_quota" gorm:"default:0"` // used quota
 	Group          string         `json:"group" gorm:"default:''"`
 	BackupGroup    string         `json:"backup_group" gorm:"default:''"`
+	WebhookSecret  string         `json:"webhook_secret" gorm:"type:varchar(64);default:''"` // 任务回调签名密钥，与令牌分开，泄露后可单独重置
 	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 
 	Setting database.JSONType[TokenSetting] `json:"setting" form:"setting" gorm:"type:json"`
@@ -59,13 +62,69 @@ func (token *Token) AfterCreate(tx *gorm.DB) (err error) {
 		return err
 	}
 
+	webhookSecret, err := generateWebhookSecret()
+	if err != nil {
+		return err
+	}
+
 	// 更新 key 字段
-	return tx.Model(token).Update("key", tokenKey).Error
+	return tx.Model(token).Updates(map[string]any{"key": tokenKey, "webhook_secret": webhookSecret}).Error
+}
+
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// ResetTokenWebhookSecret 重新生成令牌的任务回调签名密钥
+func ResetTokenWebhookSecret(id int, userId int) (string, error) {
+	token, err := GetTokenByIds(id, userId)
+	if err != nil {
+		return "", err
+	}
+
+	return token.resetWebhookSecret()
+}
+
+// GetTokenWebhookSecret 获取令牌的任务回调签名密钥，旧令牌没有密钥时自动生成
+func GetTokenWebhookSecret(id int) (string, error) {
+	token, err := GetTokenById(id)
+	if err != nil {
+		return "", err
+	}
+	if token.WebhookSecret != "" {
+		return token.WebhookSecret, nil
+	}
+
+	return token.resetWebhookSecret()
+}
+
+func (token *Token) resetWebhookSecret() (string, error) {
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return "", err
+	}
+
+	err = DB.Model(token).Update("webhook_secret", secret).Error
+	if err == nil && config.RedisEnabled {
+		redis.RedisDel(fmt.Sprintf(UserTokensKey, token.Key))
+	}
+
+	return secret, err
 }
 
 type TokenSetting struct {
 	Heartbeat HeartbeatSetting `json:"heartbeat,omitempty"`
 	Limits    LimitsConfig     `json:"limits,omitempty"`
+	Hedge     HedgeSetting     `json:"hedge,omitempty"`
+
+	ResponseCache ResponseCacheSetting `json:"response_cache,omitempty"`
+	Capture       CaptureSetting       `json:"capture,omitempty"`
+	SafeRedact    SafeRedactSetting    `json:"safe_redact,omitempty"`
+	QoS           QoSSetting           `json:"qos,omitempty"`
 }
 
 type HeartbeatSetting struct {
@@ -73,9 +132,36 @@ type HeartbeatSetting struct {
 	TimeoutSeconds int  `json:"timeout_seconds"`
 }
 
+// HedgeSetting 流式请求在指定时间内没有返回首个数据块时，向另一个渠道发起相同的请求
+type HedgeSetting struct {
+	Enabled bool `json:"enabled"`
+	DelayMs int  `json:"delay_ms"`
+}
+
+type ResponseCacheSetting struct {
+	Disabled bool `json:"disabled"` // 该令牌不使用响应缓存
+}
+
+// CaptureSetting 记录该令牌完整的请求和响应内容，用于审计和问题排查
+type CaptureSetting struct {
+	Enabled bool `json:"enabled"`
+}
+
+// SafeRedactSetting 请求中个人信息、密钥的处理方式，为空则使用分组或全局设置
+type SafeRedactSetting struct {
+	Mode string `json:"mode"` // off、block、mask、log
+}
+
+// QoSSetting 请求优先级，为空则使用分组或全局设置
+type QoSSetting struct {
+	Class string `json:"class"` // realtime、interactive、batch
+}
+
 type LimitsConfig struct {
-	LimitModelSetting LimitModelSetting `json:"limit_model_setting,omitempty"`
-	LimitsIPSetting   LimitsIPSetting   `json:"limits_ip_setting,omitempty"`
+	LimitModelSetting   LimitModelSetting   `json:"limit_model_setting,omitempty"`
+	LimitsIPSetting     LimitsIPSetting     `json:"limits_ip_setting,omitempty"`
+	LimitsBudgetSetting LimitsBudgetSetting `json:"limits_budget_setting,omitempty"`
+	LimitsRateSetting   LimitsRateSetting   `json:"limits_rate_setting,omitempty"`
 }
 
 type LimitModelSetting struct {
@@ -88,6 +174,23 @@ type LimitsIPSetting struct {
 	Whitelist []string `json:"whitelist"`
 }
 
+// LimitsBudgetSetting 令牌在滚动的 24 小时、7 天、30 天内的消费上限，以及单个模型的消费上限，0 为不限制
+type LimitsBudgetSetting struct {
+	Enabled      bool           `json:"enabled"`
+	DailyQuota   int            `json:"daily_quota"`
+	WeeklyQuota  int            `json:"weekly_quota"`
+	MonthlyQuota int            `json:"monthly_quota"`
+	ModelQuotas  map[string]int `json:"model_quotas"`
+	ModelPeriod  string         `json:"model_period"` // 模型消费上限的周期 day / week / month，默认为 month
+}
+
+// LimitsRateSetting 令牌每分钟的请求数和 token 数上限，0 为不限制
+type LimitsRateSetting struct {
+	Enabled bool `json:"enabled"`
+	RPM     int  `json:"rpm"`
+	TPM     int  `json:"tpm"`
+}
+
 func GetUserTokensList(userId int, params *GenericParams) (*DataResult[Token], error) {
 	var tokens []*Token
 	db := DB.Where("user_id = ?", userId)
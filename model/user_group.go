@@ -15,11 +15,17 @@ type UserGroup struct {
 	Name      string  `json:"name" gorm:"type:varchar(50)"`
 	Ratio     float64 `json:"ratio" gorm:"type:decimal(10,2); default:1"`      // 倍率
 	APIRate   int     `json:"api_rate" gorm:"default:600"`                     // 每分组允许的请求数
+	TPM       int     `json:"tpm" form:"tpm" gorm:"default:0"`                 // 每分组每分钟允许的 token 数，为 0 则不限制
 	Public    bool    `json:"public" form:"public" gorm:"default:false"`       // 是否为公开分组，如果是，则可以被用户在令牌中选择
 	Promotion bool    `json:"promotion" form:"promotion" gorm:"default:false"` // 是否是自动升级用户组， 如果是则用户充值金额满足条件自动升级
 	Min       int     `json:"min" form:"min" gorm:"default:0"`                 // 晋级条件最小值
 	Max       int     `json:"max" form:"max" gorm:"default:0"`                 // 晋级条件最大值
 	Enable    *bool   `json:"enable" form:"enable" gorm:"default:true"`        // 是否启用
+
+	BalanceStrategy string `json:"balance_strategy" form:"balance_strategy" gorm:"type:varchar(32);default:''"` // 渠道负载均衡策略，为空则使用全局设置
+	HedgeDelay      int    `json:"hedge_delay" form:"hedge_delay" gorm:"default:0"`                             // 流式请求对冲等待时间(毫秒)，为 0 则不对冲
+	SafeRedactMode  string `json:"safe_redact_mode" form:"safe_redact_mode" gorm:"type:varchar(16);default:''"` // 请求中个人信息、密钥的处理方式，为空则使用全局设置
+	QoSClass        string `json:"qos_class" form:"qos_class" gorm:"type:varchar(16);default:''"`               // 请求优先级，为空则使用全局设置
 }
 
 type SearchUserGroupParams struct {
@@ -75,7 +81,7 @@ func (c *UserGroup) Create() error {
 }
 
 func (c *UserGroup) Update() error {
-	err := DB.Select("name", "ratio", "public", "api_rate", "promotion", "min", "max").Updates(c).Error
+	err := DB.Select("name", "ratio", "public", "api_rate", "tpm", "promotion", "min", "max", "balance_strategy", "hedge_delay", "safe_redact_mode", "qos_class").Updates(c).Error
 	if err == nil {
 		GlobalUserGroupRatio.Load()
 	}
@@ -175,6 +181,33 @@ func (cgrm *UserGroupRatio) GetAPIRate(symbol string) int {
 	return userGroup.APIRate
 }
 
+func (cgrm *UserGroupRatio) GetTPM(symbol string) int {
+	userGroup := cgrm.GetBySymbol(symbol)
+	if userGroup == nil {
+		return 0
+	}
+
+	return userGroup.TPM
+}
+
+func (cgrm *UserGroupRatio) GetSafeRedactMode(symbol string) string {
+	userGroup := cgrm.GetBySymbol(symbol)
+	if userGroup == nil {
+		return ""
+	}
+
+	return userGroup.SafeRedactMode
+}
+
+func (cgrm *UserGroupRatio) GetQoSClass(symbol string) string {
+	userGroup := cgrm.GetBySymbol(symbol)
+	if userGroup == nil {
+		return ""
+	}
+
+	return userGroup.QoSClass
+}
+
 func (cgrm *UserGroupRatio) GetPublicGroupList() []string {
 	cgrm.RLock()
 	defer cgrm.RUnlock()
@@ -207,7 +240,7 @@ func CheckAndUpgradeUserGroup(userId int, rechargeAmount int) error {
 
 	// Calculate cumulative recharge amount
 	cumulativeAmount := user.Quota + user.UsedQuota + rechargeAmount
-	logger.SysError(fmt.Sprintf("use:%f q:%f  cumulative:%f rechargeAmount:%f", (float64)(user.UsedQuota)/config.QuotaPerUnit, (float64)(user.Quota)/config.QuotaPerUnit, cumulativeAmount, rechargeAmount))
+	logger.SysError(fmt.Sprintf("use:%f q:%f  cumulative:%d rechargeAmount:%d", (float64)(user.UsedQuota)/config.QuotaPerUnit, (float64)(user.Quota)/config.QuotaPerUnit, cumulativeAmount, rechargeAmount))
 	// Get all promotion-enabled user groups
 	var promotionGroups []*UserGroup
 	err = DB.Where("promotion = ? AND enable = ?", true, true).Find(&promotionGroups).Error
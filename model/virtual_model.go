@@ -0,0 +1,263 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/datatypes"
+)
+
+// VirtualModel 全局虚拟模型，客户端使用固定的模型名称，由系统按权重选择实际模型
+// 选中的模型没有可用渠道时，依次尝试其余目标模型和备用模型
+type VirtualModel struct {
+	Id          int                                     `json:"id"`
+	Name        string                                  `json:"name" gorm:"type:varchar(100);uniqueIndex"`
+	Description string                                  `json:"description" gorm:"type:varchar(255);default:''"`
+	Targets     datatypes.JSONSlice[VirtualModelTarget] `json:"targets" gorm:"type:json"`
+	Fallbacks   datatypes.JSONSlice[string]             `json:"fallbacks" gorm:"type:json"`
+	Enabled     *bool                                   `json:"enabled" gorm:"default:true"`
+	CreatedAt   int64                                   `json:"created_at" gorm:"bigint"`
+	UpdatedAt   int64                                   `json:"updated_at" gorm:"bigint"`
+}
+
+type VirtualModelTarget struct {
+	Model  string `json:"model"`
+	Weight int    `json:"weight"`
+}
+
+type SearchVirtualModelParams struct {
+	Name string `form:"name"`
+	PaginationParams
+}
+
+var allowedVirtualModelOrderFields = map[string]bool{
+	"id":         true,
+	"name":       true,
+	"created_at": true,
+}
+
+func GetVirtualModelsList(params *SearchVirtualModelParams) (*DataResult[VirtualModel], error) {
+	var virtualModels []*VirtualModel
+	db := DB
+
+	if params.Name != "" {
+		db = db.Where("name LIKE ?", params.Name+"%")
+	}
+
+	return PaginateAndOrder(db, &params.PaginationParams, &virtualModels, allowedVirtualModelOrderFields)
+}
+
+func GetVirtualModelById(id int) (*VirtualModel, error) {
+	var virtualModel VirtualModel
+	err := DB.Where("id = ?", id).First(&virtualModel).Error
+	return &virtualModel, err
+}
+
+// Validate 校验虚拟模型的配置
+func (v *VirtualModel) Validate() error {
+	v.Name = strings.TrimSpace(v.Name)
+	if v.Name == "" {
+		return errors.New("虚拟模型名称不能为空")
+	}
+	if len(v.Targets) == 0 {
+		return errors.New("至少需要一个目标模型")
+	}
+
+	for _, target := range v.Targets {
+		if target.Model == "" || target.Weight <= 0 {
+			return errors.New("目标模型名称不能为空，权重必须大于 0")
+		}
+	}
+
+	// 与渠道中的实际模型重名时，请求会被虚拟模型接管
+	if _, ok := ChannelGroup.GetModelsGroups()[v.Name]; ok {
+		return fmt.Errorf("虚拟模型名称 %s 与渠道中的模型重名", v.Name)
+	}
+
+	models := v.allModels()
+	for _, modelName := range models {
+		if modelName == v.Name {
+			return errors.New("目标模型不能是虚拟模型自身")
+		}
+	}
+
+	// 未启用的虚拟模型也不能作为目标，避免启用后形成嵌套
+	var nested []string
+	if err := DB.Model(&VirtualModel{}).Where("name IN ? AND id <> ?", models, v.Id).Pluck("name", &nested).Error; err != nil {
+		return err
+	}
+	if len(nested) > 0 {
+		return fmt.Errorf("目标模型 %s 是虚拟模型，不支持嵌套", nested[0])
+	}
+
+	return nil
+}
+
+func (v *VirtualModel) Create() error {
+	v.CreatedAt = utils.GetTimestamp()
+	v.UpdatedAt = v.CreatedAt
+	err := DB.Create(v).Error
+	if err == nil {
+		GlobalVirtualModels.Load()
+	}
+	return err
+}
+
+func (v *VirtualModel) Update() error {
+	v.UpdatedAt = utils.GetTimestamp()
+	err := DB.Select("name", "description", "targets", "fallbacks", "enabled", "updated_at").Updates(v).Error
+	if err == nil {
+		GlobalVirtualModels.Load()
+	}
+	return err
+}
+
+func (v *VirtualModel) Delete() error {
+	err := DB.Delete(v).Error
+	if err == nil {
+		GlobalVirtualModels.Load()
+	}
+	return err
+}
+
+// allModels 目标模型和备用模型，按配置顺序去重
+func (v *VirtualModel) allModels() []string {
+	models := make([]string, 0, len(v.Targets)+len(v.Fallbacks))
+	for _, target := range v.Targets {
+		models = append(models, target.Model)
+	}
+	models = append(models, v.Fallbacks...)
+	return uniqueModels(models)
+}
+
+// Candidates 按尝试顺序返回实际模型：按权重随机选中的目标模型，其余目标模型(权重从高到低)，备用模型
+func (v *VirtualModel) Candidates() []string {
+	targets := make([]VirtualModelTarget, len(v.Targets))
+	copy(targets, v.Targets)
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].Weight > targets[j].Weight
+	})
+
+	total := 0
+	for _, target := range targets {
+		total += target.Weight
+	}
+
+	models := make([]string, 0, len(targets)+len(v.Fallbacks))
+	if total > 0 {
+		r := rand.Intn(total)
+		for _, target := range targets {
+			if r < target.Weight {
+				models = append(models, target.Model)
+				break
+			}
+			r -= target.Weight
+		}
+	}
+	for _, target := range targets {
+		models = append(models, target.Model)
+	}
+	models = append(models, v.Fallbacks...)
+
+	return uniqueModels(models)
+}
+
+// PrimaryModel 权重最高的目标模型，虚拟模型未单独定价时按该模型计费
+func (v *VirtualModel) PrimaryModel() string {
+	primary := ""
+	weight := 0
+	for _, target := range v.Targets {
+		if target.Weight > weight {
+			primary = target.Model
+			weight = target.Weight
+		}
+	}
+	return primary
+}
+
+func uniqueModels(models []string) []string {
+	seen := make(map[string]bool, len(models))
+	result := make([]string, 0, len(models))
+	for _, modelName := range models {
+		if modelName == "" || seen[modelName] {
+			continue
+		}
+		seen[modelName] = true
+		result = append(result, modelName)
+	}
+	return result
+}
+
+type VirtualModels struct {
+	sync.RWMutex
+	Models map[string]*VirtualModel
+}
+
+var GlobalVirtualModels = VirtualModels{
+	Models: make(map[string]*VirtualModel),
+}
+
+// Load 加载已启用的虚拟模型
+func (vm *VirtualModels) Load() {
+	var virtualModels []*VirtualModel
+	if err := DB.Where("enabled = ?", true).Find(&virtualModels).Error; err != nil {
+		logger.SysError("failed to load virtual models: " + err.Error())
+		return
+	}
+
+	newModels := make(map[string]*VirtualModel, len(virtualModels))
+	for _, virtualModel := range virtualModels {
+		newModels[virtualModel.Name] = virtualModel
+	}
+
+	vm.Lock()
+	defer vm.Unlock()
+	vm.Models = newModels
+}
+
+// Get 获取虚拟模型，不存在时返回 nil
+func (vm *VirtualModels) Get(name string) *VirtualModel {
+	vm.RLock()
+	defer vm.RUnlock()
+
+	return vm.Models[name]
+}
+
+// Resolve 返回按尝试顺序排列的实际模型，非虚拟模型返回自身
+func (vm *VirtualModels) Resolve(name string) []string {
+	virtualModel := vm.Get(name)
+	if virtualModel == nil {
+		return []string{name}
+	}
+
+	return virtualModel.Candidates()
+}
+
+// GetAvailableNames 获取至少有一个实际模型在 models 中的虚拟模型名称
+func (vm *VirtualModels) GetAvailableNames(models []string) []string {
+	available := make(map[string]bool, len(models))
+	for _, modelName := range models {
+		available[modelName] = true
+	}
+
+	vm.RLock()
+	defer vm.RUnlock()
+
+	names := make([]string, 0)
+	for name, virtualModel := range vm.Models {
+		for _, modelName := range virtualModel.allModels() {
+			if available[modelName] {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+
+	return names
+}
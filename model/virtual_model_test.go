@@ -0,0 +1,165 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupVirtualModelDB(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&VirtualModel{}))
+
+	originDB := DB
+	originModelGroup := ChannelGroup.ModelGroup
+	DB = db
+	t.Cleanup(func() {
+		DB = originDB
+		ChannelGroup.ModelGroup = originModelGroup
+	})
+}
+
+func TestVirtualModelValidate(t *testing.T) {
+	setupVirtualModelDB(t)
+
+	disabled := false
+	assert.NoError(t, DB.Create(&VirtualModel{Name: "virtual-disabled", Enabled: &disabled}).Error)
+	ChannelGroup.ModelGroup = map[string]map[string]bool{"gpt-4o": {"default": true}}
+
+	cases := []struct {
+		name      string
+		model     VirtualModel
+		expectErr bool
+	}{
+		{
+			name:  "正常",
+			model: VirtualModel{Name: "virtual", Targets: []VirtualModelTarget{{"gpt-4o", 1}}, Fallbacks: []string{"gpt-4o-mini"}},
+		},
+		{
+			name:      "没有目标模型",
+			model:     VirtualModel{Name: "virtual"},
+			expectErr: true,
+		},
+		{
+			name:      "权重为 0",
+			model:     VirtualModel{Name: "virtual", Targets: []VirtualModelTarget{{"gpt-4o", 0}}},
+			expectErr: true,
+		},
+		{
+			name:      "与渠道中的模型重名",
+			model:     VirtualModel{Name: "gpt-4o", Targets: []VirtualModelTarget{{"gpt-4o-mini", 1}}},
+			expectErr: true,
+		},
+		{
+			name:      "目标为自身",
+			model:     VirtualModel{Name: "virtual", Targets: []VirtualModelTarget{{"virtual", 1}}},
+			expectErr: true,
+		},
+		{
+			name:      "未启用的虚拟模型作为备用模型",
+			model:     VirtualModel{Name: "virtual", Targets: []VirtualModelTarget{{"gpt-4o", 1}}, Fallbacks: []string{"virtual-disabled"}},
+			expectErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.model.Validate()
+			if c.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVirtualModelCandidates(t *testing.T) {
+	cases := []struct {
+		name      string
+		targets   []VirtualModelTarget
+		fallbacks []string
+		order     []string // 除首个选中的模型外，其余模型的顺序
+		share     map[string]float64
+	}{
+		{
+			name:    "单个目标",
+			targets: []VirtualModelTarget{{"a", 5}},
+			order:   []string{"a"},
+			share:   map[string]float64{"a": 1},
+		},
+		{
+			name:      "按权重从高到低，备用模型在最后且去重",
+			targets:   []VirtualModelTarget{{"a", 1}, {"b", 3}, {"c", 1}},
+			fallbacks: []string{"d", "a"},
+			order:     []string{"b", "a", "c", "d"},
+			share:     map[string]float64{"a": 0.2, "b": 0.6, "c": 0.2},
+		},
+	}
+
+	const runs = 5000
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v := &VirtualModel{Targets: c.targets, Fallbacks: c.fallbacks}
+			picked := make(map[string]int)
+			for i := 0; i < runs; i++ {
+				candidates := v.Candidates()
+				picked[candidates[0]]++
+
+				expected := []string{candidates[0]}
+				for _, modelName := range c.order {
+					if modelName != candidates[0] {
+						expected = append(expected, modelName)
+					}
+				}
+				assert.Equal(t, expected, candidates)
+			}
+
+			for modelName, share := range c.share {
+				assert.InDelta(t, share, float64(picked[modelName])/runs, 0.05, modelName)
+			}
+		})
+	}
+}
+
+func TestVirtualModelPrimaryModelPrice(t *testing.T) {
+	pricing := &Pricing{
+		Prices: map[string]*Price{
+			"a":       {Model: "a", Input: 1},
+			"b":       {Model: "b", Input: 2},
+			"priced":  {Model: "priced", Input: 3},
+			"virtual": {Model: "virtual", Input: 4},
+		},
+	}
+
+	originModels := GlobalVirtualModels.Models
+	t.Cleanup(func() {
+		GlobalVirtualModels.Models = originModels
+	})
+
+	cases := []struct {
+		name     string
+		model    string
+		targets  []VirtualModelTarget
+		primary  string
+		expected float64
+	}{
+		{"按权重最高的目标模型计费", "v1", []VirtualModelTarget{{"a", 1}, {"b", 3}}, "b", 2},
+		{"权重相同时使用第一个", "v2", []VirtualModelTarget{{"a", 2}, {"b", 2}}, "a", 1},
+		{"主模型未定价时使用默认价格", "v3", []VirtualModelTarget{{"unknown", 2}, {"a", 1}}, "unknown", DefaultPrice},
+		{"虚拟模型单独定价时优先", "virtual", []VirtualModelTarget{{"priced", 1}}, "priced", 4},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v := &VirtualModel{Name: c.model, Targets: c.targets}
+			GlobalVirtualModels.Models = map[string]*VirtualModel{c.model: v}
+
+			assert.Equal(t, c.primary, v.PrimaryModel())
+			assert.Equal(t, c.expected, pricing.GetPrice(c.model).Input)
+		})
+	}
+}
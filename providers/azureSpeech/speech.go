@@ -15,6 +15,8 @@ var outputFormatMap = map[string]string{
 	"opus": "audio-16khz-128kbitrate-mono-opus",
 	"aac":  "audio-24khz-160kbitrate-mono-mp3",
 	"flac": "audio-48khz-192kbitrate-mono-mp3",
+	// 与 OpenAI 一致，24kHz 16bit 单声道的原始音频
+	"pcm": "raw-24khz-16bit-mono-pcm",
 }
 
 func CreateSSML(text string, name string, role string) string {
@@ -128,6 +128,10 @@ func (p *BaseProvider) SetUsage(usage *types.Usage) {
 
 func (p *BaseProvider) SetContext(c *gin.Context) {
 	p.Context = c
+	if p.Requester != nil && c != nil && c.Request != nil {
+		// 默认挂在请求的 span 下，中继每次尝试时会替换为该次尝试的 span
+		p.Requester.TraceContext = c.Request.Context()
+	}
 }
 
 func (p *BaseProvider) SetOriginalModel(ModelName string) {
@@ -102,6 +102,23 @@ func stopReasonClaude2OpenAI(reason string) string {
 	}
 }
 
+func StopReasonOpenAI2Claude(reason string) string {
+	switch reason {
+	case types.FinishReasonStop:
+		return FinishReasonEndTurn
+	case types.FinishReasonLength:
+		return "max_tokens"
+	case types.FinishReasonToolCalls, types.FinishReasonFunctionCall:
+		return FinishReasonToolUse
+	case types.FinishReasonContentFilter:
+		return "refusal"
+	case "":
+		return FinishReasonEndTurn
+	default:
+		return reason
+	}
+}
+
 func convertRole(role string) string {
 	switch role {
 	case types.ChatMessageRoleUser, types.ChatMessageRoleTool, types.ChatMessageRoleFunction:
@@ -0,0 +1,381 @@
+package claude
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common/utils"
+	"one-api/types"
+	"strings"
+)
+
+// ToChatCompletionRequest 将 Claude Messages 请求转换为 OpenAI Chat 请求，用于非 Claude 渠道
+func (r *ClaudeRequest) ToChatCompletionRequest() (*types.ChatCompletionRequest, error) {
+	chat := &types.ChatCompletionRequest{
+		Model:       r.Model,
+		Messages:    make([]types.ChatCompletionMessage, 0, len(r.Messages)+1),
+		MaxTokens:   r.MaxTokens,
+		Temperature: r.Temperature,
+		TopP:        r.TopP,
+		Stream:      r.Stream,
+	}
+
+	if r.TopK != nil {
+		topK := float64(*r.TopK)
+		chat.TopK = &topK
+	}
+
+	if len(r.StopSequences) > 0 {
+		chat.Stop = r.StopSequences
+	}
+
+	if system := systemToString(r.System); system != "" {
+		chat.Messages = append(chat.Messages, types.ChatCompletionMessage{
+			Role:    types.ChatMessageRoleSystem,
+			Content: system,
+		})
+	}
+
+	for _, message := range r.Messages {
+		messages, err := convertClaudeMessage(&message)
+		if err != nil {
+			return nil, err
+		}
+		chat.Messages = append(chat.Messages, messages...)
+	}
+
+	for _, tool := range r.Tools {
+		// 服务端工具(web_search、bash 等)无法在其他渠道上执行
+		if tool.Type != "" && tool.Type != "custom" {
+			continue
+		}
+		chat.Tools = append(chat.Tools, &types.ChatCompletionTool{
+			Type: "function",
+			Function: types.ChatCompletionFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+
+	if r.ToolChoice != nil && len(chat.Tools) > 0 {
+		switch r.ToolChoice.Type {
+		case "any":
+			chat.ToolChoice = types.ToolChoiceTypeRequired
+		case "tool":
+			chat.ToolChoice = map[string]any{
+				"type": types.ToolChoiceTypeFunction,
+				"function": map[string]any{
+					"name": r.ToolChoice.Name,
+				},
+			}
+		case "none":
+			chat.ToolChoice = types.ToolChoiceTypeNone
+		default:
+			chat.ToolChoice = types.ToolChoiceTypeAuto
+		}
+	}
+
+	if r.Thinking != nil && r.Thinking.Type == "enabled" {
+		chat.Reasoning = &types.ChatReasoning{
+			MaxTokens: r.Thinking.BudgetTokens,
+		}
+	}
+
+	return chat, nil
+}
+
+func systemToString(system any) string {
+	switch v := system.(type) {
+	case string:
+		return v
+	case []any:
+		var builder strings.Builder
+		for _, item := range v {
+			block, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			if text, ok := block["text"].(string); ok && text != "" {
+				if builder.Len() > 0 {
+					builder.WriteString("\n")
+				}
+				builder.WriteString(text)
+			}
+		}
+		return builder.String()
+	}
+
+	return ""
+}
+
+func parseMessageContents(content any) ([]MessageContent, error) {
+	if text, ok := content.(string); ok {
+		return []MessageContent{{Type: ContentTypeText, Text: text}}, nil
+	}
+
+	contentBytes, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var contents []MessageContent
+	if err := json.Unmarshal(contentBytes, &contents); err != nil {
+		return nil, err
+	}
+
+	return contents, nil
+}
+
+// convertClaudeMessage 一条 Claude 消息可能拆分为多条 OpenAI 消息，tool_result 需要单独作为 tool 消息并放在最前面
+func convertClaudeMessage(message *Message) ([]types.ChatCompletionMessage, error) {
+	if text, ok := message.Content.(string); ok {
+		return []types.ChatCompletionMessage{{Role: message.Role, Content: text}}, nil
+	}
+
+	contents, err := parseMessageContents(message.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.Role == types.ChatMessageRoleAssistant {
+		return []types.ChatCompletionMessage{convertAssistantContents(contents)}, nil
+	}
+
+	messages := make([]types.ChatCompletionMessage, 0)
+	parts := make([]types.ChatMessagePart, 0, len(contents))
+
+	for _, content := range contents {
+		switch content.Type {
+		case ContentTypeToolResult:
+			text, images := toolResultContent(content.Content)
+			if content.IsError != nil && *content.IsError {
+				text = "Error: " + text
+			}
+			messages = append(messages, types.ChatCompletionMessage{
+				Role:       types.ChatMessageRoleTool,
+				Content:    text,
+				ToolCallID: content.ToolUseId,
+			})
+			parts = append(parts, images...)
+		case ContentTypeThinking, ContentTypeRedactedThinking:
+			continue
+		default:
+			if part := convertContentPart(&content); part != nil {
+				parts = append(parts, *part)
+			}
+		}
+	}
+
+	if len(parts) > 0 {
+		messages = append(messages, types.ChatCompletionMessage{
+			Role:    message.Role,
+			Content: parts,
+		})
+	}
+
+	return messages, nil
+}
+
+func convertAssistantContents(contents []MessageContent) types.ChatCompletionMessage {
+	message := types.ChatCompletionMessage{
+		Role: types.ChatMessageRoleAssistant,
+	}
+
+	// 历史消息中的 thinking 不回传，部分渠道不接受输入中带有 reasoning_content
+	var text strings.Builder
+	for _, content := range contents {
+		switch content.Type {
+		case ContentTypeText:
+			text.WriteString(content.Text)
+		case ContentTypeToolUes:
+			arguments := "{}"
+			if content.Input != nil {
+				if args, err := json.Marshal(content.Input); err == nil {
+					arguments = string(args)
+				}
+			}
+			message.ToolCalls = append(message.ToolCalls, &types.ChatCompletionToolCalls{
+				Id:    content.Id,
+				Type:  "function",
+				Index: len(message.ToolCalls),
+				Function: &types.ChatCompletionToolCallsFunction{
+					Name:      content.Name,
+					Arguments: arguments,
+				},
+			})
+		}
+	}
+
+	message.Content = text.String()
+
+	return message
+}
+
+func convertContentPart(content *MessageContent) *types.ChatMessagePart {
+	switch content.Type {
+	case ContentTypeText:
+		return &types.ChatMessagePart{
+			Type: types.ContentTypeText,
+			Text: content.Text,
+		}
+	case ContentTypeImage:
+		if url := sourceToURL(content.Source); url != "" {
+			return &types.ChatMessagePart{
+				Type:     types.ContentTypeImageURL,
+				ImageURL: &types.ChatMessageImageURL{URL: url},
+			}
+		}
+	case "document":
+		if content.Source == nil {
+			return nil
+		}
+		if content.Source.Type == "text" {
+			return &types.ChatMessagePart{
+				Type: types.ContentTypeText,
+				Text: content.Source.Data,
+			}
+		}
+		if url := sourceToURL(content.Source); url != "" {
+			return &types.ChatMessagePart{
+				Type: "file",
+				File: &types.ChatMessageFile{
+					Filename: "document.pdf",
+					FileData: url,
+				},
+			}
+		}
+	}
+
+	return nil
+}
+
+func sourceToURL(source *ContentSource) string {
+	if source == nil {
+		return ""
+	}
+
+	switch source.Type {
+	case "base64":
+		return fmt.Sprintf("data:%s;base64,%s", source.MediaType, source.Data)
+	case "url":
+		return source.Url
+	}
+
+	return ""
+}
+
+// toolResultContent tool 消息只支持文本，结果中的图片放到随后的 user 消息中
+func toolResultContent(content any) (string, []types.ChatMessagePart) {
+	if content == nil {
+		return "", nil
+	}
+
+	if text, ok := content.(string); ok {
+		return text, nil
+	}
+
+	contents, err := parseMessageContents(content)
+	if err != nil {
+		return "", nil
+	}
+
+	var text strings.Builder
+	var images []types.ChatMessagePart
+	for _, item := range contents {
+		part := convertContentPart(&item)
+		if part == nil {
+			continue
+		}
+		if part.Type == types.ContentTypeText {
+			text.WriteString(part.Text)
+			continue
+		}
+		images = append(images, *part)
+	}
+
+	return text.String(), images
+}
+
+// ConvertFromChatResponse 将 OpenAI Chat 响应转换为 Claude Messages 响应
+func ConvertFromChatResponse(response *types.ChatCompletionResponse, usage *types.Usage) *ClaudeResponse {
+	claudeResponse := &ClaudeResponse{
+		Id:      "msg_" + response.ID,
+		Type:    "message",
+		Role:    types.ChatMessageRoleAssistant,
+		Content: make([]ResContent, 0),
+		Model:   response.Model,
+	}
+
+	finishReason := ""
+	if len(response.Choices) > 0 {
+		choice := response.Choices[0]
+		finishReason = choice.FinishReason
+
+		reasoning := choice.Message.ReasoningContent
+		if reasoning == "" {
+			reasoning = choice.Message.Reasoning
+		}
+		if reasoning != "" {
+			claudeResponse.Content = append(claudeResponse.Content, ResContent{
+				Type:     ContentTypeThinking,
+				Thinking: reasoning,
+			})
+		}
+
+		if text := choice.Message.StringContent(); text != "" {
+			claudeResponse.Content = append(claudeResponse.Content, ResContent{
+				Type: ContentTypeText,
+				Text: text,
+			})
+		}
+
+		choice.Message.FuncToToolCalls()
+		for _, toolCall := range choice.Message.ToolCalls {
+			if toolCall.Function == nil {
+				continue
+			}
+			claudeResponse.Content = append(claudeResponse.Content, ResContent{
+				Type:  ContentTypeToolUes,
+				Id:    ToolUseID(toolCall.Id),
+				Name:  toolCall.Function.Name,
+				Input: ToolUseInput(toolCall.Function.Arguments),
+			})
+			finishReason = types.FinishReasonToolCalls
+		}
+	}
+
+	claudeResponse.StopReason = StopReasonOpenAI2Claude(finishReason)
+	claudeResponse.Usage = OpenaiUsageToClaudeUsage(usage)
+
+	return claudeResponse
+}
+
+func OpenaiUsageToClaudeUsage(usage *types.Usage) Usage {
+	if usage == nil {
+		return Usage{}
+	}
+
+	cachedTokens := usage.PromptTokensDetails.CachedTokens
+	return Usage{
+		InputTokens:          usage.PromptTokens - cachedTokens,
+		OutputTokens:         usage.CompletionTokens,
+		CacheReadInputTokens: cachedTokens,
+	}
+}
+
+// ToolUseID Claude 客户端依赖 tool_use id 关联 tool_result，上游没有返回时自动生成
+func ToolUseID(id string) string {
+	if id == "" {
+		return "toolu_" + utils.GetRandomString(24)
+	}
+	return id
+}
+
+func ToolUseInput(arguments string) any {
+	input := make(map[string]any)
+	if arguments != "" {
+		json.Unmarshal([]byte(arguments), &input)
+	}
+	return input
+}
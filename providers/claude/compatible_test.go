@@ -0,0 +1,153 @@
+package claude
+
+import (
+	"encoding/json"
+	"one-api/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToChatCompletionRequest(t *testing.T) {
+	cases := []struct {
+		name     string
+		request  string
+		messages string
+	}{
+		{
+			name:     "字符串内容和 system",
+			request:  `{"model":"m","max_tokens":10,"system":"be nice","messages":[{"role":"user","content":"hi"}]}`,
+			messages: `[{"role":"system","content":"be nice"},{"role":"user","content":"hi"}]`,
+		},
+		{
+			name:     "system 为内容块数组",
+			request:  `{"model":"m","max_tokens":10,"system":[{"type":"text","text":"a"},{"type":"text","text":"b"}],"messages":[{"role":"user","content":"hi"}]}`,
+			messages: `[{"role":"system","content":"a\nb"},{"role":"user","content":"hi"}]`,
+		},
+		{
+			name:     "图片转换为 data url",
+			request:  `{"model":"m","max_tokens":10,"messages":[{"role":"user","content":[{"type":"text","text":"look"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAA"}}]}]}`,
+			messages: `[{"role":"user","content":[{"type":"text","text":"look"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAA"}}]}]`,
+		},
+		{
+			name:     "assistant 的 tool_use 转换为 tool_calls，thinking 不回传",
+			request:  `{"model":"m","max_tokens":10,"messages":[{"role":"assistant","content":[{"type":"thinking","thinking":"hmm"},{"type":"text","text":"ok"},{"type":"tool_use","id":"toolu_1","name":"get","input":{"a":1}}]}]}`,
+			messages: `[{"role":"assistant","content":"ok","tool_calls":[{"id":"toolu_1","type":"function","index":0,"function":{"name":"get","arguments":"{\"a\":1}"}}]}]`,
+		},
+		{
+			name:     "tool_result 拆分为 tool 消息并放在前面",
+			request:  `{"model":"m","max_tokens":10,"messages":[{"role":"user","content":[{"type":"text","text":"next"},{"type":"tool_result","tool_use_id":"toolu_1","content":"42"}]}]}`,
+			messages: `[{"role":"tool","content":"42","tool_call_id":"toolu_1"},{"role":"user","content":[{"type":"text","text":"next"}]}]`,
+		},
+		{
+			name:     "tool_result 错误和图片",
+			request:  `{"model":"m","max_tokens":10,"messages":[{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","is_error":true,"content":[{"type":"text","text":"bad"},{"type":"image","source":{"type":"url","url":"https://x/y.png"}}]}]}]}`,
+			messages: `[{"role":"tool","content":"Error: bad","tool_call_id":"toolu_1"},{"role":"user","content":[{"type":"image_url","image_url":{"url":"https://x/y.png"}}]}]`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var request ClaudeRequest
+			assert.NoError(t, json.Unmarshal([]byte(c.request), &request))
+
+			chat, err := request.ToChatCompletionRequest()
+			assert.NoError(t, err)
+
+			messages, _ := json.Marshal(chat.Messages)
+			assert.JSONEq(t, c.messages, string(messages))
+		})
+	}
+}
+
+func TestToChatCompletionRequestTools(t *testing.T) {
+	cases := []struct {
+		name       string
+		toolChoice string
+		expected   any
+	}{
+		{"auto", `{"type":"auto"}`, types.ToolChoiceTypeAuto},
+		{"any", `{"type":"any"}`, types.ToolChoiceTypeRequired},
+		{"none", `{"type":"none"}`, types.ToolChoiceTypeNone},
+		{"tool", `{"type":"tool","name":"get"}`, map[string]any{
+			"type":     types.ToolChoiceTypeFunction,
+			"function": map[string]any{"name": "get"},
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			body := `{"model":"m","max_tokens":10,"messages":[{"role":"user","content":"hi"}],` +
+				`"tools":[{"name":"get","description":"d","input_schema":{"type":"object"}},{"type":"web_search_20250305","name":"web_search"}],` +
+				`"tool_choice":` + c.toolChoice + `}`
+
+			var request ClaudeRequest
+			assert.NoError(t, json.Unmarshal([]byte(body), &request))
+
+			chat, err := request.ToChatCompletionRequest()
+			assert.NoError(t, err)
+
+			// 服务端工具被忽略
+			assert.Len(t, chat.Tools, 1)
+			assert.Equal(t, "get", chat.Tools[0].Function.Name)
+			assert.Equal(t, c.expected, chat.ToolChoice)
+		})
+	}
+}
+
+func TestConvertFromChatResponse(t *testing.T) {
+	cases := []struct {
+		name       string
+		response   string
+		content    string
+		stopReason string
+	}{
+		{
+			name:       "文本",
+			response:   `{"id":"1","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}]}`,
+			content:    `[{"type":"text","text":"hello"}]`,
+			stopReason: FinishReasonEndTurn,
+		},
+		{
+			name:       "推理内容转换为 thinking",
+			response:   `{"id":"1","model":"m","choices":[{"index":0,"message":{"role":"assistant","reasoning_content":"think","content":"hello"},"finish_reason":"length"}]}`,
+			content:    `[{"type":"thinking","thinking":"think"},{"type":"text","text":"hello"}]`,
+			stopReason: "max_tokens",
+		},
+		{
+			name:       "工具调用",
+			response:   `{"id":"1","model":"m","choices":[{"index":0,"message":{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"get","arguments":"{\"a\":1}"}}]},"finish_reason":"stop"}]}`,
+			content:    `[{"type":"tool_use","id":"call_1","name":"get","input":{"a":1}}]`,
+			stopReason: FinishReasonToolUse,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var response types.ChatCompletionResponse
+			assert.NoError(t, json.Unmarshal([]byte(c.response), &response))
+
+			claudeResponse := ConvertFromChatResponse(&response, &types.Usage{PromptTokens: 10, CompletionTokens: 5})
+			content, _ := json.Marshal(claudeResponse.Content)
+			assert.JSONEq(t, c.content, string(content))
+			assert.Equal(t, c.stopReason, claudeResponse.StopReason)
+			assert.Equal(t, "msg_1", claudeResponse.Id)
+		})
+	}
+}
+
+func TestOpenaiUsageToClaudeUsage(t *testing.T) {
+	usage := &types.Usage{
+		PromptTokens:        100,
+		CompletionTokens:    20,
+		PromptTokensDetails: types.PromptTokensDetails{CachedTokens: 30},
+	}
+
+	assert.Equal(t, Usage{InputTokens: 70, OutputTokens: 20, CacheReadInputTokens: 30}, OpenaiUsageToClaudeUsage(usage))
+	assert.Equal(t, Usage{}, OpenaiUsageToClaudeUsage(nil))
+}
+
+func TestToolUseID(t *testing.T) {
+	assert.Equal(t, "toolu_1", ToolUseID("toolu_1"))
+	assert.Regexp(t, `^toolu_\w{24}$`, ToolUseID(""))
+}
@@ -109,6 +109,8 @@ type MessageContent struct {
 	IsError      *bool          `json:"is_error,omitempty"`
 	ToolUseId    string         `json:"tool_use_id,omitempty"`
 	CacheControl any            `json:"cache_control,omitempty"`
+	Thinking     string         `json:"thinking,omitempty"`
+	Signature    string         `json:"signature,omitempty"`
 }
 
 type Message struct {
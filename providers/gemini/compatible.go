@@ -0,0 +1,361 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common/utils"
+	"one-api/types"
+	"strings"
+)
+
+// ToChatCompletionRequest 将 Gemini generateContent 请求转换为 OpenAI Chat 请求，用于非 Gemini 渠道
+func (r *GeminiChatRequest) ToChatCompletionRequest() (*types.ChatCompletionRequest, error) {
+	generationConfig := r.GenerationConfig
+	chat := &types.ChatCompletionRequest{
+		Model:       r.Model,
+		Messages:    make([]types.ChatCompletionMessage, 0, len(r.Contents)+1),
+		MaxTokens:   generationConfig.MaxOutputTokens,
+		Temperature: generationConfig.Temperature,
+		TopP:        generationConfig.TopP,
+		TopK:        generationConfig.TopK,
+		Stream:      r.Stream,
+	}
+
+	if generationConfig.CandidateCount > 1 {
+		chat.N = &generationConfig.CandidateCount
+	}
+
+	if len(generationConfig.StopSequences) > 0 {
+		chat.Stop = generationConfig.StopSequences
+	}
+
+	if generationConfig.ResponseMimeType == "application/json" {
+		chat.ResponseFormat = &types.ChatCompletionResponseFormat{Type: "json_object"}
+		if generationConfig.ResponseSchema != nil {
+			chat.ResponseFormat = &types.ChatCompletionResponseFormat{
+				Type: "json_schema",
+				JsonSchema: &types.FormatJsonSchema{
+					Name:   "response",
+					Schema: normalizeSchema(generationConfig.ResponseSchema),
+				},
+			}
+		}
+	}
+
+	if generationConfig.ThinkingConfig != nil && generationConfig.ThinkingConfig.ThinkingBudget != nil && *generationConfig.ThinkingConfig.ThinkingBudget != 0 {
+		budget := *generationConfig.ThinkingConfig.ThinkingBudget
+		// -1 为动态思考
+		if budget < 0 {
+			budget = 0
+		}
+		chat.Reasoning = &types.ChatReasoning{MaxTokens: budget}
+	}
+
+	if system := systemInstructionToString(r.SystemInstruction); system != "" {
+		chat.Messages = append(chat.Messages, types.ChatCompletionMessage{
+			Role:    types.ChatMessageRoleSystem,
+			Content: system,
+		})
+	}
+
+	// Gemini 的函数调用没有 id，按函数名依次分配，保证 tool 消息能对应上
+	toolCallIds := make(map[string][]string)
+	for _, content := range r.Contents {
+		chat.Messages = append(chat.Messages, convertGeminiContent(&content, toolCallIds)...)
+	}
+
+	for _, tool := range r.Tools {
+		for _, function := range tool.FunctionDeclarations {
+			function.Parameters = normalizeSchema(function.Parameters)
+			chat.Tools = append(chat.Tools, &types.ChatCompletionTool{
+				Type:     "function",
+				Function: function,
+			})
+		}
+	}
+
+	if r.ToolConfig != nil && r.ToolConfig.FunctionCallingConfig != nil && len(chat.Tools) > 0 {
+		chat.ToolChoice = convertFunctionCallingConfig(r.ToolConfig.FunctionCallingConfig)
+	}
+
+	return chat, nil
+}
+
+func systemInstructionToString(system any) string {
+	switch v := system.(type) {
+	case string:
+		return v
+	case map[string]any:
+		parts, _ := v["parts"].([]any)
+		texts := make([]string, 0, len(parts))
+		for _, part := range parts {
+			if partMap, ok := part.(map[string]any); ok {
+				if text, ok := partMap["text"].(string); ok && text != "" {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+
+	return ""
+}
+
+func convertGeminiContent(content *GeminiChatContent, toolCallIds map[string][]string) []types.ChatCompletionMessage {
+	if content.Role == "model" {
+		return []types.ChatCompletionMessage{convertGeminiModelContent(content, toolCallIds)}
+	}
+
+	messages := make([]types.ChatCompletionMessage, 0)
+	parts := make([]types.ChatMessagePart, 0, len(content.Parts))
+
+	for _, part := range content.Parts {
+		if part.FunctionResponse != nil {
+			response, _ := json.Marshal(part.FunctionResponse.Response)
+			messages = append(messages, types.ChatCompletionMessage{
+				Role:       types.ChatMessageRoleTool,
+				Content:    string(response),
+				ToolCallID: popToolCallId(toolCallIds, part.FunctionResponse.Name),
+			})
+			continue
+		}
+
+		if part.Thought {
+			continue
+		}
+
+		if chatPart := convertGeminiPart(&part); chatPart != nil {
+			parts = append(parts, *chatPart)
+		}
+	}
+
+	if len(parts) > 0 {
+		messages = append(messages, types.ChatCompletionMessage{
+			Role:    types.ChatMessageRoleUser,
+			Content: parts,
+		})
+	}
+
+	return messages
+}
+
+func convertGeminiModelContent(content *GeminiChatContent, toolCallIds map[string][]string) types.ChatCompletionMessage {
+	message := types.ChatCompletionMessage{
+		Role: types.ChatMessageRoleAssistant,
+	}
+
+	texts := make([]string, 0, len(content.Parts))
+	for _, part := range content.Parts {
+		if part.FunctionCall != nil {
+			id := "call_" + utils.GetRandomString(24)
+			toolCallIds[part.FunctionCall.Name] = append(toolCallIds[part.FunctionCall.Name], id)
+
+			args, _ := json.Marshal(part.FunctionCall.Args)
+			message.ToolCalls = append(message.ToolCalls, &types.ChatCompletionToolCalls{
+				Id:    id,
+				Type:  "function",
+				Index: len(message.ToolCalls),
+				Function: &types.ChatCompletionToolCallsFunction{
+					Name:      part.FunctionCall.Name,
+					Arguments: string(args),
+				},
+			})
+			continue
+		}
+
+		// 历史消息中的思考内容不回传
+		if part.Thought || part.Text == "" {
+			continue
+		}
+		texts = append(texts, part.Text)
+	}
+
+	message.Content = strings.Join(texts, "")
+
+	return message
+}
+
+func popToolCallId(toolCallIds map[string][]string, name string) string {
+	ids := toolCallIds[name]
+	if len(ids) == 0 {
+		return "call_" + utils.GetRandomString(24)
+	}
+
+	toolCallIds[name] = ids[1:]
+	return ids[0]
+}
+
+func convertGeminiPart(part *GeminiPart) *types.ChatMessagePart {
+	if part.Text != "" {
+		return &types.ChatMessagePart{
+			Type: types.ContentTypeText,
+			Text: part.Text,
+		}
+	}
+
+	if part.InlineData != nil {
+		mimeType := part.InlineData.MimeType
+		dataURL := fmt.Sprintf("data:%s;base64,%s", mimeType, part.InlineData.Data)
+		switch {
+		case strings.HasPrefix(mimeType, "image/"):
+			return &types.ChatMessagePart{
+				Type:     types.ContentTypeImageURL,
+				ImageURL: &types.ChatMessageImageURL{URL: dataURL},
+			}
+		case strings.HasPrefix(mimeType, "audio/"):
+			return &types.ChatMessagePart{
+				Type: "input_audio",
+				InputAudio: &types.InputAudio{
+					Data:   part.InlineData.Data,
+					Format: strings.TrimPrefix(mimeType, "audio/"),
+				},
+			}
+		default:
+			return &types.ChatMessagePart{
+				Type: "file",
+				File: &types.ChatMessageFile{FileData: dataURL},
+			}
+		}
+	}
+
+	if part.FileData != nil && strings.HasPrefix(part.FileData.MimeType, "image/") {
+		return &types.ChatMessagePart{
+			Type:     types.ContentTypeImageURL,
+			ImageURL: &types.ChatMessageImageURL{URL: part.FileData.FileUri},
+		}
+	}
+
+	return nil
+}
+
+func convertFunctionCallingConfig(callingConfig *GeminiFunctionCallingConfig) any {
+	switch strings.ToUpper(callingConfig.Mode) {
+	case "NONE":
+		return types.ToolChoiceTypeNone
+	case "ANY":
+		// 只允许一个函数时，指定调用该函数
+		if names, ok := callingConfig.AllowedFunctionNames.([]any); ok && len(names) == 1 {
+			if name, ok := names[0].(string); ok {
+				return map[string]any{
+					"type": types.ToolChoiceTypeFunction,
+					"function": map[string]any{
+						"name": name,
+					},
+				}
+			}
+		}
+		return types.ToolChoiceTypeRequired
+	default:
+		return types.ToolChoiceTypeAuto
+	}
+}
+
+// normalizeSchema Gemini 的 Schema 类型为大写(OBJECT、STRING)，转换为 JSON Schema 的小写类型
+func normalizeSchema(schema any) any {
+	switch v := schema.(type) {
+	case map[string]any:
+		normalized := make(map[string]any, len(v))
+		for key, value := range v {
+			if key == "type" {
+				if typeName, ok := value.(string); ok {
+					normalized[key] = strings.ToLower(typeName)
+					continue
+				}
+			}
+			normalized[key] = normalizeSchema(value)
+		}
+		return normalized
+	case []any:
+		normalized := make([]any, len(v))
+		for i, value := range v {
+			normalized[i] = normalizeSchema(value)
+		}
+		return normalized
+	}
+
+	return schema
+}
+
+// ConvertFromChatResponse 将 OpenAI Chat 响应转换为 Gemini generateContent 响应
+func ConvertFromChatResponse(response *types.ChatCompletionResponse, usage *types.Usage) *GeminiChatResponse {
+	geminiResponse := &GeminiChatResponse{
+		Candidates:    make([]GeminiChatCandidate, 0, len(response.Choices)),
+		ModelVersion:  response.Model,
+		ResponseId:    response.ID,
+		UsageMetadata: OpenaiUsageToGeminiUsage(usage),
+	}
+
+	for _, choice := range response.Choices {
+		parts := make([]GeminiPart, 0)
+
+		reasoning := choice.Message.ReasoningContent
+		if reasoning == "" {
+			reasoning = choice.Message.Reasoning
+		}
+		if reasoning != "" {
+			parts = append(parts, GeminiPart{Text: reasoning, Thought: true})
+		}
+
+		if text := choice.Message.StringContent(); text != "" {
+			parts = append(parts, GeminiPart{Text: text})
+		}
+
+		choice.Message.FuncToToolCalls()
+		for _, toolCall := range choice.Message.ToolCalls {
+			if toolCall.Function == nil {
+				continue
+			}
+			parts = append(parts, GeminiPart{FunctionCall: ToGeminiFunctionCall(toolCall.Function)})
+		}
+
+		finishReason := FinishReasonOpenAI2Gemini(choice.FinishReason)
+		geminiResponse.Candidates = append(geminiResponse.Candidates, GeminiChatCandidate{
+			Content: GeminiChatContent{
+				Role:  "model",
+				Parts: parts,
+			},
+			FinishReason: &finishReason,
+			Index:        int64(choice.Index),
+		})
+	}
+
+	return geminiResponse
+}
+
+func ToGeminiFunctionCall(function *types.ChatCompletionToolCallsFunction) *GeminiFunctionCall {
+	args := map[string]interface{}{}
+	if function.Arguments != "" {
+		json.Unmarshal([]byte(function.Arguments), &args)
+	}
+
+	return &GeminiFunctionCall{
+		Name: function.Name,
+		Args: args,
+	}
+}
+
+func FinishReasonOpenAI2Gemini(reason string) string {
+	switch reason {
+	case types.FinishReasonLength:
+		return "MAX_TOKENS"
+	case types.FinishReasonContentFilter:
+		return "SAFETY"
+	default:
+		return "STOP"
+	}
+}
+
+func OpenaiUsageToGeminiUsage(usage *types.Usage) *GeminiUsageMetadata {
+	if usage == nil {
+		return nil
+	}
+
+	reasoningTokens := usage.CompletionTokensDetails.ReasoningTokens
+	return &GeminiUsageMetadata{
+		PromptTokenCount:        usage.PromptTokens,
+		CandidatesTokenCount:    usage.CompletionTokens - reasoningTokens,
+		ThoughtsTokenCount:      reasoningTokens,
+		TotalTokenCount:         usage.PromptTokens + usage.CompletionTokens,
+		CachedContentTokenCount: usage.PromptTokensDetails.CachedTokens,
+	}
+}
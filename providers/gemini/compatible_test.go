@@ -0,0 +1,186 @@
+package gemini
+
+import (
+	"encoding/json"
+	"one-api/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToChatCompletionRequest(t *testing.T) {
+	cases := []struct {
+		name     string
+		request  string
+		messages string
+	}{
+		{
+			name:     "文本和 systemInstruction",
+			request:  `{"systemInstruction":{"parts":[{"text":"a"},{"text":"b"}]},"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`,
+			messages: `[{"role":"system","content":"a\nb"},{"role":"user","content":[{"type":"text","text":"hi"}]}]`,
+		},
+		{
+			name:     "inlineData 按类型转换",
+			request:  `{"contents":[{"role":"user","parts":[{"inlineData":{"mimeType":"image/png","data":"AAA"}},{"inlineData":{"mimeType":"audio/wav","data":"BBB"}},{"inlineData":{"mimeType":"application/pdf","data":"CCC"}}]}]}`,
+			messages: `[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,AAA"}},{"type":"input_audio","input_audio":{"data":"BBB","format":"wav"}},{"type":"file","file":{"file_data":"data:application/pdf;base64,CCC"}}]}]`,
+		},
+		{
+			name:     "model 消息中的思考内容不回传",
+			request:  `{"contents":[{"role":"model","parts":[{"text":"hmm","thought":true},{"text":"hello"}]}]}`,
+			messages: `[{"role":"assistant","content":"hello"}]`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var request GeminiChatRequest
+			assert.NoError(t, json.Unmarshal([]byte(c.request), &request))
+
+			chat, err := request.ToChatCompletionRequest()
+			assert.NoError(t, err)
+
+			messages, _ := json.Marshal(chat.Messages)
+			assert.JSONEq(t, c.messages, string(messages))
+		})
+	}
+}
+
+func TestToChatCompletionRequestFunctionCalls(t *testing.T) {
+	body := `{"contents":[` +
+		`{"role":"user","parts":[{"text":"weather?"}]},` +
+		`{"role":"model","parts":[{"functionCall":{"name":"get","args":{"city":"a"}}},{"functionCall":{"name":"get","args":{"city":"b"}}}]},` +
+		`{"role":"user","parts":[{"functionResponse":{"name":"get","response":{"t":1}}},{"functionResponse":{"name":"get","response":{"t":2}}}]}` +
+		`]}`
+
+	var request GeminiChatRequest
+	assert.NoError(t, json.Unmarshal([]byte(body), &request))
+
+	chat, err := request.ToChatCompletionRequest()
+	assert.NoError(t, err)
+	assert.Len(t, chat.Messages, 4)
+
+	toolCalls := chat.Messages[1].ToolCalls
+	assert.Len(t, toolCalls, 2)
+	assert.Equal(t, `{"city":"a"}`, toolCalls[0].Function.Arguments)
+
+	// 同名函数的结果按顺序对应调用 id
+	assert.Equal(t, types.ChatMessageRoleTool, chat.Messages[2].Role)
+	assert.Equal(t, toolCalls[0].Id, chat.Messages[2].ToolCallID)
+	assert.Equal(t, `{"t":1}`, chat.Messages[2].Content)
+	assert.Equal(t, toolCalls[1].Id, chat.Messages[3].ToolCallID)
+}
+
+func TestToChatCompletionRequestConfig(t *testing.T) {
+	body := `{"contents":[{"role":"user","parts":[{"text":"hi"}]}],` +
+		`"generationConfig":{"maxOutputTokens":100,"candidateCount":2,"stopSequences":["x"],"responseMimeType":"application/json",` +
+		`"responseSchema":{"type":"OBJECT","properties":{"a":{"type":"STRING"}}},"thinkingConfig":{"thinkingBudget":-1}},` +
+		`"tools":[{"functionDeclarations":[{"name":"get","parameters":{"type":"OBJECT"}}]}]}`
+
+	var request GeminiChatRequest
+	assert.NoError(t, json.Unmarshal([]byte(body), &request))
+
+	chat, err := request.ToChatCompletionRequest()
+	assert.NoError(t, err)
+
+	assert.Equal(t, 100, chat.MaxTokens)
+	assert.Equal(t, 2, *chat.N)
+	assert.Equal(t, []string{"x"}, chat.Stop)
+	assert.Equal(t, "json_schema", chat.ResponseFormat.Type)
+	assert.Equal(t, map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"a": map[string]any{"type": "string"}},
+	}, chat.ResponseFormat.JsonSchema.Schema)
+	assert.Equal(t, 0, chat.Reasoning.MaxTokens)
+	assert.Len(t, chat.Tools, 1)
+	assert.Equal(t, map[string]any{"type": "object"}, chat.Tools[0].Function.Parameters)
+}
+
+func TestConvertFunctionCallingConfig(t *testing.T) {
+	cases := []struct {
+		mode     string
+		allowed  any
+		expected any
+	}{
+		{"AUTO", nil, types.ToolChoiceTypeAuto},
+		{"NONE", nil, types.ToolChoiceTypeNone},
+		{"any", nil, types.ToolChoiceTypeRequired},
+		{"ANY", []any{"a", "b"}, types.ToolChoiceTypeRequired},
+		{"ANY", []any{"a"}, map[string]any{
+			"type":     types.ToolChoiceTypeFunction,
+			"function": map[string]any{"name": "a"},
+		}},
+	}
+
+	for _, c := range cases {
+		config := &GeminiFunctionCallingConfig{Mode: c.mode, AllowedFunctionNames: c.allowed}
+		assert.Equal(t, c.expected, convertFunctionCallingConfig(config), c.mode)
+	}
+}
+
+func TestConvertFromChatResponse(t *testing.T) {
+	cases := []struct {
+		name         string
+		response     string
+		parts        string
+		finishReason string
+	}{
+		{
+			name:         "文本",
+			response:     `{"id":"1","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}]}`,
+			parts:        `[{"text":"hello"}]`,
+			finishReason: "STOP",
+		},
+		{
+			name:         "推理内容转换为 thought",
+			response:     `{"id":"1","model":"m","choices":[{"index":0,"message":{"role":"assistant","reasoning_content":"think","content":"hello"},"finish_reason":"length"}]}`,
+			parts:        `[{"text":"think","thought":true},{"text":"hello"}]`,
+			finishReason: "MAX_TOKENS",
+		},
+		{
+			name:         "工具调用",
+			response:     `{"id":"1","model":"m","choices":[{"index":0,"message":{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"get","arguments":"{\"a\":1}"}}]},"finish_reason":"tool_calls"}]}`,
+			parts:        `[{"functionCall":{"name":"get","args":{"a":1}}}]`,
+			finishReason: "STOP",
+		},
+		{
+			name:         "内容过滤",
+			response:     `{"id":"1","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":""},"finish_reason":"content_filter"}]}`,
+			parts:        `[]`,
+			finishReason: "SAFETY",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var response types.ChatCompletionResponse
+			assert.NoError(t, json.Unmarshal([]byte(c.response), &response))
+
+			geminiResponse := ConvertFromChatResponse(&response, nil)
+			assert.Len(t, geminiResponse.Candidates, 1)
+
+			candidate := geminiResponse.Candidates[0]
+			parts, _ := json.Marshal(candidate.Content.Parts)
+			assert.JSONEq(t, c.parts, string(parts))
+			assert.Equal(t, c.finishReason, *candidate.FinishReason)
+			assert.Equal(t, "model", candidate.Content.Role)
+		})
+	}
+}
+
+func TestOpenaiUsageToGeminiUsage(t *testing.T) {
+	usage := &types.Usage{
+		PromptTokens:            100,
+		CompletionTokens:        50,
+		PromptTokensDetails:     types.PromptTokensDetails{CachedTokens: 30},
+		CompletionTokensDetails: types.CompletionTokensDetails{ReasoningTokens: 20},
+	}
+
+	assert.Equal(t, &GeminiUsageMetadata{
+		PromptTokenCount:        100,
+		CandidatesTokenCount:    30,
+		ThoughtsTokenCount:      20,
+		TotalTokenCount:         150,
+		CachedContentTokenCount: 30,
+	}, OpenaiUsageToGeminiUsage(usage))
+	assert.Nil(t, OpenaiUsageToGeminiUsage(nil))
+}
@@ -50,7 +50,7 @@ type GeminiToolConfig struct {
 }
 
 type GeminiFunctionCallingConfig struct {
-	Model                string `json:"model,omitempty"`
+	Mode                 string `json:"mode,omitempty"`
 	AllowedFunctionNames any    `json:"allowedFunctionNames,omitempty"`
 }
 type GeminiInlineData struct {
@@ -351,12 +351,12 @@ func (e *GeminiErrorResponse) Error() string {
 }
 
 type GeminiChatResponse struct {
-	Candidates     []GeminiChatCandidate    `json:"candidates"`
-	PromptFeedback GeminiChatPromptFeedback `json:"promptFeedback"`
-	UsageMetadata  *GeminiUsageMetadata     `json:"usageMetadata,omitempty"`
-	ModelVersion   string                   `json:"modelVersion,omitempty"`
-	Model          string                   `json:"model,omitempty"`
-	ResponseId     string                   `json:"responseId,omitempty"`
+	Candidates     []GeminiChatCandidate     `json:"candidates"`
+	PromptFeedback *GeminiChatPromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *GeminiUsageMetadata      `json:"usageMetadata,omitempty"`
+	ModelVersion   string                    `json:"modelVersion,omitempty"`
+	Model          string                    `json:"model,omitempty"`
+	ResponseId     string                    `json:"responseId,omitempty"`
 	GeminiErrorResponse
 }
 
@@ -381,7 +381,7 @@ type GeminiChatCandidate struct {
 	Content               GeminiChatContent        `json:"content"`
 	FinishReason          *string                  `json:"finishReason,omitempty"`
 	Index                 int64                    `json:"index"`
-	SafetyRatings         []GeminiChatSafetyRating `json:"safetyRatings"`
+	SafetyRatings         []GeminiChatSafetyRating `json:"safetyRatings,omitempty"`
 	CitationMetadata      any                      `json:"citationMetadata,omitempty"`
 	TokenCount            int                      `json:"tokenCount,omitempty"`
 	GroundingAttributions []any                    `json:"groundingAttributions,omitempty"`
@@ -395,7 +395,7 @@ type GeminiChatSafetyRating struct {
 }
 
 type GeminiChatPromptFeedback struct {
-	BlockReason   string                   `json:"blockReason"`
+	BlockReason   string                   `json:"blockReason,omitempty"`
 	SafetyRatings []GeminiChatSafetyRating `json:"safetyRatings"`
 }
 
@@ -72,7 +72,14 @@ func (p *MiniMaxProvider) getRequestBody(request *types.SpeechAudioRequest) *Spe
 	}
 
 	// mp3-1-32000-128000
-	if request.ResponseFormat != "" {
+	// pcm 与 OpenAI 一致，为 24kHz 单声道的原始音频
+	if request.ResponseFormat == "pcm" {
+		speechRequest.AudioSetting = &AudioSetting{
+			Format:     "pcm",
+			Channel:    1,
+			SampleRate: 24000,
+		}
+	} else if request.ResponseFormat != "" {
 		formats := strings.Split(request.ResponseFormat, "-")
 		speechRequest.AudioSetting = &AudioSetting{
 			Format: formats[0],
@@ -0,0 +1,99 @@
+package batch
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/relay"
+	"one-api/types"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 支持批处理的接口
+var supportedEndpoints = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	"/v1/embeddings":       true,
+	"/v1/responses":        true,
+}
+
+func StringError(c *gin.Context, httpCode int, code, message string) {
+	c.JSON(httpCode, gin.H{
+		"error": types.OpenAIError{
+			Message: message,
+			Type:    "invalid_request_error",
+			Code:    code,
+		},
+	})
+}
+
+// RelayFiles /v1/files 指定渠道的令牌透传到上游，其余由本地批处理处理
+func RelayFiles(c *gin.Context) {
+	if isPassthrough(c) {
+		relayOnly(c)
+		return
+	}
+
+	fileId, action := parsePath(c.Param("any"))
+	switch {
+	case c.Request.Method == http.MethodPost && fileId == "":
+		UploadFile(c)
+	case c.Request.Method == http.MethodGet && fileId == "":
+		ListFiles(c)
+	case c.Request.Method == http.MethodGet && action == "":
+		RetrieveFile(c, fileId)
+	case c.Request.Method == http.MethodGet && action == "content":
+		RetrieveFileContent(c, fileId)
+	case c.Request.Method == http.MethodDelete && action == "":
+		DeleteFile(c, fileId)
+	default:
+		StringError(c, http.StatusNotFound, "not_found", "Not Found")
+	}
+}
+
+// RelayBatches /v1/batches 指定渠道的令牌透传到上游，其余由本地批处理处理
+func RelayBatches(c *gin.Context) {
+	if isPassthrough(c) {
+		relayOnly(c)
+		return
+	}
+
+	batchId, action := parsePath(c.Param("any"))
+	switch {
+	case c.Request.Method == http.MethodPost && batchId == "":
+		CreateBatch(c)
+	case c.Request.Method == http.MethodGet && batchId == "":
+		ListBatches(c)
+	case c.Request.Method == http.MethodGet && action == "":
+		RetrieveBatch(c, batchId)
+	case c.Request.Method == http.MethodPost && action == "cancel":
+		CancelBatch(c, batchId)
+	default:
+		StringError(c, http.StatusNotFound, "not_found", "Not Found")
+	}
+}
+
+func isPassthrough(c *gin.Context) bool {
+	return !config.BatchSettingsInstance.Enabled || c.GetInt("specific_channel_id") > 0
+}
+
+func relayOnly(c *gin.Context) {
+	if c.GetInt("specific_channel_id") <= 0 {
+		common.AbortWithMessage(c, http.StatusForbidden, "必须指定渠道")
+		return
+	}
+	c.Set("specific_channel_id_ignore", false)
+	relay.RelayOnly(c)
+}
+
+// parsePath 解析 /{id}/{action}
+func parsePath(path string) (id, action string) {
+	parts := strings.SplitN(strings.Trim(path, "/"), "/", 2)
+	id = parts[0]
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+	return
+}
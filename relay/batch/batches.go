@@ -0,0 +1,223 @@
+package batch
+
+import (
+	"encoding/json"
+	"net/http"
+	"one-api/common/utils"
+	"one-api/model"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BatchRequest struct {
+	InputFileID      string            `json:"input_file_id" binding:"required"`
+	Endpoint         string            `json:"endpoint" binding:"required"`
+	CompletionWindow string            `json:"completion_window" binding:"required"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+func batchObject(batch *model.Batch) gin.H {
+	obj := gin.H{
+		"id":                batch.BatchID,
+		"object":            "batch",
+		"endpoint":          batch.Endpoint,
+		"errors":            nil,
+		"input_file_id":     batch.InputFileID,
+		"completion_window": batch.CompletionWindow,
+		"status":            batch.Status,
+		"output_file_id":    nil,
+		"error_file_id":     nil,
+		"created_at":        batch.CreatedAt,
+		"in_progress_at":    nullTime(batch.InProgressAt),
+		"expires_at":        nullTime(batch.ExpiresAt),
+		"finalizing_at":     nullTime(batch.FinalizingAt),
+		"completed_at":      nullTime(batch.CompletedAt),
+		"failed_at":         nullTime(batch.FailedAt),
+		"expired_at":        nullTime(batch.ExpiredAt),
+		"cancelling_at":     nullTime(batch.CancellingAt),
+		"cancelled_at":      nullTime(batch.CancelledAt),
+		"request_counts": gin.H{
+			"total":     batch.Total,
+			"completed": batch.Completed,
+			"failed":    batch.Failed,
+		},
+		"metadata": nil,
+	}
+
+	if batch.OutputFileID != "" {
+		obj["output_file_id"] = batch.OutputFileID
+	}
+	if batch.ErrorFileID != "" {
+		obj["error_file_id"] = batch.ErrorFileID
+	}
+	if len(batch.Errors) > 0 {
+		obj["errors"] = json.RawMessage(batch.Errors)
+	}
+	if len(batch.Metadata) > 0 {
+		obj["metadata"] = json.RawMessage(batch.Metadata)
+	}
+
+	return obj
+}
+
+func nullTime(timestamp int64) any {
+	if timestamp == 0 {
+		return nil
+	}
+	return timestamp
+}
+
+func CreateBatch(c *gin.Context) {
+	var request BatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		StringError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	if !supportedEndpoints[request.Endpoint] {
+		StringError(c, http.StatusBadRequest, "invalid_endpoint", "unsupported endpoint: "+request.Endpoint)
+		return
+	}
+
+	if request.CompletionWindow != "24h" {
+		StringError(c, http.StatusBadRequest, "invalid_completion_window", "completion_window must be 24h")
+		return
+	}
+
+	file := getFile(c, request.InputFileID)
+	if file == nil {
+		return
+	}
+	if file.Purpose != model.BatchFilePurposeBatch {
+		StringError(c, http.StatusBadRequest, "invalid_input_file", "input file purpose must be batch")
+		return
+	}
+
+	batch := &model.Batch{
+		BatchID:          "batch_" + utils.GetRandomString(24),
+		UserId:           c.GetInt("id"),
+		TokenId:          c.GetInt("token_id"),
+		Endpoint:         request.Endpoint,
+		InputFileID:      request.InputFileID,
+		CompletionWindow: request.CompletionWindow,
+		Status:           model.BatchStatusValidating,
+		ExpiresAt:        time.Now().Add(24 * time.Hour).Unix(),
+	}
+
+	if len(request.Metadata) > 0 {
+		batch.Metadata, _ = json.Marshal(request.Metadata)
+	}
+
+	if err := batch.Insert(); err != nil {
+		StringError(c, http.StatusInternalServerError, "create_batch_failed", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, batchObject(batch))
+}
+
+func getBatch(c *gin.Context, batchId string) *model.Batch {
+	batch, err := model.GetBatch(c.GetInt("id"), batchId)
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, "get_batch_failed", err.Error())
+		return nil
+	}
+	if batch == nil {
+		StringError(c, http.StatusNotFound, "batch_not_found", "No such Batch object: "+batchId)
+		return nil
+	}
+
+	return batch
+}
+
+func RetrieveBatch(c *gin.Context, batchId string) {
+	batch := getBatch(c, batchId)
+	if batch == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, batchObject(batch))
+}
+
+func ListBatches(c *gin.Context) {
+	limit := utils.String2Int(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	// 多取一条用于判断是否还有更多
+	batches, err := model.GetUserBatches(c.GetInt("id"), c.Query("after"), limit+1)
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, "get_batches_failed", err.Error())
+		return
+	}
+
+	hasMore := len(batches) > limit
+	if hasMore {
+		batches = batches[:limit]
+	}
+
+	data := make([]gin.H, 0, len(batches))
+	for _, batch := range batches {
+		data = append(data, batchObject(batch))
+	}
+
+	var firstId, lastId any
+	if len(batches) > 0 {
+		firstId = batches[0].BatchID
+		lastId = batches[len(batches)-1].BatchID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"object":   "list",
+		"data":     data,
+		"first_id": firstId,
+		"last_id":  lastId,
+		"has_more": hasMore,
+	})
+}
+
+func CancelBatch(c *gin.Context, batchId string) {
+	batch := getBatch(c, batchId)
+	if batch == nil {
+		return
+	}
+
+	now := utils.GetTimestamp()
+	switch batch.Status {
+	case model.BatchStatusValidating:
+		// 还未开始执行，直接取消
+		if ok, _ := model.UpdateBatchStatus(batchId, model.BatchStatusValidating, map[string]any{
+			"status":        model.BatchStatusCancelled,
+			"cancelling_at": now,
+			"cancelled_at":  now,
+		}); ok {
+			batch.Status = model.BatchStatusCancelled
+			batch.CancellingAt = now
+			batch.CancelledAt = now
+		}
+	case model.BatchStatusInProgress:
+		// 执行中的批处理由执行器检测到后停止
+		if ok, _ := model.UpdateBatchStatus(batchId, model.BatchStatusInProgress, map[string]any{
+			"status":        model.BatchStatusCancelling,
+			"cancelling_at": now,
+		}); ok {
+			batch.Status = model.BatchStatusCancelling
+			batch.CancellingAt = now
+		}
+	default:
+		StringError(c, http.StatusConflict, "invalid_batch_status", "Cannot cancel a batch with status "+batch.Status)
+		return
+	}
+
+	// 状态在此期间发生了变化
+	if batch.Status != model.BatchStatusCancelled && batch.Status != model.BatchStatusCancelling {
+		batch = getBatch(c, batchId)
+		if batch == nil {
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, batchObject(batch))
+}
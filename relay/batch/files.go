@@ -0,0 +1,150 @@
+package batch
+
+import (
+	"io"
+	"net/http"
+	"one-api/common/config"
+	"one-api/common/utils"
+	"one-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func fileObject(file *model.BatchFile) gin.H {
+	return gin.H{
+		"id":         file.FileID,
+		"object":     "file",
+		"bytes":      file.Bytes,
+		"created_at": file.CreatedAt,
+		"filename":   file.Filename,
+		"purpose":    file.Purpose,
+		"status":     "processed",
+	}
+}
+
+func UploadFile(c *gin.Context) {
+	maxSize := int64(config.BatchSettingsInstance.MaxFileSize) << 20
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+(1<<20))
+
+	purpose := c.PostForm("purpose")
+	if purpose != model.BatchFilePurposeBatch {
+		StringError(c, http.StatusBadRequest, "invalid_purpose", "only purpose 'batch' is supported")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		StringError(c, http.StatusBadRequest, "invalid_file", err.Error())
+		return
+	}
+	if fileHeader.Size > maxSize {
+		StringError(c, http.StatusBadRequest, "file_too_large", "file is too large")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		StringError(c, http.StatusBadRequest, "invalid_file", err.Error())
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		StringError(c, http.StatusBadRequest, "invalid_file", err.Error())
+		return
+	}
+
+	batchFile := &model.BatchFile{
+		FileID:   "file-" + utils.GetRandomString(24),
+		UserId:   c.GetInt("id"),
+		Filename: fileHeader.Filename,
+		Purpose:  purpose,
+		Bytes:    len(data),
+	}
+	if err := batchFile.Insert(data); err != nil {
+		StringError(c, http.StatusInternalServerError, "save_file_failed", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, fileObject(batchFile))
+}
+
+func ListFiles(c *gin.Context) {
+	limit := utils.String2Int(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 10000 {
+		limit = 100
+	}
+
+	files, err := model.GetUserBatchFiles(c.GetInt("id"), c.Query("purpose"), limit)
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, "get_files_failed", err.Error())
+		return
+	}
+
+	data := make([]gin.H, 0, len(files))
+	for _, file := range files {
+		data = append(data, fileObject(file))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"object": "list",
+		"data":   data,
+	})
+}
+
+func getFile(c *gin.Context, fileId string) *model.BatchFile {
+	file, err := model.GetBatchFile(c.GetInt("id"), fileId)
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, "get_file_failed", err.Error())
+		return nil
+	}
+	if file == nil {
+		StringError(c, http.StatusNotFound, "file_not_found", "No such File object: "+fileId)
+		return nil
+	}
+
+	return file
+}
+
+func RetrieveFile(c *gin.Context, fileId string) {
+	file := getFile(c, fileId)
+	if file == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, fileObject(file))
+}
+
+func RetrieveFileContent(c *gin.Context, fileId string) {
+	file := getFile(c, fileId)
+	if file == nil {
+		return
+	}
+
+	data, err := model.GetBatchFileContent(file.FileID)
+	if err != nil {
+		StringError(c, http.StatusBadGateway, "download_file_failed", err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, "application/octet-stream", data)
+}
+
+func DeleteFile(c *gin.Context, fileId string) {
+	deleted, err := model.DeleteBatchFile(c.GetInt("id"), fileId)
+	if err != nil {
+		StringError(c, http.StatusInternalServerError, "delete_file_failed", err.Error())
+		return
+	}
+	if !deleted {
+		StringError(c, http.StatusNotFound, "file_not_found", "No such File object: "+fileId)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":      fileId,
+		"object":  "file",
+		"deleted": true,
+	})
+}
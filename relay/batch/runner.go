@@ -0,0 +1,501 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/middleware"
+	"one-api/model"
+	"one-api/relay"
+	"one-api/relay/relay_util"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	batchWatchInterval = 5 * time.Second
+	batchMaxErrors     = 100
+)
+
+var (
+	runningBatches sync.Map
+	recoverOnce    sync.Once
+)
+
+type batchLine struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+type batchLineError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+type batchLineResponse struct {
+	StatusCode int    `json:"status_code"`
+	RequestID  string `json:"request_id"`
+	Body       any    `json:"body"`
+}
+
+type batchOutputLine struct {
+	ID       string             `json:"id"`
+	CustomID string             `json:"custom_id"`
+	Response *batchLineResponse `json:"response"`
+	Error    *batchLineError    `json:"error"`
+}
+
+// ProcessBatches 由定时任务调用，启动等待执行的批处理
+func ProcessBatches() {
+	if !config.BatchSettingsInstance.Enabled {
+		return
+	}
+
+	recoverOnce.Do(recoverInterruptedBatches)
+
+	running := 0
+	runningBatches.Range(func(_, _ any) bool {
+		running++
+		return true
+	})
+
+	slots := config.BatchSettingsInstance.MaxRunning - running
+	if slots <= 0 {
+		return
+	}
+
+	batches, err := model.GetBatchesByStatus(model.BatchStatusValidating, slots+running)
+	if err != nil {
+		logger.SysError("get batches error: " + err.Error())
+		return
+	}
+
+	for _, batch := range batches {
+		if slots <= 0 {
+			break
+		}
+		if _, loaded := runningBatches.LoadOrStore(batch.BatchID, true); loaded {
+			continue
+		}
+		slots--
+
+		batch := batch
+		gopool.Go(func() {
+			defer runningBatches.Delete(batch.BatchID)
+			runBatch(batch)
+		})
+	}
+}
+
+// recoverInterruptedBatches 服务重启时，上次未执行完的批处理无法恢复，标记为失败
+func recoverInterruptedBatches() {
+	now := utils.GetTimestamp()
+	errorsData := batchErrors([]batchLineError{{Code: "batch_interrupted", Message: "batch was interrupted by a server restart"}})
+
+	for _, status := range []string{model.BatchStatusInProgress, model.BatchStatusFinalizing} {
+		batches, err := model.GetBatchesByStatus(status, 1000)
+		if err != nil {
+			continue
+		}
+		for _, batch := range batches {
+			model.UpdateBatchStatus(batch.BatchID, status, map[string]any{
+				"status":    model.BatchStatusFailed,
+				"failed_at": now,
+				"errors":    errorsData,
+			})
+		}
+	}
+
+	batches, err := model.GetBatchesByStatus(model.BatchStatusCancelling, 1000)
+	if err != nil {
+		return
+	}
+	for _, batch := range batches {
+		model.UpdateBatchStatus(batch.BatchID, model.BatchStatusCancelling, map[string]any{
+			"status":       model.BatchStatusCancelled,
+			"cancelled_at": now,
+		})
+	}
+}
+
+func batchErrors(lineErrors []batchLineError) []byte {
+	data, _ := json.Marshal(map[string]any{
+		"object": "list",
+		"data":   lineErrors,
+	})
+	return data
+}
+
+func runBatch(batch *model.Batch) {
+	if batch.ExpiresAt < utils.GetTimestamp() {
+		model.UpdateBatchStatus(batch.BatchID, model.BatchStatusValidating, map[string]any{
+			"status":     model.BatchStatusExpired,
+			"expired_at": utils.GetTimestamp(),
+		})
+		return
+	}
+
+	lines, lineErrors := loadBatchLines(batch)
+	if len(lineErrors) > 0 {
+		model.UpdateBatchStatus(batch.BatchID, model.BatchStatusValidating, map[string]any{
+			"status":    model.BatchStatusFailed,
+			"failed_at": utils.GetTimestamp(),
+			"errors":    batchErrors(lineErrors),
+		})
+		return
+	}
+
+	ok, err := model.UpdateBatchStatus(batch.BatchID, model.BatchStatusValidating, map[string]any{
+		"status":         model.BatchStatusInProgress,
+		"in_progress_at": utils.GetTimestamp(),
+		"total":          len(lines),
+	})
+	if err != nil || !ok {
+		return
+	}
+
+	token, err := model.GetTokenById(batch.TokenId)
+	if err == nil {
+		token, err = model.ValidateUserToken(token.Key)
+	}
+	if err != nil {
+		model.UpdateBatchStatus(batch.BatchID, model.BatchStatusInProgress, map[string]any{
+			"status":    model.BatchStatusFailed,
+			"failed_at": utils.GetTimestamp(),
+			"errors":    batchErrors([]batchLineError{{Code: "token_invalid", Message: err.Error()}}),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Unix(batch.ExpiresAt, 0))
+	defer cancel()
+
+	var completed, failed atomic.Int64
+	var cancelled atomic.Bool
+	watchDone := make(chan struct{})
+	gopool.Go(func() {
+		watchBatch(ctx, batch.BatchID, &completed, &failed, &cancelled, cancel, watchDone)
+	})
+
+	outputs := make([]*batchOutputLine, len(lines))
+	concurrency := config.BatchSettingsInstance.LineConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, line := range lines {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			outputs[i] = stoppedLine(line, cancelled.Load())
+			failed.Add(1)
+			continue
+		}
+
+		wg.Add(1)
+		i, line := i, line
+		gopool.Go(func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			output := executeLine(batch, token, line)
+			if output.Response.StatusCode == http.StatusOK {
+				completed.Add(1)
+			} else {
+				failed.Add(1)
+			}
+			outputs[i] = output
+		})
+	}
+
+	wg.Wait()
+	close(watchDone)
+
+	finalizeBatch(batch, outputs, int(completed.Load()), int(failed.Load()), cancelled.Load())
+}
+
+// watchBatch 定时同步进度，并检测批处理是否被取消
+func watchBatch(ctx context.Context, batchId string, completed, failed *atomic.Int64, cancelled *atomic.Bool, cancel context.CancelFunc, done chan struct{}) {
+	ticker := time.NewTicker(batchWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			status, err := model.GetBatchStatus(batchId)
+			if err != nil {
+				continue
+			}
+			if status == model.BatchStatusCancelling {
+				cancelled.Store(true)
+				cancel()
+			}
+			model.UpdateBatchStatus(batchId, status, map[string]any{
+				"completed": completed.Load(),
+				"failed":    failed.Load(),
+			})
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}
+}
+
+func loadBatchLines(batch *model.Batch) ([]*batchLine, []batchLineError) {
+	file, err := model.GetBatchFile(batch.UserId, batch.InputFileID)
+	if err != nil || file == nil {
+		return nil, []batchLineError{{Code: "file_not_found", Message: "input file not found", Param: "input_file_id"}}
+	}
+
+	data, err := model.GetBatchFileContent(file.FileID)
+	if err != nil {
+		return nil, []batchLineError{{Code: "file_download_failed", Message: err.Error(), Param: "input_file_id"}}
+	}
+
+	lines := make([]*batchLine, 0)
+	lineErrors := make([]batchLineError, 0)
+	customIds := make(map[string]bool)
+	addError := func(lineNumber int, code, message string) {
+		if len(lineErrors) < batchMaxErrors {
+			lineErrors = append(lineErrors, batchLineError{Code: code, Message: message, Line: lineNumber})
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		line := &batchLine{}
+		if err := json.Unmarshal(raw, line); err != nil {
+			addError(lineNumber, "invalid_json_line", "this line is not parseable as valid JSON")
+			continue
+		}
+
+		var body map[string]any
+		switch {
+		case line.CustomID == "":
+			addError(lineNumber, "missing_required_parameter", "custom_id is required")
+		case customIds[line.CustomID]:
+			addError(lineNumber, "duplicate_custom_id", "the custom_id for this request is a duplicate of another request")
+		case line.Method != http.MethodPost:
+			addError(lineNumber, "invalid_method", "method must be POST")
+		case line.URL != batch.Endpoint:
+			addError(lineNumber, "mismatched_endpoint", fmt.Sprintf("url must be %s", batch.Endpoint))
+		case json.Unmarshal(line.Body, &body) != nil || body == nil:
+			addError(lineNumber, "invalid_body", "body must be a JSON object")
+		default:
+			if modelName, _ := body["model"].(string); modelName == "" {
+				addError(lineNumber, "missing_required_parameter", "body.model is required")
+				continue
+			}
+			customIds[line.CustomID] = true
+			lines = append(lines, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, []batchLineError{{Code: "invalid_file", Message: err.Error(), Param: "input_file_id"}}
+	}
+	if len(lineErrors) > 0 {
+		return nil, lineErrors
+	}
+	if len(lines) == 0 {
+		return nil, []batchLineError{{Code: "empty_file", Message: "the input file is empty", Param: "input_file_id"}}
+	}
+	if maxLines := config.BatchSettingsInstance.MaxLines; maxLines > 0 && len(lines) > maxLines {
+		return nil, []batchLineError{{Code: "too_many_requests", Message: fmt.Sprintf("the input file can contain at most %d requests", maxLines), Param: "input_file_id"}}
+	}
+
+	return lines, nil
+}
+
+// executeLine 通过正常的转发流程执行单个请求，请求会按批处理折扣计费
+func executeLine(batch *model.Batch, token *model.Token, line *batchLine) *batchOutputLine {
+	requestId := utils.GetTimeString() + utils.GetRandomString(8)
+	output := &batchOutputLine{
+		ID:       "batch_req_" + utils.GetRandomString(24),
+		CustomID: line.CustomID,
+		Response: &batchLineResponse{RequestID: requestId},
+	}
+
+	// 批处理不支持流式输出
+	var body map[string]any
+	json.Unmarshal(line.Body, &body)
+	body["stream"] = false
+	delete(body, "stream_options")
+	bodyBytes, _ := json.Marshal(body)
+
+	ctx := context.WithValue(context.Background(), logger.RequestIdKey, requestId)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, line.URL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		output.Response.StatusCode = http.StatusInternalServerError
+		output.Response.Body = gin.H{"error": gin.H{"message": err.Error(), "type": "one_hub_error"}}
+		return output
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set(logger.RequestIdKey, requestId)
+	c.Set("requestStartTime", time.Now())
+	c.Set("id", token.UserId)
+	c.Set("token_id", token.Id)
+	c.Set("token_name", token.Name)
+	c.Set("token_group", token.Group)
+	c.Set("token_backup_group", token.BackupGroup)
+	c.Set("token_setting", utils.GetPointer(token.Setting.Data()))
+	c.Set("is_batch", true)
+
+	if err := middleware.NewGroupDistributor(c).SetupGroups(); err == nil {
+		relay.Relay(c)
+		relay_util.ReleaseChannelSlots(c)
+	}
+
+	output.Response.StatusCode = w.Code
+	var responseBody any
+	if err := json.Unmarshal(w.Body.Bytes(), &responseBody); err != nil {
+		responseBody = w.Body.String()
+	}
+	output.Response.Body = responseBody
+
+	return output
+}
+
+func stoppedLine(line *batchLine, cancelled bool) *batchOutputLine {
+	lineError := &batchLineError{Code: "batch_expired", Message: "This request could not be executed before the completion window expired."}
+	if cancelled {
+		lineError = &batchLineError{Code: "batch_cancelled", Message: "This request was not executed because the batch was cancelled."}
+	}
+
+	return &batchOutputLine{
+		ID:       "batch_req_" + utils.GetRandomString(24),
+		CustomID: line.CustomID,
+		Error:    lineError,
+	}
+}
+
+// finalizeBatch 写入输出文件和错误文件，并更新最终状态
+func finalizeBatch(batch *model.Batch, outputs []*batchOutputLine, completed, failed int, cancelled bool) {
+	status, err := model.GetBatchStatus(batch.BatchID)
+	if err != nil {
+		logger.SysError("get batch status error: " + err.Error())
+		return
+	}
+
+	finalStatus := model.BatchStatusCompleted
+	timeField := "completed_at"
+	switch {
+	case cancelled || status == model.BatchStatusCancelling:
+		finalStatus = model.BatchStatusCancelled
+		timeField = "cancelled_at"
+	case batch.ExpiresAt < utils.GetTimestamp():
+		finalStatus = model.BatchStatusExpired
+		timeField = "expired_at"
+	default:
+		if ok, _ := model.UpdateBatchStatus(batch.BatchID, status, map[string]any{
+			"status":        model.BatchStatusFinalizing,
+			"finalizing_at": utils.GetTimestamp(),
+		}); ok {
+			status = model.BatchStatusFinalizing
+		}
+	}
+
+	var outputData, errorData bytes.Buffer
+	for _, output := range outputs {
+		if output == nil {
+			continue
+		}
+		data, _ := json.Marshal(output)
+		if output.Error == nil && output.Response.StatusCode == http.StatusOK {
+			outputData.Write(data)
+			outputData.WriteByte('\n')
+		} else {
+			errorData.Write(data)
+			errorData.WriteByte('\n')
+		}
+	}
+
+	updates := map[string]any{
+		"status":    finalStatus,
+		timeField:   utils.GetTimestamp(),
+		"completed": completed,
+		"failed":    failed,
+	}
+
+	var saveErrors []batchLineError
+	if outputData.Len() > 0 {
+		fileId, err := saveOutputFile(batch, outputData.Bytes(), "batch_output")
+		if err != nil {
+			saveErrors = append(saveErrors, batchLineError{Code: "output_file_failed", Message: err.Error()})
+		} else {
+			updates["output_file_id"] = fileId
+		}
+	}
+	if errorData.Len() > 0 {
+		fileId, err := saveOutputFile(batch, errorData.Bytes(), "batch_error")
+		if err != nil {
+			saveErrors = append(saveErrors, batchLineError{Code: "error_file_failed", Message: err.Error()})
+		} else {
+			updates["error_file_id"] = fileId
+		}
+	}
+
+	// 结果无法保存时批处理视为失败
+	if len(saveErrors) > 0 {
+		updates["status"] = model.BatchStatusFailed
+		delete(updates, timeField)
+		updates["failed_at"] = utils.GetTimestamp()
+		updates["errors"] = batchErrors(saveErrors)
+	}
+
+	if _, err := model.UpdateBatchStatus(batch.BatchID, status, updates); err != nil {
+		logger.SysError("update batch status error: " + err.Error())
+	}
+}
+
+func saveOutputFile(batch *model.Batch, data []byte, name string) (string, error) {
+	fileId := "file-" + utils.GetRandomString(24)
+	file := &model.BatchFile{
+		FileID:   fileId,
+		UserId:   batch.UserId,
+		Filename: fmt.Sprintf("%s_%s.jsonl", batch.BatchID, name),
+		Purpose:  model.BatchFilePurposeBatchOutput,
+		Bytes:    len(data),
+	}
+	if err := file.Insert(data); err != nil {
+		return "", errors.New("save " + name + " file failed: " + err.Error())
+	}
+
+	return fileId, nil
+}
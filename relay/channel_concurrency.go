@@ -0,0 +1,128 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"one-api/common/config"
+	"one-api/common/limit"
+	"one-api/common/utils"
+	"one-api/metrics"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 排队时没有被唤醒的最长等待时间，其他节点释放名额时不会唤醒本节点的队列
+const channelQueuePollInterval = time.Second
+
+var (
+	errChannelQueueFull    = errors.New("当前模型的渠道并发已满，排队人数过多，请稍后再试")
+	errChannelQueueTimeout = errors.New("当前模型的渠道并发已满，排队超时，请稍后再试")
+	errChannelQueueEvicted = errors.New("当前模型的渠道并发已满，请求优先级较低已被拒绝，请稍后再试")
+)
+
+// getQoSClass 获取请求优先级，批处理请求固定为 batch，其余按 令牌 > 用户分组 > 全局设置
+func getQoSClass(c *gin.Context) string {
+	if c.GetBool("is_batch") {
+		return config.QoSClassBatch
+	}
+
+	if setting, ok := utils.GetGinValue[*model.TokenSetting](c, "token_setting"); ok && setting != nil && config.IsValidQoSClass(setting.QoS.Class) {
+		return setting.QoS.Class
+	}
+
+	if class := model.GlobalUserGroupRatio.GetQoSClass(c.GetString("token_group")); config.IsValidQoSClass(class) {
+		return class
+	}
+
+	if config.IsValidQoSClass(config.QoSSettingsInstance.DefaultClass) {
+		return config.QoSSettingsInstance.DefaultClass
+	}
+	return config.QoSClassInteractive
+}
+
+// shedRequest 拒绝排队的请求，返回 Retry-After 提示客户端稍后重试
+func shedRequest(c *gin.Context, class string, reason string, err error) error {
+	if retryAfter := config.QoSSettingsInstance.RetryAfter; retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+	}
+	metrics.RecordChannelQueueShed(class, reason)
+	return err
+}
+
+// fetchChannelWithConcurrency 选择渠道并占用并发名额
+// 可用渠道的并发或 TPM 都已用满时按优先级和到达顺序排队，有名额释放或超时后返回
+// 排队过长时低优先级的请求直接被拒绝，队列已满时高优先级的请求会挤出排在最后的低优先级请求
+func fetchChannelWithConcurrency(c *gin.Context, modelName string) (*model.Channel, string, error) {
+	settings := config.ChannelConcurrencySettingsInstance
+	queueKey := c.GetString("token_group") + ":" + modelName
+	class := getQoSClass(c)
+
+	var ticket *limit.QueueTicket
+	defer func() {
+		if ticket != nil {
+			ticket.Leave()
+		}
+	}()
+
+	enter := func() error {
+		if settings.QueueSize <= 0 {
+			return shedRequest(c, class, "full", errChannelQueueFull)
+		}
+		if depth, ok := config.QoSSettingsInstance.GetShedDepth(class); ok && relay_util.ChannelQueue.Len(queueKey) >= depth {
+			return shedRequest(c, class, "shed", errChannelQueueFull)
+		}
+
+		var ok bool
+		ticket, ok = relay_util.ChannelQueue.Enter(queueKey, config.QoSPriority(class), settings.QueueSize)
+		if !ok {
+			return shedRequest(c, class, "full", errChannelQueueFull)
+		}
+		return nil
+	}
+
+	// 已有请求在排队时先排队，同优先级先到先得
+	if relay_util.ChannelQueue.Len(queueKey) > 0 {
+		if err := enter(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(settings.QueueTimeout)*time.Second)
+	defer cancel()
+
+	for {
+		if ticket == nil || ticket.IsHead() {
+			saturated := false
+			channel, targetModel, err := fetchChannelByModel(c, modelName, func() {
+				saturated = true
+			})
+			if err == nil {
+				if relay_util.AcquireChannelSlot(c, channel) {
+					return channel, targetModel, nil
+				}
+				// 名额被其他请求抢先占用
+				saturated = true
+			}
+			if !saturated {
+				return nil, "", err
+			}
+
+			if ticket == nil {
+				if err := enter(); err != nil {
+					return nil, "", err
+				}
+			}
+		}
+
+		if err := ticket.Wait(ctx, channelQueuePollInterval); err != nil {
+			if errors.Is(err, limit.ErrQueueEvicted) {
+				return nil, "", shedRequest(c, class, "evicted", errChannelQueueEvicted)
+			}
+			return nil, "", shedRequest(c, class, "timeout", errChannelQueueTimeout)
+		}
+	}
+}
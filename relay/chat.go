@@ -10,6 +10,7 @@ import (
 	"one-api/common/config"
 	"one-api/common/requester"
 	"one-api/common/utils"
+	"one-api/model"
 	providersBase "one-api/providers/base"
 	"one-api/safty"
 	"one-api/types"
@@ -46,6 +47,9 @@ func (r *relayChat) setRequest() error {
 		r.c.Set("skip_only_chat", true)
 	}
 
+	// 用于跳过探测失败、被禁用了相应能力的渠道
+	r.c.Set("request_capabilities", requestCapabilities(&r.chatRequest))
+
 	if !r.chatRequest.Stream {
 		r.chatRequest.StreamOptions = nil
 	}
@@ -62,6 +66,31 @@ func (r *relayChat) setRequest() error {
 	return nil
 }
 
+func requestCapabilities(request *types.ChatCompletionRequest) []string {
+	capabilities := make([]string, 0)
+	if len(request.Tools) > 0 || len(request.Functions) > 0 {
+		capabilities = append(capabilities, model.ChannelCapabilityTool)
+	}
+
+	if request.ResponseFormat != nil && (request.ResponseFormat.Type == "json_schema" || request.ResponseFormat.Type == "json_object") {
+		capabilities = append(capabilities, model.ChannelCapabilityJSON)
+	}
+
+	for _, message := range request.Messages {
+		if _, ok := message.Content.(string); ok {
+			continue
+		}
+		for _, part := range message.ParseContent() {
+			if part.Type == types.ContentTypeImageURL {
+				capabilities = append(capabilities, model.ChannelCapabilityVision)
+				return capabilities
+			}
+		}
+	}
+
+	return capabilities
+}
+
 func (r *relayChat) getRequest() interface{} {
 	return &r.chatRequest
 }
@@ -118,12 +147,17 @@ func (r *relayChat) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
 	}
 
 	if r.chatRequest.Stream {
+		sendStartTime := time.Now()
 		var response requester.StreamReaderInterface[string]
 		response, err = chatProvider.CreateChatCompletionStream(&r.chatRequest)
 		if err != nil {
 			return
 		}
 
+		if hedgeDelay := getHedgeDelay(r.c); hedgeDelay > 0 {
+			response = r.hedgeStream(response, hedgeDelay, sendStartTime)
+		}
+
 		if r.heartbeat != nil {
 			r.heartbeat.Stop()
 		}
@@ -146,6 +180,7 @@ func (r *relayChat) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
 			r.heartbeat.Stop()
 		}
 
+		guardChatResponse(r.c, response)
 		err = responseJsonClient(r.c, response)
 
 	}
@@ -211,7 +246,9 @@ func (r *relayChat) compatibleSend(resProvider providersBase.ResponsesInterface)
 		if r.heartbeat != nil {
 			r.heartbeat.Stop()
 		}
-		err = responseJsonClient(r.c, response.ToChat())
+		chatResponse := response.ToChat()
+		guardChatResponse(r.c, chatResponse)
+		err = responseJsonClient(r.c, chatResponse)
 	}
 
 	if err != nil {
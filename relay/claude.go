@@ -6,7 +6,9 @@ import (
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/requester"
+	providersBase "one-api/providers/base"
 	"one-api/providers/claude"
+	"one-api/relay/relay_util"
 	"one-api/safty"
 	"one-api/types"
 	"strings"
@@ -14,15 +16,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-var AllowChannelType = []int{config.ChannelTypeAnthropic, config.ChannelTypeVertexAI, config.ChannelTypeBedrock}
-
 type relayClaudeOnly struct {
 	relayBase
 	claudeRequest *claude.ClaudeRequest
 }
 
 func NewRelayClaudeOnly(c *gin.Context) *relayClaudeOnly {
-	c.Set("allow_channel_type", AllowChannelType)
 	relay := &relayClaudeOnly{
 		relayBase: relayBase{
 			allowHeartbeat: true,
@@ -56,13 +55,6 @@ func (r *relayClaudeOnly) getPromptTokens() (int, error) {
 }
 
 func (r *relayClaudeOnly) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
-	chatProvider, ok := r.provider.(claude.ClaudeChatInterface)
-	if !ok {
-		err = common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
-		done = true
-		return
-	}
-
 	r.claudeRequest.Model = r.modelName
 	// 内容审查
 	if config.EnableSafe {
@@ -78,6 +70,19 @@ func (r *relayClaudeOnly) send() (err *types.OpenAIErrorWithStatusCode, done boo
 		}
 	}
 
+	chatProvider, ok := r.provider.(claude.ClaudeChatInterface)
+	if !ok {
+		// 非 Claude 渠道，转换为 Chat 请求
+		compatibleProvider, ok := r.provider.(providersBase.ChatInterface)
+		if !ok {
+			err = common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+			done = true
+			return
+		}
+
+		return r.compatibleSend(compatibleProvider)
+	}
+
 	if r.claudeRequest.Stream {
 		var response requester.StreamReaderInterface[string]
 		response, err = chatProvider.CreateClaudeChatStream(r.claudeRequest)
@@ -118,6 +123,45 @@ func (r *relayClaudeOnly) send() (err *types.OpenAIErrorWithStatusCode, done boo
 	return
 }
 
+func (r *relayClaudeOnly) compatibleSend(chatProvider providersBase.ChatInterface) (errWithCode *types.OpenAIErrorWithStatusCode, done bool) {
+	chatReq, err := r.claudeRequest.ToChatCompletionRequest()
+	if err != nil {
+		return common.ErrorWrapperLocal(err, "invalid_claude_request", http.StatusBadRequest), true
+	}
+
+	if r.claudeRequest.Stream {
+		var response requester.StreamReaderInterface[string]
+		response, errWithCode = chatProvider.CreateChatCompletionStream(chatReq)
+		if errWithCode != nil {
+			return
+		}
+
+		if r.heartbeat != nil {
+			r.heartbeat.Stop()
+		}
+
+		converter := relay_util.NewClaudeStreamConverter(r.c, r.modelName, r.provider.GetUsage())
+		firstResponseTime := responseConvertStreamClient(r.c, response, converter)
+		r.SetFirstResponseTime(firstResponseTime)
+	} else {
+		var response *types.ChatCompletionResponse
+		response, errWithCode = chatProvider.CreateChatCompletion(chatReq)
+		if errWithCode != nil {
+			return
+		}
+
+		if r.heartbeat != nil {
+			r.heartbeat.Stop()
+		}
+
+		guardChatResponse(r.c, response)
+		claudeResponse := claude.ConvertFromChatResponse(response, r.provider.GetUsage())
+		responseJsonClient(r.c, claudeResponse)
+	}
+
+	return
+}
+
 func (r *relayClaudeOnly) GetError(err *types.OpenAIErrorWithStatusCode) (int, any) {
 	newErr := FilterOpenAIErr(r.c, err)
 
@@ -17,12 +17,15 @@ import (
 	"one-api/model"
 	"one-api/providers"
 	providersBase "one-api/providers/base"
+	"one-api/tracing"
 	"one-api/types"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func Path2Relay(c *gin.Context, path string) RelayBaseInterface {
@@ -100,6 +103,20 @@ func checkLimitModel(c *gin.Context, modelName string) (error error) {
 }
 
 func GetProvider(c *gin.Context, modelName string) (provider providersBase.ProviderInterface, newModelName string, fail error) {
+	_, span := tracing.Start(c.Request.Context(), "relay.select_channel",
+		attribute.String("model", modelName),
+		attribute.Int("token_id", c.GetInt("token_id")),
+	)
+	defer func() {
+		if provider != nil {
+			span.SetAttributes(
+				attribute.Int("channel_id", c.GetInt("channel_id")),
+				attribute.String("target_model", newModelName),
+			)
+		}
+		tracing.End(span, fail)
+	}()
+
 	// 检查模型限制
 	if modelName != "" {
 		if err := checkLimitModel(c, modelName); err != nil {
@@ -107,12 +124,13 @@ func GetProvider(c *gin.Context, modelName string) (provider providersBase.Provi
 			return nil, "", err
 		}
 	}
-	channel, fail := fetchChannel(c, modelName)
+	channel, targetModel, fail := fetchChannel(c, modelName)
 	if fail != nil {
 		return
 	}
 	c.Set("channel_id", channel.Id)
 	c.Set("channel_type", channel.Type)
+	c.Set("channel_tpm", channel.TPM)
 
 	provider = providers.GetProvider(channel, c)
 	if provider == nil {
@@ -122,7 +140,7 @@ func GetProvider(c *gin.Context, modelName string) (provider providersBase.Provi
 	provider.SetOriginalModel(modelName)
 	c.Set("original_model", modelName)
 
-	newModelName, fail = provider.ModelMappingHandler(modelName)
+	newModelName, fail = provider.ModelMappingHandler(targetModel)
 	if fail != nil {
 		return
 	}
@@ -134,20 +152,41 @@ func GetProvider(c *gin.Context, modelName string) (provider providersBase.Provi
 		BillingOriginalModel = true
 	}
 
+	// 虚拟模型单独定价时按虚拟模型计费，否则按实际模型计费
+	if targetModel != modelName && model.PricingInstance.HasPrice(modelName) {
+		BillingOriginalModel = true
+	}
+
 	c.Set("new_model", newModelName)
 	c.Set("billing_original_model", BillingOriginalModel)
 
 	return
 }
 
-func fetchChannel(c *gin.Context, modelName string) (channel *model.Channel, fail error) {
+// lookupProvider 查看请求将使用的渠道，只用于在解析请求前读取渠道配置
+// 不会真正向渠道发送请求，因此不占用并发名额和熔断器的探测名额
+func lookupProvider(c *gin.Context, modelName string) (providersBase.ProviderInterface, error) {
+	c.Set("channel_lookup", true)
+	defer c.Set("channel_lookup", false)
+
+	provider, _, err := GetProvider(c, modelName)
+	return provider, err
+}
+
+// fetchChannel 选择渠道，返回渠道和实际请求的模型，虚拟模型会解析为其中一个目标模型
+func fetchChannel(c *gin.Context, modelName string) (channel *model.Channel, targetModel string, fail error) {
 	channelId := c.GetInt("specific_channel_id")
 	ignore := c.GetBool("specific_channel_id_ignore")
 	if channelId > 0 && !ignore {
-		return fetchChannelById(channelId)
+		channel, fail = fetchChannelById(channelId)
+		return channel, modelName, fail
+	}
+
+	if c.GetBool("channel_lookup") {
+		return fetchChannelByModel(c, modelName, nil)
 	}
 
-	return fetchChannelByModel(c, modelName)
+	return fetchChannelWithConcurrency(c, modelName)
 }
 
 func fetchChannelById(channelId int) (*model.Channel, error) {
@@ -234,11 +273,26 @@ func (gm *GroupManager) createGroupError(group string, modelName string, channel
 	return fmt.Errorf("当前分组 %s 下对于模型 %s 无可用渠道", group, modelName)
 }
 
-func fetchChannelByModel(c *gin.Context, modelName string) (*model.Channel, error) {
+// fetchChannelByModel 按模型选择渠道，onSaturated 在有渠道因并发或 TPM 用满被跳过时调用
+// 虚拟模型按顺序尝试各个实际模型，返回选中的渠道和实际模型
+func fetchChannelByModel(c *gin.Context, modelName string, onSaturated func()) (*model.Channel, string, error) {
+	var lastErr error
+	for _, targetModel := range model.GlobalVirtualModels.Resolve(modelName) {
+		channel, err := fetchChannelByTargetModel(c, targetModel, onSaturated)
+		if err == nil {
+			return channel, targetModel, nil
+		}
+		lastErr = err
+	}
+
+	return nil, "", lastErr
+}
+
+func fetchChannelByTargetModel(c *gin.Context, modelName string, onSaturated func()) (*model.Channel, error) {
 	skipOnlyChat := c.GetBool("skip_only_chat")
 	isStream := c.GetBool("is_stream")
 
-	var filters []model.ChannelsFilterFunc
+	filters := []model.ChannelsFilterFunc{model.FilterChannelTPM(onSaturated), model.FilterChannelConcurrency(onSaturated)}
 	if skipOnlyChat {
 		filters = append(filters, model.FilterOnlyChat())
 	}
@@ -258,10 +312,22 @@ func fetchChannelByModel(c *gin.Context, modelName string) (*model.Channel, erro
 		filters = append(filters, model.FilterDisabledStream(modelName))
 	}
 
+	if capabilities, ok := utils.GetGinValue[[]string](c, "request_capabilities"); ok && len(capabilities) > 0 {
+		filters = append(filters, model.FilterDisabledCapabilities(modelName, capabilities))
+	}
+
+	if minScore := config.ChannelProbeSettingsInstance.FingerprintMinScore; minScore > 0 {
+		filters = append(filters, model.FilterChannelFingerprint(modelName, minScore))
+	}
+
 	// 使用统一的分组管理器
 	groupManager := NewGroupManager(c)
+	next := model.ChannelGroup.Next
+	if c.GetBool("channel_lookup") {
+		next = model.ChannelGroup.Peek
+	}
 	return groupManager.TryWithGroups(modelName, filters, func(group string) (*model.Channel, error) {
-		return model.ChannelGroup.Next(group, modelName, filters...)
+		return next(group, modelName, filters...)
 	})
 
 }
@@ -298,6 +364,9 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 
 	var isFirstResponse bool
 
+	// 输出内容审查，未开启时为 nil
+	guard := newOutputGuard(c, true)
+
 	// 在新的goroutine中处理stream数据
 	go func() {
 		defer close(done)
@@ -308,6 +377,11 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 				if !ok {
 					return
 				}
+
+				terminate := false
+				if guard != nil {
+					data, terminate = guard.inspectChunk(data)
+				}
 				streamData := "data: " + data + "\n\n"
 
 				if !isFirstResponse {
@@ -325,6 +399,12 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 					c.Writer.Flush()
 				}
 
+				// 输出内容违规，不再读取上游数据，直接结束
+				if terminate {
+					writeStreamEnd(c, endHandler)
+					return
+				}
+
 			case err := <-errChan:
 				if !errors.Is(err, io.EOF) {
 					// 处理错误情况
@@ -341,30 +421,24 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 					finalErr = common.StringErrorWrapper(err.Error(), "stream_error", 900)
 					logger.LogError(c.Request.Context(), "Stream err:"+err.Error())
 				} else {
-					// 正常结束，处理endHandler
-					if finalErr == nil && endHandler != nil {
-						streamData := endHandler()
-						if streamData != "" {
+					// 输出审查暂缓的内容
+					if guard != nil {
+						if data := guard.flush(); data != "" {
 							select {
 							case <-c.Request.Context().Done():
-								// 客户端已断开，不执行任何操作，直接跳过
 							default:
-								// 客户端正常，发送数据
-								c.Writer.Write([]byte("data: " + streamData + "\n\n"))
+								c.Writer.Write([]byte("data: " + data + "\n\n"))
 								c.Writer.Flush()
 							}
 						}
 					}
 
-					// 发送结束标记
-					streamData := "data: [DONE]\n\n"
-					select {
-					case <-c.Request.Context().Done():
-						// 客户端已断开，不执行任何操作，直接跳过
-					default:
-						c.Writer.Write([]byte(streamData))
-						c.Writer.Flush()
+					// 正常结束，处理endHandler
+					handler := endHandler
+					if finalErr != nil {
+						handler = nil
 					}
+					writeStreamEnd(c, handler)
 				}
 				return
 			}
@@ -376,6 +450,29 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 	return firstResponseTime, nil
 }
 
+// writeStreamEnd 发送 endHandler 返回的数据和结束标记
+func writeStreamEnd(c *gin.Context, endHandler StreamEndHandler) {
+	streamData := ""
+	if endHandler != nil {
+		streamData = endHandler()
+	}
+
+	select {
+	case <-c.Request.Context().Done():
+		// 客户端已断开，不执行任何操作，直接跳过
+		return
+	default:
+	}
+
+	if streamData != "" {
+		c.Writer.Write([]byte("data: " + streamData + "\n\n"))
+	}
+
+	// 发送结束标记
+	c.Writer.Write([]byte("data: [DONE]\n\n"))
+	c.Writer.Flush()
+}
+
 func responseGeneralStreamClient(c *gin.Context, stream requester.StreamReaderInterface[string], endHandler StreamEndHandler) (firstResponseTime time.Time) {
 	requester.SetEventStreamHeaders(c)
 	dataChan, errChan := stream.Recv()
@@ -451,6 +548,94 @@ func responseGeneralStreamClient(c *gin.Context, stream requester.StreamReaderIn
 	return firstResponseTime
 }
 
+// StreamConverter 将 Chat 流转换为其他格式(Claude、Gemini)的流
+type StreamConverter interface {
+	ProcessStreamData(jsonStr string)
+	ProcessError(jsonStr string)
+}
+
+// responseConvertStreamClient 将 Chat 流经过 converter 转换后发送给客户端，结束时传入 [DONE]
+func responseConvertStreamClient(c *gin.Context, stream requester.StreamReaderInterface[string], converter StreamConverter) (firstResponseTime time.Time) {
+	requester.SetEventStreamHeaders(c)
+	dataChan, errChan := stream.Recv()
+
+	// 创建一个done channel用于通知处理完成
+	done := make(chan struct{})
+
+	defer stream.Close()
+	var isFirstResponse bool
+
+	// 输出内容审查，在转换格式之前按 Chat 分块检查
+	guard := newOutputGuard(c, true)
+
+	// 在新的goroutine中处理stream数据
+	gopool.Go(func() {
+		defer close(done)
+
+		for {
+			select {
+			case data, ok := <-dataChan:
+				if !ok {
+					return
+				}
+
+				terminate := false
+				if guard != nil {
+					data, terminate = guard.inspectChunk(data)
+				}
+
+				if !isFirstResponse {
+					firstResponseTime = time.Now()
+					isFirstResponse = true
+				}
+
+				// 尝试写入数据，如果客户端断开也继续处理
+				select {
+				case <-c.Request.Context().Done():
+					// 客户端已断开，不执行任何操作，直接跳过
+				default:
+					// 客户端正常，发送数据
+					converter.ProcessStreamData(data)
+				}
+
+				// 输出内容违规，不再读取上游数据，直接结束
+				if terminate {
+					converter.ProcessStreamData("[DONE]")
+					return
+				}
+
+			case err := <-errChan:
+				if !errors.Is(err, io.EOF) {
+					// 处理错误情况
+					select {
+					case <-c.Request.Context().Done():
+						// 客户端已断开，不执行任何操作，直接跳过
+					default:
+						// 客户端正常，发送错误信息
+						converter.ProcessError(err.Error())
+					}
+
+					logger.LogError(c.Request.Context(), "Stream err:"+err.Error())
+				} else {
+					// 输出审查暂缓的内容
+					if guard != nil {
+						if data := guard.flush(); data != "" {
+							converter.ProcessStreamData(data)
+						}
+					}
+					// 要发送最后的完成状态
+					converter.ProcessStreamData("[DONE]")
+				}
+				return
+			}
+		}
+	})
+
+	// 等待处理完成
+	<-done
+	return firstResponseTime
+}
+
 func responseMultipart(c *gin.Context, resp *http.Response) *types.OpenAIErrorWithStatusCode {
 	defer resp.Body.Close()
 
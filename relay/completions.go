@@ -101,6 +101,7 @@ func (r *relayCompletions) send() (err *types.OpenAIErrorWithStatusCode, done bo
 		if err != nil {
 			return
 		}
+		guardCompletionResponse(r.c, response)
 		err = responseJsonClient(r.c, response)
 	}
 
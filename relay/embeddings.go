@@ -37,6 +37,10 @@ func (r *relayEmbeddings) setRequest() error {
 	return nil
 }
 
+func (r *relayEmbeddings) getRequest() interface{} {
+	return &r.request
+}
+
 func (r *relayEmbeddings) getPromptTokens() (int, error) {
 	return common.CountTokenInput(r.request.Input, r.modelName), nil
 }
@@ -7,7 +7,9 @@ import (
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/requester"
+	providersBase "one-api/providers/base"
 	"one-api/providers/gemini"
+	"one-api/relay/relay_util"
 	"one-api/safty"
 	"one-api/types"
 	"strings"
@@ -15,15 +17,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-var AllowGeminiChannelType = []int{config.ChannelTypeGemini, config.ChannelTypeVertexAI}
-
 type relayGeminiOnly struct {
 	relayBase
 	geminiRequest *gemini.GeminiChatRequest
 }
 
 func NewRelayGeminiOnly(c *gin.Context) *relayGeminiOnly {
-	c.Set("allow_channel_type", AllowGeminiChannelType)
 	relay := &relayGeminiOnly{
 		relayBase: relayBase{
 			allowHeartbeat: true,
@@ -76,11 +75,6 @@ func (r *relayGeminiOnly) getPromptTokens() (int, error) {
 }
 
 func (r *relayGeminiOnly) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
-	chatProvider, ok := r.provider.(gemini.GeminiChatInterface)
-	if !ok {
-		return nil, false
-	}
-
 	// 内容审查
 	if config.EnableSafe {
 		for _, message := range r.geminiRequest.Contents {
@@ -97,6 +91,19 @@ func (r *relayGeminiOnly) send() (err *types.OpenAIErrorWithStatusCode, done boo
 
 	r.geminiRequest.Model = r.modelName
 
+	chatProvider, ok := r.provider.(gemini.GeminiChatInterface)
+	if !ok {
+		// 非 Gemini 渠道，转换为 Chat 请求
+		compatibleProvider, ok := r.provider.(providersBase.ChatInterface)
+		if !ok {
+			err = common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+			done = true
+			return
+		}
+
+		return r.compatibleSend(compatibleProvider)
+	}
+
 	if r.geminiRequest.Stream {
 		var response requester.StreamReaderInterface[string]
 		response, err = chatProvider.CreateGeminiChatStream(r.geminiRequest)
@@ -134,6 +141,45 @@ func (r *relayGeminiOnly) send() (err *types.OpenAIErrorWithStatusCode, done boo
 	return
 }
 
+func (r *relayGeminiOnly) compatibleSend(chatProvider providersBase.ChatInterface) (errWithCode *types.OpenAIErrorWithStatusCode, done bool) {
+	chatReq, err := r.geminiRequest.ToChatCompletionRequest()
+	if err != nil {
+		return common.ErrorWrapperLocal(err, "invalid_gemini_request", http.StatusBadRequest), true
+	}
+
+	if r.geminiRequest.Stream {
+		var response requester.StreamReaderInterface[string]
+		response, errWithCode = chatProvider.CreateChatCompletionStream(chatReq)
+		if errWithCode != nil {
+			return
+		}
+
+		if r.heartbeat != nil {
+			r.heartbeat.Stop()
+		}
+
+		converter := relay_util.NewGeminiStreamConverter(r.c, r.modelName, r.provider.GetUsage())
+		firstResponseTime := responseConvertStreamClient(r.c, response, converter)
+		r.SetFirstResponseTime(firstResponseTime)
+	} else {
+		var response *types.ChatCompletionResponse
+		response, errWithCode = chatProvider.CreateChatCompletion(chatReq)
+		if errWithCode != nil {
+			return
+		}
+
+		if r.heartbeat != nil {
+			r.heartbeat.Stop()
+		}
+
+		guardChatResponse(r.c, response)
+		geminiResponse := gemini.ConvertFromChatResponse(response, r.provider.GetUsage())
+		responseJsonClient(r.c, geminiResponse)
+	}
+
+	return
+}
+
 func (r *relayGeminiOnly) GetError(err *types.OpenAIErrorWithStatusCode) (int, any) {
 	newErr := FilterOpenAIErr(r.c, err)
 
@@ -0,0 +1,376 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/common/requester"
+	"one-api/common/utils"
+	"one-api/model"
+	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 丢弃落败流剩余数据的最长等待时间
+const hedgeDrainTimeout = 30 * time.Second
+
+// getHedgeDelay 获取流式请求的对冲等待时间，令牌设置优先于用户分组，返回 0 表示不对冲
+func getHedgeDelay(c *gin.Context) time.Duration {
+	if c.GetInt("specific_channel_id") > 0 && !c.GetBool("specific_channel_id_ignore") {
+		return 0
+	}
+
+	if setting, ok := utils.GetGinValue[*model.TokenSetting](c, "token_setting"); ok && setting != nil && setting.Hedge.Enabled {
+		return time.Duration(setting.Hedge.DelayMs) * time.Millisecond
+	}
+
+	userGroup := model.GlobalUserGroupRatio.GetBySymbol(c.GetString("token_group"))
+	if userGroup != nil && userGroup.HedgeDelay > 0 {
+		return time.Duration(userGroup.HedgeDelay) * time.Millisecond
+	}
+
+	return 0
+}
+
+// providerContext GetProvider 会改写 context 中的渠道信息，对冲结束后需要恢复为胜出渠道的信息
+type providerContext struct {
+	channelId            int
+	channelType          int
+	channelTPM           int
+	originalModel        string
+	newModel             string
+	billingOriginalModel bool
+}
+
+func saveProviderContext(c *gin.Context) providerContext {
+	return providerContext{
+		channelId:            c.GetInt("channel_id"),
+		channelType:          c.GetInt("channel_type"),
+		channelTPM:           c.GetInt("channel_tpm"),
+		originalModel:        c.GetString("original_model"),
+		newModel:             c.GetString("new_model"),
+		billingOriginalModel: c.GetBool("billing_original_model"),
+	}
+}
+
+func (p providerContext) restore(c *gin.Context) {
+	c.Set("channel_id", p.channelId)
+	c.Set("channel_type", p.channelType)
+	c.Set("channel_tpm", p.channelTPM)
+	c.Set("original_model", p.originalModel)
+	c.Set("new_model", p.newModel)
+	c.Set("billing_original_model", p.billingOriginalModel)
+}
+
+type hedgeAttempt struct {
+	provider  providersBase.ChatInterface
+	modelName string
+	context   providerContext
+	stream    requester.StreamReaderInterface[string]
+	dataChan  <-chan string
+	errChan   <-chan error
+	release   func()
+	// 对冲请求在复制的 context 上选择渠道，主请求为 nil
+	ginContext *gin.Context
+}
+
+func (a *hedgeAttempt) recv() {
+	a.dataChan, a.errChan = a.stream.Recv()
+}
+
+// finish 释放请求占用的统计和并发名额
+func (a *hedgeAttempt) finish() {
+	if a.release != nil {
+		a.release()
+	}
+	if a.ginContext != nil {
+		relay_util.ReleaseChannelSlot(a.ginContext, a.provider.GetChannel().Id)
+	}
+}
+
+// close 关闭请求，并丢弃还未读取的数据
+func (a *hedgeAttempt) close() {
+	a.stream.Close()
+	a.finish()
+
+	go func() {
+		timeout := time.After(hedgeDrainTimeout)
+		for {
+			select {
+			case <-a.dataChan:
+			case <-a.errChan:
+				return
+			case <-timeout:
+				return
+			}
+		}
+	}()
+}
+
+// bufferedStream 将对冲过程中已经读取的首个数据重新放回流中
+type bufferedStream struct {
+	requester.StreamReaderInterface[string]
+	first    *string
+	firstErr error
+	dataChan <-chan string
+	errChan  <-chan error
+	onClose  func()
+}
+
+func (s *bufferedStream) Recv() (<-chan string, <-chan error) {
+	dataChan := make(chan string)
+	errChan := make(chan error)
+
+	go func() {
+		if s.firstErr != nil {
+			errChan <- s.firstErr
+			return
+		}
+
+		if s.first != nil {
+			dataChan <- *s.first
+		}
+
+		for {
+			select {
+			case data, ok := <-s.dataChan:
+				if !ok {
+					close(dataChan)
+					return
+				}
+				dataChan <- data
+			case err := <-s.errChan:
+				errChan <- err
+				return
+			}
+		}
+	}()
+
+	return dataChan, errChan
+}
+
+func (s *bufferedStream) Close() {
+	s.StreamReaderInterface.Close()
+	if s.onClose != nil {
+		s.onClose()
+	}
+}
+
+// hedgeStream 在首个数据块超过对冲时间仍未返回时，使用另一个渠道发起相同的请求，先返回数据的渠道胜出，另一个被取消
+func (r *relayChat) hedgeStream(stream requester.StreamReaderInterface[string], delay time.Duration, sendStartTime time.Time) requester.StreamReaderInterface[string] {
+	chatProvider, _ := r.provider.(providersBase.ChatInterface)
+	primary := &hedgeAttempt{
+		provider:  chatProvider,
+		modelName: r.modelName,
+		context:   saveProviderContext(r.c),
+		stream:    stream,
+	}
+	primary.recv()
+
+	timer := time.NewTimer(delay - time.Since(sendStartTime))
+	defer timer.Stop()
+
+	var secondary *hedgeAttempt
+	var hedgeReady chan *hedgeAttempt
+	var primaryFailure error
+	primaryAlive := true
+
+	// 对冲请求的选择渠道和建立连接可以被取消
+	hedgeCtx, cancelHedge := context.WithCancel(r.c.Request.Context())
+	abandonHedge := func() {
+		cancelHedge()
+		if hedgeReady == nil {
+			return
+		}
+		// 对冲请求仍在建立中，等它返回后关闭，避免占用的渠道资源泄露
+		go func(ready <-chan *hedgeAttempt) {
+			if attempt := <-ready; attempt != nil {
+				attempt.recv()
+				r.hedgeLose(attempt, sendStartTime)
+			}
+		}(hedgeReady)
+		hedgeReady = nil
+	}
+
+	for {
+		var primaryData, secondaryData <-chan string
+		var primaryErr, secondaryErr <-chan error
+		if primaryAlive {
+			primaryData, primaryErr = primary.dataChan, primary.errChan
+		}
+		if secondary != nil {
+			secondaryData, secondaryErr = secondary.dataChan, secondary.errChan
+		}
+
+		select {
+		case data := <-primaryData:
+			if secondary != nil {
+				r.hedgeLose(secondary, sendStartTime)
+			}
+			abandonHedge()
+			return r.hedgeWin(primary, &data, nil)
+
+		case data := <-secondaryData:
+			if primaryAlive {
+				r.hedgeLose(primary, sendStartTime)
+			} else {
+				r.hedgeFail(primary, primaryFailure)
+			}
+			return r.hedgeWin(secondary, &data, nil)
+
+		case err := <-primaryErr:
+			primaryAlive = false
+			primaryFailure = err
+			if secondary == nil && hedgeReady == nil {
+				abandonHedge()
+				return r.hedgeWin(primary, nil, err)
+			}
+			// 主请求在首个数据块之前失败，等待对冲请求的结果
+			primary.stream.Close()
+
+		case err := <-secondaryErr:
+			r.hedgeFail(secondary, err)
+			secondary = nil
+			if !primaryAlive {
+				cancelHedge()
+				return r.hedgeWin(primary, nil, err)
+			}
+
+		case <-timer.C:
+			hedgeReady = make(chan *hedgeAttempt, 1)
+			go func(ready chan<- *hedgeAttempt) {
+				ready <- r.startHedgeAttempt(hedgeCtx, primary)
+			}(hedgeReady)
+
+		case attempt := <-hedgeReady:
+			hedgeReady = nil
+			if attempt == nil {
+				// 没有其他可用渠道，继续等待主请求
+				if !primaryAlive {
+					cancelHedge()
+					return r.hedgeWin(primary, nil, primaryFailure)
+				}
+				continue
+			}
+			attempt.recv()
+			secondary = attempt
+		}
+	}
+}
+
+// startHedgeAttempt 在复制的 context 上选择另一个渠道并发起请求，不会改写主请求 context 中的渠道信息
+func (r *relayChat) startHedgeAttempt(ctx context.Context, primary *hedgeAttempt) *hedgeAttempt {
+	hc := r.c.Copy()
+	hc.Request = hc.Request.WithContext(ctx)
+
+	skipChannelIds, _ := utils.GetGinValue[[]int](hc, "skip_channel_ids")
+	hc.Set("skip_channel_ids", append(slices.Clone(skipChannelIds), primary.provider.GetChannel().Id))
+
+	provider, modelName, err := GetProvider(hc, r.getOriginalModel())
+	if err != nil {
+		logger.LogWarn(ctx, "hedge request skipped: "+err.Error())
+		return nil
+	}
+
+	channel := provider.GetChannel()
+	chatProvider, ok := provider.(providersBase.ChatInterface)
+	if !ok || ctx.Err() != nil {
+		relay_util.ReleaseChannelSlot(hc, channel.Id)
+		model.CircuitBreakers.Release(channel.Id, r.getOriginalModel())
+		return nil
+	}
+
+	// 主请求胜出时取消对冲请求的上游连接
+	if providerRequester := provider.GetRequester(); providerRequester != nil {
+		providerRequester.Context = ctx
+		if primaryRequester := r.provider.GetRequester(); primaryRequester != nil {
+			providerRequester.TraceContext = primaryRequester.TraceContext
+		}
+	}
+	provider.SetOtherArg(r.otherArg)
+	provider.SetUsage(&types.Usage{PromptTokens: r.provider.GetUsage().PromptTokens})
+
+	logger.LogInfo(ctx, fmt.Sprintf("first response timeout, hedging with channel #%d(%s)", channel.Id, channel.Name))
+
+	attempt := &hedgeAttempt{
+		provider:   chatProvider,
+		modelName:  modelName,
+		context:    saveProviderContext(hc),
+		release:    model.ChannelStats.Acquire(channel.Id, r.getOriginalModel()),
+		ginContext: hc,
+	}
+
+	request := r.chatRequest
+	request.Model = modelName
+	stream, apiErr := chatProvider.CreateChatCompletionStream(&request)
+	if apiErr != nil {
+		attempt.finish()
+		if ctx.Err() != nil {
+			model.CircuitBreakers.Release(channel.Id, r.getOriginalModel())
+			return nil
+		}
+		recordCircuitBreaker(channel.Id, r.getOriginalModel(), apiErr)
+		logger.LogWarn(ctx, fmt.Sprintf("hedge request failed (channel #%d): %s", channel.Id, apiErr.Message))
+		return nil
+	}
+
+	attempt.stream = stream
+	return attempt
+}
+
+// hedgeLose 取消落败的请求，并按请求开始后已等待的时间给渠道记录延迟惩罚
+func (r *relayChat) hedgeLose(loser *hedgeAttempt, sendStartTime time.Time) {
+	loser.close()
+
+	channelId := loser.provider.GetChannel().Id
+	if loser.ginContext == nil {
+		// 主请求的并发名额记录在原 context 中
+		relay_util.ReleaseChannelSlot(r.c, channelId)
+	}
+	// 落败的请求无法说明渠道是否可用，只释放熔断器的探测名额
+	model.CircuitBreakers.Release(channelId, r.getOriginalModel())
+
+	// 对冲请求开始得晚，只按自身的等待时间记录会让落败的渠道显得更快
+	elapsed := time.Since(sendStartTime)
+	model.ChannelStats.Record(channelId, r.getOriginalModel(), model.ChannelOutcome{
+		Success:       true,
+		Latency:       elapsed,
+		FirstResponse: elapsed,
+	})
+}
+
+// hedgeFail 请求在返回首个数据块之前失败，记录渠道失败和熔断结果
+func (r *relayChat) hedgeFail(attempt *hedgeAttempt, err error) {
+	channelId := attempt.provider.GetChannel().Id
+	if attempt.ginContext == nil {
+		// 主请求的流在失败时已经关闭，并发名额记录在原 context 中
+		relay_util.ReleaseChannelSlot(r.c, channelId)
+	} else {
+		attempt.close()
+	}
+
+	model.ChannelStats.Record(channelId, r.getOriginalModel(), model.ChannelOutcome{Success: false})
+	recordCircuitBreaker(channelId, r.getOriginalModel(), common.StringErrorWrapper(err.Error(), "stream_error", 900))
+}
+
+// hedgeWin 切换到胜出的请求，后续的计费和日志都以胜出的渠道为准
+func (r *relayChat) hedgeWin(winner *hedgeAttempt, first *string, firstErr error) requester.StreamReaderInterface[string] {
+	winner.context.restore(r.c)
+	r.provider = winner.provider
+	r.modelName = winner.modelName
+
+	return &bufferedStream{
+		StreamReaderInterface: winner.stream,
+		first:                 first,
+		firstErr:              firstErr,
+		dataChan:              winner.dataChan,
+		errChan:               winner.errChan,
+		onClose:               winner.finish,
+	}
+}
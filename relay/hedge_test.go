@@ -0,0 +1,117 @@
+package relay
+
+import (
+	"errors"
+	"net/http/httptest"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/model"
+	providersBase "one-api/providers/base"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type testHedgeProvider struct {
+	providersBase.ChatInterface
+	channel *model.Channel
+}
+
+func (p *testHedgeProvider) GetChannel() *model.Channel {
+	return p.channel
+}
+
+type testHedgeStream struct {
+	closed bool
+}
+
+func (s *testHedgeStream) Recv() (<-chan string, <-chan error) {
+	return make(chan string), make(chan error)
+}
+
+func (s *testHedgeStream) Close() {
+	s.closed = true
+}
+
+func newTestHedgeAttempt(channelId int, ginContext *gin.Context) (*hedgeAttempt, *testHedgeStream) {
+	stream := &testHedgeStream{}
+	errChan := make(chan error)
+	close(errChan)
+
+	return &hedgeAttempt{
+		provider:   &testHedgeProvider{channel: &model.Channel{Id: channelId}},
+		stream:     stream,
+		dataChan:   make(chan string),
+		errChan:    errChan,
+		ginContext: ginContext,
+	}, stream
+}
+
+func newTestHedgeRelay(modelName string) *relayChat {
+	logger.Logger = zap.NewNop()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+
+	return &relayChat{relayBase: relayBase{c: c, originalModel: modelName}}
+}
+
+func TestHedgeLoseRecordsRequestWaitTime(t *testing.T) {
+	r := newTestHedgeRelay("hedge-lose")
+	loser, stream := newTestHedgeAttempt(9001, r.c.Copy())
+
+	// 对冲请求刚发出不久，主请求已经等待了 2 秒
+	r.hedgeLose(loser, time.Now().Add(-2*time.Second))
+
+	assert.True(t, stream.closed)
+	stats := model.ChannelStats.Get(9001, "hedge-lose")
+	assert.Equal(t, int64(1), stats.Requests)
+	assert.Equal(t, float64(1), stats.SuccessRate)
+	assert.GreaterOrEqual(t, stats.Latency, float64(2000))
+	assert.GreaterOrEqual(t, stats.FirstResponse, float64(2000))
+}
+
+func TestHedgeFailRecordsChannelFailure(t *testing.T) {
+	originThreshold := config.CircuitBreakerFailureThreshold
+	config.CircuitBreakerFailureThreshold = 1
+	t.Cleanup(func() {
+		config.CircuitBreakerFailureThreshold = originThreshold
+		model.CircuitBreakers.Reset(9002)
+		model.CircuitBreakers.Reset(9003)
+	})
+
+	cases := []struct {
+		name      string
+		channelId int
+		secondary bool
+	}{
+		{"对冲请求胜出时记录主请求的失败", 9002, false},
+		{"对冲请求在首个数据块之前失败", 9003, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := newTestHedgeRelay("hedge-fail")
+			var ginContext *gin.Context
+			if c.secondary {
+				ginContext = r.c.Copy()
+			}
+			attempt, stream := newTestHedgeAttempt(c.channelId, ginContext)
+
+			r.hedgeFail(attempt, errors.New("upstream error"))
+
+			// 主请求的流在失败时已经关闭，不会重复关闭
+			assert.Equal(t, c.secondary, stream.closed)
+
+			stats := model.ChannelStats.Get(c.channelId, "hedge-fail")
+			assert.Equal(t, int64(1), stats.Requests)
+			assert.Equal(t, float64(0), stats.SuccessRate)
+
+			breakers := model.CircuitBreakers.GetByChannel(c.channelId)
+			assert.Len(t, breakers, 1)
+			assert.Equal(t, model.CircuitOpen, breakers[0].State)
+		})
+	}
+}
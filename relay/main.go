@@ -2,6 +2,7 @@ package relay
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,11 +14,13 @@ import (
 	"one-api/metrics"
 	"one-api/model"
 	"one-api/relay/relay_util"
+	"one-api/tracing"
 	"one-api/types"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func Relay(c *gin.Context) {
@@ -30,6 +33,13 @@ func Relay(c *gin.Context) {
 	// Apply pre-mapping before setRequest to ensure request body modifications take effect
 	applyPreMappingBeforeRequest(c)
 
+	// 个人信息、密钥需要在解析请求、选择渠道和发送到上游之前处理
+	if err := applySafeRedact(c); err != nil {
+		openaiErr := common.StringErrorWrapperLocal(err.Error(), "sensitive_data_detected", http.StatusBadRequest)
+		relay.HandleJsonError(openaiErr)
+		return
+	}
+
 	if err := relay.setRequest(); err != nil {
 		openaiErr := common.StringErrorWrapperLocal(err.Error(), "one_hub_error", http.StatusBadRequest)
 		relay.HandleJsonError(openaiErr)
@@ -48,7 +58,7 @@ func Relay(c *gin.Context) {
 		defer heartbeat.Close()
 	}
 
-	apiErr, done := RelayHandler(relay)
+	apiErr, done := relayAttempt(relay, 0)
 	if apiErr == nil {
 		metrics.RecordProvider(c, 200)
 		return
@@ -67,21 +77,23 @@ func Relay(c *gin.Context) {
 	timeout := time.Duration(config.RetryTimeOut) * time.Second
 
 	for i := retryTimes; i > 0; i-- {
-		// 冻结通道
-		shouldCooldowns(c, channel, apiErr)
+		skipChannel(c, channel)
 
 		if time.Since(startTime) > timeout {
 			apiErr = common.StringErrorWrapperLocal("重试超时，上游负载已饱和，请稍后再试", "system_error", http.StatusTooManyRequests)
 			break
 		}
 
+		// 按失败的渠道记录重试次数
+		metrics.RecordRelayRetry(metrics.GetRelayLabels(c))
+
 		if err := relay.setProvider(relay.getOriginalModel()); err != nil {
 			break
 		}
 
 		channel = relay.getProvider().GetChannel()
 		logger.LogError(c.Request.Context(), fmt.Sprintf("using channel #%d(%s) to retry (remain times %d)", channel.Id, channel.Name, i))
-		apiErr, done = RelayHandler(relay)
+		apiErr, done = relayAttempt(relay, retryTimes-i+1)
 		if apiErr == nil {
 			metrics.RecordProvider(c, 200)
 			return
@@ -102,7 +114,43 @@ func Relay(c *gin.Context) {
 	}
 }
 
+// relayAttempt 向当前渠道发起一次请求，每次尝试（包括重试）记录为一个 span
+// span 的上下文通过渠道的请求器传递，不改写 c.Request，避免与对冲、计费等协程产生数据竞争
+func relayAttempt(relay RelayBaseInterface, attempt int) (*types.OpenAIErrorWithStatusCode, bool) {
+	c := relay.getContext()
+	ctx, span := tracing.Start(c.Request.Context(), "relay.attempt", append(tracing.GinAttributes(c), attribute.Int("attempt", attempt))...)
+	if requester := relay.getProvider().GetRequester(); requester != nil {
+		requester.TraceContext = ctx
+	}
+
+	apiErr, done := RelayHandler(relay)
+	if apiErr == nil {
+		tracing.End(span, nil)
+		return nil, done
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", apiErr.StatusCode))
+	tracing.End(span, &apiErr.OpenAIError)
+	return apiErr, done
+}
+
+// traceContext 当前尝试的链路上下文
+func traceContext(relay RelayBaseInterface) context.Context {
+	if requester := relay.getProvider().GetRequester(); requester != nil && requester.TraceContext != nil {
+		return requester.TraceContext
+	}
+	return relay.getContext().Request.Context()
+}
+
 func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCode, done bool) {
+	// 没有向渠道发出请求时，释放选择渠道时占用的熔断器探测名额
+	sent := false
+	defer func() {
+		if !sent {
+			model.CircuitBreakers.Release(relay.getProvider().GetChannel().Id, relay.getOriginalModel())
+		}
+	}()
+
 	promptTokens, tonkeErr := relay.getPromptTokens()
 	if tonkeErr != nil {
 		err = common.ErrorWrapperLocal(tonkeErr, "token_error", http.StatusBadRequest)
@@ -110,6 +158,16 @@ func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCod
 		return
 	}
 
+	responseCache := relay_util.NewResponseCache(relay.getContext(), relay.getOriginalModel(), relay.getRequest(), relay.IsStream())
+	if responseCache != nil {
+		if entry := responseCache.Get(); entry != nil {
+			if err = relayResponseCacheHit(relay, entry); err != nil {
+				done = true
+			}
+			return
+		}
+	}
+
 	usage := &types.Usage{
 		PromptTokens: promptTokens,
 	}
@@ -122,17 +180,55 @@ func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCod
 		return
 	}
 
+	channelId := relay.getProvider().GetChannel().Id
+	release := model.ChannelStats.Acquire(channelId, relay.getOriginalModel())
+	sendStartTime := time.Now()
+	// 先于响应缓存包装 writer，恢复时顺序相反
+	payloadCapture := relay_util.NewPayloadCapture(relay.getContext(), relay.getOriginalModel(), relay.IsStream())
+	if payloadCapture != nil {
+		payloadCapture.Capture()
+	}
+	if responseCache != nil {
+		responseCache.Capture()
+	}
+	sent = true
 	err, done = relay.send()
+	release()
+	if relay.IsStream() {
+		tracing.RecordSpan(traceContext(relay), "relay.first_token", sendStartTime, relay.GetFirstResponseTime(), tracing.GinAttributes(relay.getContext())...)
+	}
+
+	// 流式对冲时实际完成请求的可能是另一个渠道，以胜出的渠道为准
+	if winner := relay.getProvider(); winner.GetChannel().Id != channelId {
+		channelId = winner.GetChannel().Id
+		usage = winner.GetUsage()
+		quota.SetChannel(winner.GetChannel())
+	}
+	recordChannelOutcome(relay, channelId, sendStartTime, err)
 	// 最后处理流式中断时计算tokens
 	if usage.CompletionTokens == 0 && usage.TextBuilder.Len() > 0 {
 		usage.CompletionTokens = common.CountTokenText(usage.TextBuilder.String(), relay.getModelName())
 		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
 	}
+	recordRelayMetrics(relay, sendStartTime, usage, err)
 	if err != nil {
+		if responseCache != nil {
+			responseCache.Release()
+		}
+		if payloadCapture != nil {
+			payloadCapture.Store(channelId, usage, err)
+		}
 		quota.Undo(relay.getContext())
 		return
 	}
 
+	if responseCache != nil {
+		responseCache.Store(usage)
+	}
+	if payloadCapture != nil {
+		payloadCapture.Store(channelId, usage, nil)
+	}
+
 	quota.SetFirstResponseTime(relay.GetFirstResponseTime())
 
 	quota.Consume(relay.getContext(), usage, relay.IsStream())
@@ -140,23 +236,95 @@ func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCod
 	return
 }
 
-func shouldCooldowns(c *gin.Context, channel *model.Channel, apiErr *types.OpenAIErrorWithStatusCode) {
-	modelName := c.GetString("new_model")
-	channelId := channel.Id
+// relayResponseCacheHit 直接返回缓存的响应，按缓存计费倍率计费
+// 与正常请求一样先预扣费，额度不足或超出令牌限额时不返回缓存
+func relayResponseCacheHit(relay RelayBaseInterface, entry *relay_util.ResponseCacheEntry) *types.OpenAIErrorWithStatusCode {
+	c := relay.getContext()
+	usage := &types.Usage{
+		PromptTokens:     entry.PromptTokens,
+		CompletionTokens: entry.CompletionTokens,
+		TotalTokens:      entry.PromptTokens + entry.CompletionTokens,
+	}
+
+	quota := relay_util.NewQuota(c, relay.getModelName(), entry.PromptTokens)
+	quota.SetCacheHit(config.ResponseCacheSettingsInstance.BillingRatio)
+	if err := quota.PreQuotaConsumption(); err != nil {
+		return err
+	}
 
-	// 如果是频率限制，冻结通道
-	if apiErr.StatusCode == http.StatusTooManyRequests {
-		model.ChannelGroup.SetCooldowns(channelId, modelName)
+	c.Header(relay_util.ResponseCacheStatusHeader, "HIT")
+	responseCache(c, entry.Body, entry.IsStream)
+
+	quota.Consume(c, usage, entry.IsStream)
+	return nil
+}
+
+// 记录渠道的请求结果，用于负载均衡和熔断
+func recordChannelOutcome(relay RelayBaseInterface, channelId int, sendStartTime time.Time, apiErr *types.OpenAIErrorWithStatusCode) {
+	if apiErr != nil && !isChannelFailure(apiErr) {
+		recordCircuitBreaker(channelId, relay.getOriginalModel(), apiErr)
+		return
 	}
 
+	outcome := model.ChannelOutcome{
+		Success: apiErr == nil,
+		Latency: time.Since(sendStartTime),
+	}
+
+	if firstResponseTime := relay.GetFirstResponseTime(); !firstResponseTime.IsZero() {
+		outcome.FirstResponse = firstResponseTime.Sub(sendStartTime)
+	}
+
+	model.ChannelStats.Record(channelId, relay.getOriginalModel(), outcome)
+	recordCircuitBreaker(channelId, relay.getOriginalModel(), apiErr)
+}
+
+// recordRelayMetrics 记录上游请求的耗时、首字时间和错误类型
+func recordRelayMetrics(relay RelayBaseInterface, sendStartTime time.Time, usage *types.Usage, apiErr *types.OpenAIErrorWithStatusCode) {
+	channel := relay.getProvider().GetChannel()
+	labels := metrics.GetRelayLabels(relay.getContext())
+	labels.ChannelId = channel.Id
+	labels.ChannelType = channel.Type
+
+	if apiErr != nil {
+		if !apiErr.LocalError {
+			metrics.RecordUpstreamError(labels, apiErr.StatusCode, apiErr.Type)
+		}
+		return
+	}
+
+	var firstToken time.Duration
+	if firstResponseTime := relay.GetFirstResponseTime(); !firstResponseTime.IsZero() {
+		firstToken = firstResponseTime.Sub(sendStartTime)
+	}
+	metrics.RecordRelayLatency(labels, time.Since(sendStartTime), firstToken, usage.CompletionTokens)
+}
+
+func recordCircuitBreaker(channelId int, modelName string, apiErr *types.OpenAIErrorWithStatusCode) {
+	if apiErr == nil {
+		model.CircuitBreakers.RecordOutcome(channelId, modelName, true, 0, false)
+		return
+	}
+	model.CircuitBreakers.RecordOutcome(channelId, modelName, false, apiErr.StatusCode, apiErr.LocalError)
+}
+
+// 本地错误和请求参数错误不计入渠道的失败
+func isChannelFailure(apiErr *types.OpenAIErrorWithStatusCode) bool {
+	return model.IsCircuitFailure(apiErr.StatusCode, apiErr.LocalError)
+}
+
+func skipChannel(c *gin.Context, channel *model.Channel) {
 	skipChannelIds, ok := utils.GetGinValue[[]int](c, "skip_channel_ids")
 	if !ok {
 		skipChannelIds = make([]int, 0)
 	}
 
-	skipChannelIds = append(skipChannelIds, channelId)
+	skipChannelIds = append(skipChannelIds, channel.Id)
 
 	c.Set("skip_channel_ids", skipChannelIds)
+
+	// 不再使用该渠道，释放占用的并发名额
+	relay_util.ReleaseChannelSlot(c, channel.Id)
 }
 
 // applies pre-mapping before setRequest to ensure modifications take effect
@@ -186,7 +354,7 @@ func applyPreMappingBeforeRequest(c *gin.Context) {
 		return
 	}
 
-	provider, _, err := GetProvider(c, requestBody.Model)
+	provider, err := lookupProvider(c, requestBody.Model)
 	if err != nil {
 		return
 	}
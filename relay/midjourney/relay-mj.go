@@ -33,6 +33,11 @@ func RelayMidjourneyImage(c *gin.Context) {
 		})
 		return
 	}
+	// 已转存的图片直接跳转，上游地址可能已过期
+	if midjourneyTask.StoredImageUrl != "" {
+		c.Redirect(http.StatusFound, midjourneyTask.StoredImageUrl)
+		return
+	}
 	resp, err := http.Get(midjourneyTask.ImageUrl)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -90,6 +95,7 @@ func RelayMidjourneyNotify(c *gin.Context) *provider.MidjourneyResponse {
 	midjourneyTask.StartTime = midjRequest.StartTime
 	midjourneyTask.FinishTime = midjRequest.FinishTime
 	midjourneyTask.ImageUrl = midjRequest.ImageUrl
+	oldStatus := midjourneyTask.Status
 	midjourneyTask.Status = midjRequest.Status
 	midjourneyTask.FailReason = midjRequest.FailReason
 	err = midjourneyTask.Update()
@@ -100,6 +106,12 @@ func RelayMidjourneyNotify(c *gin.Context) *provider.MidjourneyResponse {
 		}
 	}
 
+	if midjourneyTask.Status == "SUCCESS" && oldStatus != "SUCCESS" {
+		common.SafeGoroutine(func() {
+			relay_util.ArchiveMidjourneyTask(midjourneyTask)
+		})
+	}
+
 	return nil
 }
 
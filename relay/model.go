@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"net/http"
 	"one-api/common"
-	"one-api/common/config"
 	"one-api/common/utils"
 	"one-api/model"
 	"one-api/providers/claude"
@@ -46,6 +45,8 @@ func ListModelsByToken(c *gin.Context) {
 		})
 		return
 	}
+	// 虚拟模型的任一实际模型在分组中可用时一并列出
+	models = append(models, model.GlobalVirtualModels.GetAvailableNames(models)...)
 	sort.Strings(models)
 
 	var groupOpenAIModels []*OpenAIModels
@@ -92,18 +93,15 @@ func ListGeminiModelsByToken(c *gin.Context) {
 	sort.Strings(models)
 
 	var geminiModels []gemini.ModelDetails
+	// 非 Gemini 渠道会转换为 Chat 请求，所以分组下的模型都可以使用
 	for _, modelName := range models {
-		// Get the price to check if it's a Gemini model (channel_type=25)
-		price := model.PricingInstance.GetPrice(modelName)
-		if price.ChannelType == config.ChannelTypeGemini {
-			geminiModels = append(geminiModels, gemini.ModelDetails{
-				Name:        fmt.Sprintf("models/%s", modelName),
-				DisplayName: cases.Title(language.Und).String(strings.ReplaceAll(modelName, "-", " ")),
-				SupportedGenerationMethods: []string{
-					"generateContent",
-				},
-			})
-		}
+		geminiModels = append(geminiModels, gemini.ModelDetails{
+			Name:        fmt.Sprintf("models/%s", modelName),
+			DisplayName: cases.Title(language.Und).String(strings.ReplaceAll(modelName, "-", " ")),
+			SupportedGenerationMethods: []string{
+				"generateContent",
+			},
+		})
 	}
 
 	c.JSON(200, gemini.ModelListResponse{
@@ -132,15 +130,12 @@ func ListClaudeModelsByToken(c *gin.Context) {
 	sort.Strings(models)
 
 	var claudeModelsData []claude.Model
+	// 非 Claude 渠道会转换为 Chat 请求，所以分组下的模型都可以使用
 	for _, modelName := range models {
-		// Get the price to check if it's a Gemini model (channel_type=25)
-		price := model.PricingInstance.GetPrice(modelName)
-		if price.ChannelType == config.ChannelTypeAnthropic {
-			claudeModelsData = append(claudeModelsData, claude.Model{
-				ID:   modelName,
-				Type: "model",
-			})
-		}
+		claudeModelsData = append(claudeModelsData, claude.Model{
+			ID:   modelName,
+			Type: "model",
+		})
 	}
 
 	c.JSON(200, claude.ModelListResponse{
@@ -25,6 +25,7 @@ type RelayModeChatRealtime struct {
 	providerConn   *websocket.Conn
 	quota          *relay_util.Quota
 	usage          *types.UsageEvent
+	bridge         bool
 }
 
 var upgrader = websocket.Upgrader{
@@ -60,6 +61,11 @@ func ChatRealtime(c *gin.Context) {
 		return
 	}
 
+	if relay.bridge {
+		newRealtimeBridge(c, userConn, modelName).Run()
+		return
+	}
+
 	relay.quota = relay_util.NewQuota(relay.getContext(), relay.getModelName(), 0)
 
 	relay.usage = &types.UsageEvent{}
@@ -106,6 +112,11 @@ func (r *RelayModeChatRealtime) getProvider() bool {
 			return false
 		}
 
+		if r.useBridge() {
+			r.bridge = true
+			return true
+		}
+
 		realtimeProvider, ok := r.provider.(providersBase.RealtimeInterface)
 		if !ok {
 			r.abortWithMessage("channel not implemented")
@@ -137,6 +148,20 @@ func (r *RelayModeChatRealtime) getProvider() bool {
 	return false
 }
 
+// useBridge 渠道不支持原生 Realtime 协议时，使用语音转文字、对话、文字转语音组合实现
+func (r *RelayModeChatRealtime) useBridge() bool {
+	if !config.RealtimeBridgeSettingsInstance.Enabled {
+		return false
+	}
+
+	if _, ok := r.provider.(providersBase.RealtimeInterface); !ok {
+		return true
+	}
+
+	uriProvider, ok := r.provider.(interface{ GetAPIUri(relayMode int) string })
+	return ok && uriProvider.GetAPIUri(config.RelayModeChatRealtime) == ""
+}
+
 func (r *RelayModeChatRealtime) skipChannelIds(channelId int) {
 	skipChannelIds, ok := utils.GetGinValue[[]int](r.c, "skip_channel_ids")
 	if !ok {
@@ -0,0 +1,914 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Realtime 桥接：渠道不支持原生 Realtime 协议时，由 hub 维护会话
+// 用户提交的音频经过 语音转文字 -> 对话(流式) -> 文字转语音 三个阶段处理
+// 每个阶段都作为一次独立的内部请求转发，分别选择渠道、分别计费
+
+const (
+	realtimeAudioFormatPCM16 = "pcm16"
+	realtimeAudioFormatULaw  = "g711_ulaw"
+	realtimeAudioFormatALaw  = "g711_alaw"
+
+	realtimeAudioChunkSize = 24000 // 每个 response.audio.delta 的音频字节数(pcm16 24kHz 下为 0.5 秒)
+
+	realtimeTranscriptionQueueSize = 16 // 等待转写的音频数量，超出时暂停读取客户端消息
+)
+
+// 内部请求需要从 WebSocket 连接上继承的上下文
+var realtimeBridgeContextKeys = []string{
+	logger.RequestIdKey,
+	"id",
+	"username",
+	"role",
+	"token_id",
+	"token_name",
+	"token_group",
+	"token_backup_group",
+	"token_setting",
+	"group",
+	"group_ratio",
+	"is_backupGroup",
+	"specific_channel_id",
+	"specific_channel_id_ignore",
+}
+
+type realtimeTranscriptionSetting struct {
+	Model    string `json:"model,omitempty"`
+	Language string `json:"language,omitempty"`
+	Prompt   string `json:"prompt,omitempty"`
+}
+
+type realtimeBridgeSession struct {
+	ID                      string                        `json:"id"`
+	Object                  string                        `json:"object"`
+	Model                   string                        `json:"model"`
+	Modalities              []string                      `json:"modalities"`
+	Instructions            string                        `json:"instructions"`
+	Voice                   string                        `json:"voice"`
+	InputAudioFormat        string                        `json:"input_audio_format"`
+	OutputAudioFormat       string                        `json:"output_audio_format"`
+	InputAudioTranscription *realtimeTranscriptionSetting `json:"input_audio_transcription"`
+	TurnDetection           any                           `json:"turn_detection"`
+	Tools                   []any                         `json:"tools"`
+	ToolChoice              string                        `json:"tool_choice"`
+	Temperature             *float64                      `json:"temperature,omitempty"`
+	MaxResponseOutputTokens any                           `json:"max_response_output_tokens"`
+}
+
+// realtimeSessionUpdate 只更新客户端传入的字段
+type realtimeSessionUpdate struct {
+	Modalities              []string                      `json:"modalities,omitempty"`
+	Instructions            *string                       `json:"instructions,omitempty"`
+	Voice                   string                        `json:"voice,omitempty"`
+	InputAudioFormat        string                        `json:"input_audio_format,omitempty"`
+	OutputAudioFormat       string                        `json:"output_audio_format,omitempty"`
+	InputAudioTranscription *realtimeTranscriptionSetting `json:"input_audio_transcription,omitempty"`
+	Temperature             *float64                      `json:"temperature,omitempty"`
+	MaxResponseOutputTokens any                           `json:"max_response_output_tokens,omitempty"`
+}
+
+type realtimeContentPart struct {
+	Type       string `json:"type"`
+	Text       string `json:"text,omitempty"`
+	Audio      string `json:"audio,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+}
+
+type realtimeItem struct {
+	ID      string                `json:"id"`
+	Object  string                `json:"object"`
+	Type    string                `json:"type"`
+	Status  string                `json:"status"`
+	Role    string                `json:"role"`
+	Content []realtimeContentPart `json:"content"`
+
+	transcribed chan struct{} // 音频消息转写结束后关闭
+}
+
+func (i *realtimeItem) text() string {
+	var text strings.Builder
+	for _, part := range i.Content {
+		switch part.Type {
+		case "input_text", "text":
+			text.WriteString(part.Text)
+		case "input_audio", "audio":
+			text.WriteString(part.Transcript)
+		}
+	}
+	return text.String()
+}
+
+type realtimeClientEvent struct {
+	EventID  string                 `json:"event_id"`
+	Type     string                 `json:"type"`
+	Session  *realtimeSessionUpdate `json:"session,omitempty"`
+	Audio    string                 `json:"audio,omitempty"`
+	Item     *realtimeItem          `json:"item,omitempty"`
+	ItemID   string                 `json:"item_id,omitempty"`
+	Response *realtimeSessionUpdate `json:"response,omitempty"`
+}
+
+type realtimeTranscriptionJob struct {
+	item    *realtimeItem
+	audio   []byte
+	session realtimeBridgeSession
+}
+
+type realtimeBridge struct {
+	c        *gin.Context
+	ctx      context.Context
+	userConn *websocket.Conn
+	model    string
+
+	writeLock sync.Mutex
+
+	transcriptions chan *realtimeTranscriptionJob
+
+	lock           sync.Mutex
+	session        realtimeBridgeSession
+	audioBuffer    []byte
+	items          []*realtimeItem
+	cancelResponse context.CancelFunc
+	responses      sync.WaitGroup
+}
+
+func newRealtimeBridge(c *gin.Context, userConn *websocket.Conn, modelName string) *realtimeBridge {
+	setting := config.RealtimeBridgeSettingsInstance
+
+	return &realtimeBridge{
+		c:        c,
+		userConn: userConn,
+		model:    modelName,
+		session: realtimeBridgeSession{
+			ID:                "sess_" + utils.GetRandomString(24),
+			Object:            "realtime.session",
+			Model:             modelName,
+			Modalities:        []string{"text", "audio"},
+			Voice:             setting.Voice,
+			InputAudioFormat:  realtimeAudioFormatPCM16,
+			OutputAudioFormat: realtimeAudioFormatPCM16,
+			InputAudioTranscription: &realtimeTranscriptionSetting{
+				Model: setting.TranscriptionModel,
+			},
+			// 不支持服务端语音活动检测，需要客户端提交音频
+			TurnDetection:           nil,
+			Tools:                   []any{},
+			ToolChoice:              "auto",
+			MaxResponseOutputTokens: "inf",
+		},
+	}
+}
+
+func (b *realtimeBridge) Run() {
+	defer b.userConn.Close()
+
+	ctx, cancel := context.WithCancel(b.c.Request.Context())
+	b.ctx = ctx
+
+	// 转写在单独的协程中按提交顺序处理，不阻塞读取客户端消息
+	b.transcriptions = make(chan *realtimeTranscriptionJob, realtimeTranscriptionQueueSize)
+	var worker sync.WaitGroup
+	worker.Add(1)
+	go func() {
+		defer worker.Done()
+		for job := range b.transcriptions {
+			b.runTranscription(job)
+		}
+	}()
+
+	b.send(gin.H{"type": "session.created", "session": b.session})
+
+	for {
+		messageType, message, err := b.userConn.ReadMessage()
+		if err != nil {
+			break
+		}
+		if messageType != websocket.TextMessage {
+			continue
+		}
+
+		var event realtimeClientEvent
+		if err := json.Unmarshal(message, &event); err != nil {
+			b.sendError("", "invalid_request_error", "invalid_event", err.Error())
+			continue
+		}
+
+		b.handleEvent(&event)
+	}
+
+	logger.LogInfo(b.c.Request.Context(), "连接由user关闭")
+
+	cancel()
+	close(b.transcriptions)
+	worker.Wait()
+	b.responses.Wait()
+}
+
+func (b *realtimeBridge) handleEvent(event *realtimeClientEvent) {
+	switch event.Type {
+	case "session.update":
+		b.updateSession(event)
+	case "input_audio_buffer.append":
+		b.appendAudio(event)
+	case "input_audio_buffer.clear":
+		b.lock.Lock()
+		b.audioBuffer = nil
+		b.lock.Unlock()
+		b.send(gin.H{"type": "input_audio_buffer.cleared"})
+	case "input_audio_buffer.commit":
+		b.commitAudio(event)
+	case "conversation.item.create":
+		b.createItem(event)
+	case "conversation.item.delete":
+		b.deleteItem(event)
+	case "response.create":
+		b.createResponse(event)
+	case "response.cancel":
+		b.lock.Lock()
+		if b.cancelResponse != nil {
+			b.cancelResponse()
+		}
+		b.lock.Unlock()
+	default:
+		b.sendError(event.EventID, "invalid_request_error", "unsupported_event", "unsupported event type: "+event.Type)
+	}
+}
+
+func (b *realtimeBridge) updateSession(event *realtimeClientEvent) {
+	if event.Session == nil {
+		b.sendError(event.EventID, "invalid_request_error", "missing_session", "session is required")
+		return
+	}
+	update := event.Session
+
+	if update.OutputAudioFormat != "" && update.OutputAudioFormat != realtimeAudioFormatPCM16 {
+		b.sendError(event.EventID, "invalid_request_error", "unsupported_audio_format", "only pcm16 output audio is supported")
+		return
+	}
+	if update.InputAudioFormat != "" && wavFormat(update.InputAudioFormat) == nil {
+		b.sendError(event.EventID, "invalid_request_error", "unsupported_audio_format", "unsupported input audio format: "+update.InputAudioFormat)
+		return
+	}
+
+	b.lock.Lock()
+	b.session.applyUpdate(update)
+	session := b.session
+	b.lock.Unlock()
+
+	b.send(gin.H{"type": "session.updated", "session": session})
+}
+
+func (s *realtimeBridgeSession) applyUpdate(update *realtimeSessionUpdate) {
+	if len(update.Modalities) > 0 {
+		s.Modalities = update.Modalities
+	}
+	if update.Instructions != nil {
+		s.Instructions = *update.Instructions
+	}
+	if update.Voice != "" {
+		s.Voice = update.Voice
+	}
+	if update.InputAudioFormat != "" {
+		s.InputAudioFormat = update.InputAudioFormat
+	}
+	if update.InputAudioTranscription != nil {
+		if update.InputAudioTranscription.Model == "" {
+			update.InputAudioTranscription.Model = config.RealtimeBridgeSettingsInstance.TranscriptionModel
+		}
+		s.InputAudioTranscription = update.InputAudioTranscription
+	}
+	if update.Temperature != nil {
+		s.Temperature = update.Temperature
+	}
+	if update.MaxResponseOutputTokens != nil {
+		s.MaxResponseOutputTokens = update.MaxResponseOutputTokens
+	}
+}
+
+func (b *realtimeBridge) appendAudio(event *realtimeClientEvent) {
+	audio, err := base64.StdEncoding.DecodeString(event.Audio)
+	if err != nil {
+		b.sendError(event.EventID, "invalid_request_error", "invalid_audio", err.Error())
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if len(b.audioBuffer)+len(audio) > config.RealtimeBridgeSettingsInstance.MaxAudioSize<<20 {
+		b.sendError(event.EventID, "invalid_request_error", "audio_buffer_too_large", "input audio buffer is too large")
+		return
+	}
+	b.audioBuffer = append(b.audioBuffer, audio...)
+}
+
+// commitAudio 提交音频，交给转写协程转写为用户消息
+// 之后的 response.create 会等待转写结束再生成回复
+func (b *realtimeBridge) commitAudio(event *realtimeClientEvent) {
+	b.lock.Lock()
+	audio := b.audioBuffer
+	b.audioBuffer = nil
+	session := b.session
+	b.lock.Unlock()
+
+	if len(audio) == 0 {
+		b.sendError(event.EventID, "invalid_request_error", "input_audio_buffer_commit_empty", "input audio buffer is empty")
+		return
+	}
+
+	item := &realtimeItem{
+		ID:      "item_" + utils.GetRandomString(24),
+		Object:  "realtime.item",
+		Type:    "message",
+		Status:  "completed",
+		Role:    "user",
+		Content: []realtimeContentPart{{Type: "input_audio"}},
+
+		transcribed: make(chan struct{}),
+	}
+	previousItemId := b.addItem(item)
+
+	b.send(gin.H{"type": "input_audio_buffer.committed", "previous_item_id": previousItemId, "item_id": item.ID})
+	b.send(gin.H{"type": "conversation.item.created", "previous_item_id": previousItemId, "item": item})
+
+	b.transcriptions <- &realtimeTranscriptionJob{item: item, audio: audio, session: session}
+}
+
+func (b *realtimeBridge) runTranscription(job *realtimeTranscriptionJob) {
+	item := job.item
+	defer close(item.transcribed)
+
+	if b.ctx.Err() != nil {
+		return
+	}
+
+	transcript, err := b.transcribe(b.ctx, job.audio, &job.session)
+	if err != nil {
+		if b.ctx.Err() != nil {
+			return
+		}
+		logger.LogError(b.c.Request.Context(), "realtime bridge transcription error: "+err.Error())
+		b.send(gin.H{
+			"type":          "conversation.item.input_audio_transcription.failed",
+			"item_id":       item.ID,
+			"content_index": 0,
+			"error":         gin.H{"type": "transcription_error", "code": "transcription_failed", "message": err.Error()},
+		})
+		return
+	}
+
+	b.lock.Lock()
+	item.Content[0].Transcript = transcript
+	b.lock.Unlock()
+
+	b.send(gin.H{
+		"type":          "conversation.item.input_audio_transcription.completed",
+		"item_id":       item.ID,
+		"content_index": 0,
+		"transcript":    transcript,
+	})
+}
+
+func (b *realtimeBridge) createItem(event *realtimeClientEvent) {
+	item := event.Item
+	if item == nil || item.Type != "message" {
+		b.sendError(event.EventID, "invalid_request_error", "unsupported_item", "only message items are supported")
+		return
+	}
+	if item.ID == "" {
+		item.ID = "item_" + utils.GetRandomString(24)
+	}
+	item.Object = "realtime.item"
+	item.Status = "completed"
+
+	previousItemId := b.addItem(item)
+	b.send(gin.H{"type": "conversation.item.created", "previous_item_id": previousItemId, "item": item})
+}
+
+func (b *realtimeBridge) deleteItem(event *realtimeClientEvent) {
+	b.lock.Lock()
+	deleted := false
+	for i, item := range b.items {
+		if item.ID == event.ItemID {
+			b.items = append(b.items[:i], b.items[i+1:]...)
+			deleted = true
+			break
+		}
+	}
+	b.lock.Unlock()
+
+	if !deleted {
+		b.sendError(event.EventID, "invalid_request_error", "item_not_found", "item not found: "+event.ItemID)
+		return
+	}
+	b.send(gin.H{"type": "conversation.item.deleted", "item_id": event.ItemID})
+}
+
+// addItem 添加到会话末尾，返回前一条的 id
+func (b *realtimeBridge) addItem(item *realtimeItem) any {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var previousItemId any
+	if len(b.items) > 0 {
+		previousItemId = b.items[len(b.items)-1].ID
+	}
+	b.items = append(b.items, item)
+
+	return previousItemId
+}
+
+func (b *realtimeBridge) createResponse(event *realtimeClientEvent) {
+	b.lock.Lock()
+	if b.cancelResponse != nil {
+		b.lock.Unlock()
+		b.sendError(event.EventID, "invalid_request_error", "conversation_already_has_active_response", "Conversation already has an active response")
+		return
+	}
+
+	session := b.session
+	if event.Response != nil {
+		session.applyUpdate(event.Response)
+	}
+	items := slices.Clone(b.items)
+
+	ctx, cancel := context.WithCancel(b.ctx)
+	b.cancelResponse = cancel
+	b.responses.Add(1)
+	b.lock.Unlock()
+
+	go func() {
+		defer func() {
+			cancel()
+			b.lock.Lock()
+			b.cancelResponse = nil
+			b.lock.Unlock()
+			b.responses.Done()
+		}()
+
+		// 等待之前提交的音频转写结束
+		for _, item := range items {
+			if item.transcribed == nil {
+				continue
+			}
+			select {
+			case <-item.transcribed:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		b.lock.Lock()
+		messages := chatMessages(&session, items)
+		b.lock.Unlock()
+
+		b.runResponse(ctx, &session, messages)
+	}()
+}
+
+// chatMessages 将会话转换为对话消息，调用方需持有锁
+func chatMessages(session *realtimeBridgeSession, items []*realtimeItem) []types.ChatCompletionMessage {
+	messages := make([]types.ChatCompletionMessage, 0, len(items)+1)
+	if session.Instructions != "" {
+		messages = append(messages, types.ChatCompletionMessage{Role: types.ChatMessageRoleSystem, Content: session.Instructions})
+	}
+
+	for _, item := range items {
+		text := item.text()
+		if text == "" {
+			continue
+		}
+		messages = append(messages, types.ChatCompletionMessage{Role: item.Role, Content: text})
+	}
+
+	return messages
+}
+
+func (b *realtimeBridge) runResponse(ctx context.Context, session *realtimeBridgeSession, messages []types.ChatCompletionMessage) {
+	responseId := "resp_" + utils.GetRandomString(24)
+	withAudio := utils.Contains("audio", session.Modalities)
+
+	response := gin.H{
+		"id":     responseId,
+		"object": "realtime.response",
+		"status": "in_progress",
+		"output": []any{},
+	}
+	b.send(gin.H{"type": "response.created", "response": response})
+
+	item := &realtimeItem{
+		ID:      "item_" + utils.GetRandomString(24),
+		Object:  "realtime.item",
+		Type:    "message",
+		Status:  "in_progress",
+		Role:    types.ChatMessageRoleAssistant,
+		Content: []realtimeContentPart{},
+	}
+	part := realtimeContentPart{Type: "text"}
+	deltaType := "response.text.delta"
+	if withAudio {
+		part.Type = "audio"
+		deltaType = "response.audio_transcript.delta"
+	}
+
+	itemEvent := gin.H{"response_id": responseId, "output_index": 0}
+	partEvent := gin.H{"response_id": responseId, "item_id": item.ID, "output_index": 0, "content_index": 0}
+
+	b.send(mergeEvent(itemEvent, "response.output_item.added", gin.H{"item": item}))
+	b.send(mergeEvent(partEvent, "response.content_part.added", gin.H{"part": part}))
+
+	text, usage, err := b.chat(ctx, session, messages, func(delta string) {
+		b.send(mergeEvent(partEvent, deltaType, gin.H{"delta": delta}))
+	})
+
+	if err == nil && withAudio {
+		b.send(mergeEvent(partEvent, "response.audio_transcript.done", gin.H{"transcript": text}))
+		if text != "" {
+			err = b.speech(ctx, session, text, func(audio []byte) {
+				b.send(mergeEvent(partEvent, "response.audio.delta", gin.H{"delta": base64.StdEncoding.EncodeToString(audio)}))
+			})
+		}
+		if err == nil {
+			b.send(mergeEvent(partEvent, "response.audio.done", nil))
+		}
+	} else if err == nil {
+		b.send(mergeEvent(partEvent, "response.text.done", gin.H{"text": text}))
+	}
+
+	status := "completed"
+	var statusDetails any
+	switch {
+	case ctx.Err() != nil:
+		status = "cancelled"
+		statusDetails = gin.H{"type": "cancelled", "reason": "client_cancelled"}
+	case err != nil:
+		status = "failed"
+		statusDetails = gin.H{"type": "failed", "error": gin.H{"type": "server_error", "code": "bridge_error", "message": err.Error()}}
+		logger.LogError(b.c.Request.Context(), "realtime bridge response error: "+err.Error())
+		b.sendError("", "server_error", "bridge_error", err.Error())
+	}
+
+	if withAudio {
+		part.Transcript = text
+	} else {
+		part.Text = text
+	}
+	item.Content = []realtimeContentPart{part}
+	item.Status = "completed"
+	if status != "completed" {
+		item.Status = "incomplete"
+	}
+
+	if status != "failed" {
+		b.send(mergeEvent(partEvent, "response.content_part.done", gin.H{"part": part}))
+		b.send(mergeEvent(itemEvent, "response.output_item.done", gin.H{"item": item}))
+	}
+
+	// 已生成的内容加入会话，用于之后的对话
+	if text != "" {
+		b.addItem(item)
+	}
+
+	response["status"] = status
+	response["status_details"] = statusDetails
+	response["output"] = []any{item}
+	response["usage"] = usage
+	b.send(gin.H{"type": "response.done", "response": response})
+}
+
+func mergeEvent(base gin.H, eventType string, fields gin.H) gin.H {
+	event := gin.H{"type": eventType}
+	for key, value := range base {
+		event[key] = value
+	}
+	for key, value := range fields {
+		event[key] = value
+	}
+	return event
+}
+
+// transcribe 语音转文字阶段
+func (b *realtimeBridge) transcribe(ctx context.Context, audio []byte, session *realtimeBridgeSession) (string, error) {
+	transcription := session.InputAudioTranscription
+	if transcription == nil {
+		transcription = &realtimeTranscriptionSetting{Model: config.RealtimeBridgeSettingsInstance.TranscriptionModel}
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	file, err := writer.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		return "", err
+	}
+	file.Write(pcmToWav(audio, session.InputAudioFormat))
+	writer.WriteField("model", transcription.Model)
+	writer.WriteField("response_format", "json")
+	if transcription.Language != "" {
+		writer.WriteField("language", transcription.Language)
+	}
+	if transcription.Prompt != "" {
+		writer.WriteField("prompt", transcription.Prompt)
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	w := newBridgeResponseWriter(nil)
+	b.relayStage(ctx, "/v1/audio/transcriptions", writer.FormDataContentType(), body.Bytes(), w)
+	if err := w.error(); err != nil {
+		return "", err
+	}
+
+	var response types.AudioResponse
+	if err := json.Unmarshal(w.body.Bytes(), &response); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(response.Text), nil
+}
+
+// chat 对话阶段，流式返回文本
+func (b *realtimeBridge) chat(ctx context.Context, session *realtimeBridgeSession, messages []types.ChatCompletionMessage, onDelta func(delta string)) (string, *types.UsageEvent, error) {
+	modelName := config.RealtimeBridgeSettingsInstance.ChatModel
+	if modelName == "" {
+		modelName = b.model
+	}
+
+	request := types.ChatCompletionRequest{
+		Model:         modelName,
+		Messages:      messages,
+		Stream:        true,
+		StreamOptions: &types.StreamOptions{IncludeUsage: true},
+		Temperature:   session.Temperature,
+	}
+	if maxTokens, ok := session.MaxResponseOutputTokens.(float64); ok {
+		request.MaxTokens = int(maxTokens)
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var text strings.Builder
+	usage := &types.UsageEvent{}
+	var streamErr error
+
+	w := newBridgeResponseWriter(func(data string) {
+		if data == "[DONE]" || streamErr != nil {
+			return
+		}
+
+		var chunk types.ChatCompletionStreamResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil || (chunk.Choices == nil && chunk.Usage == nil) {
+			streamErr = parseBridgeError([]byte(data))
+			return
+		}
+
+		if delta := chunk.GetResponseText(); delta != "" {
+			text.WriteString(delta)
+			onDelta(delta)
+		}
+		if chunk.Usage != nil {
+			usage.InputTokens = chunk.Usage.PromptTokens
+			usage.OutputTokens = chunk.Usage.CompletionTokens
+			usage.TotalTokens = chunk.Usage.TotalTokens
+		}
+	})
+	b.relayStage(ctx, "/v1/chat/completions", "application/json", body, w)
+
+	if err := w.error(); err != nil {
+		return "", usage, err
+	}
+
+	return text.String(), usage, streamErr
+}
+
+// speech 文字转语音阶段，按块返回 pcm16 音频
+// pcm 为 24kHz 16bit 单声道的原始音频，支持语音合成的渠道都按此格式返回
+func (b *realtimeBridge) speech(ctx context.Context, session *realtimeBridgeSession, text string, onAudio func(audio []byte)) error {
+	body, err := json.Marshal(types.SpeechAudioRequest{
+		Model:          config.RealtimeBridgeSettingsInstance.SpeechModel,
+		Input:          text,
+		Voice:          session.Voice,
+		ResponseFormat: "pcm",
+	})
+	if err != nil {
+		return err
+	}
+
+	w := newBridgeResponseWriter(nil)
+	b.relayStage(ctx, "/v1/audio/speech", "application/json", body, w)
+	if err := w.error(); err != nil {
+		return err
+	}
+
+	audio := w.body.Bytes()
+	for start := 0; start < len(audio); start += realtimeAudioChunkSize {
+		if ctx.Err() != nil {
+			return nil
+		}
+		end := min(start+realtimeAudioChunkSize, len(audio))
+		onAudio(audio[start:end])
+	}
+
+	return nil
+}
+
+// relayStage 以 WebSocket 连接的用户和令牌身份转发一次内部请求
+func (b *realtimeBridge) relayStage(ctx context.Context, path, contentType string, body []byte, w *bridgeResponseWriter) {
+	requestId := b.c.GetString(logger.RequestIdKey)
+	ctx = context.WithValue(ctx, logger.RequestIdKey, requestId)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.RemoteAddr = b.c.Request.RemoteAddr
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	for _, key := range realtimeBridgeContextKeys {
+		if value, ok := b.c.Get(key); ok {
+			c.Set(key, value)
+		}
+	}
+	c.Set("requestStartTime", time.Now())
+	// 内部请求不经过 Distribute 中间件，需要自行释放占用的渠道并发名额
+	defer relay_util.ReleaseChannelSlots(c)
+
+	Relay(c)
+}
+
+func (b *realtimeBridge) send(event gin.H) {
+	if _, ok := event["event_id"]; !ok {
+		event["event_id"] = "event_" + utils.GetRandomString(20)
+	}
+
+	b.writeLock.Lock()
+	defer b.writeLock.Unlock()
+
+	b.userConn.WriteJSON(event)
+}
+
+func (b *realtimeBridge) sendError(eventId, errType, code, message string) {
+	eventErr := types.NewErrorEvent("", errType, code, message)
+	eventErr.ErrorDetail.EventId = eventId
+
+	b.writeLock.Lock()
+	defer b.writeLock.Unlock()
+
+	b.userConn.WriteMessage(websocket.TextMessage, []byte(eventErr.Error()))
+}
+
+// bridgeResponseWriter 接收内部请求的响应，流式响应按 SSE 的 data 行回调
+type bridgeResponseWriter struct {
+	header  http.Header
+	status  int
+	body    bytes.Buffer
+	pending []byte
+	onData  func(data string)
+}
+
+func newBridgeResponseWriter(onData func(data string)) *bridgeResponseWriter {
+	return &bridgeResponseWriter{
+		header: make(http.Header),
+		onData: onData,
+	}
+}
+
+func (w *bridgeResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bridgeResponseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+func (w *bridgeResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if w.onData == nil || !strings.HasPrefix(w.header.Get("Content-Type"), "text/event-stream") {
+		return w.body.Write(data)
+	}
+
+	w.pending = append(w.pending, data...)
+	for {
+		index := bytes.IndexByte(w.pending, '\n')
+		if index < 0 {
+			break
+		}
+		line := strings.TrimSpace(string(w.pending[:index]))
+		w.pending = w.pending[index+1:]
+
+		if strings.HasPrefix(line, "data:") {
+			w.onData(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+
+	return len(data), nil
+}
+
+func (w *bridgeResponseWriter) Flush() {}
+
+func (w *bridgeResponseWriter) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func (w *bridgeResponseWriter) error() error {
+	if w.status == 0 {
+		return errors.New("empty response")
+	}
+	if w.status == http.StatusOK {
+		return nil
+	}
+
+	return parseBridgeError(w.body.Bytes())
+}
+
+func parseBridgeError(body []byte) error {
+	var response types.OpenAIErrorResponse
+	if err := json.Unmarshal(body, &response); err == nil && response.Error.Message != "" {
+		return errors.New(response.Error.Message)
+	}
+
+	if len(body) > 200 {
+		body = body[:200]
+	}
+	return fmt.Errorf("unexpected response: %s", body)
+}
+
+type wavFormatInfo struct {
+	audioFormat   uint16
+	sampleRate    uint32
+	bitsPerSample uint16
+}
+
+func wavFormat(format string) *wavFormatInfo {
+	switch format {
+	case realtimeAudioFormatPCM16:
+		return &wavFormatInfo{audioFormat: 1, sampleRate: 24000, bitsPerSample: 16}
+	case realtimeAudioFormatALaw:
+		return &wavFormatInfo{audioFormat: 6, sampleRate: 8000, bitsPerSample: 8}
+	case realtimeAudioFormatULaw:
+		return &wavFormatInfo{audioFormat: 7, sampleRate: 8000, bitsPerSample: 8}
+	}
+	return nil
+}
+
+// pcmToWav 为单声道的原始音频加上 WAV 文件头
+func pcmToWav(audio []byte, format string) []byte {
+	info := wavFormat(format)
+	if info == nil {
+		info = wavFormat(realtimeAudioFormatPCM16)
+	}
+
+	blockAlign := info.bitsPerSample / 8
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+len(audio)))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, info.audioFormat)
+	binary.Write(&buf, binary.LittleEndian, uint16(1))
+	binary.Write(&buf, binary.LittleEndian, info.sampleRate)
+	binary.Write(&buf, binary.LittleEndian, info.sampleRate*uint32(blockAlign))
+	binary.Write(&buf, binary.LittleEndian, blockAlign)
+	binary.Write(&buf, binary.LittleEndian, info.bitsPerSample)
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(audio)))
+	buf.Write(audio)
+
+	return buf.Bytes()
+}
@@ -40,6 +40,7 @@ func RelayRecraftAI(c *gin.Context) {
 	requestURL := strings.Replace(c.Request.URL.Path, "/recraftAI", "", 1)
 	response, apiErr := recraftProvider.CreateRelay(requestURL)
 	if apiErr == nil {
+		recordCircuitBreaker(recraftProvider.GetChannel().Id, model, nil)
 		quota.Consume(c, usage, false)
 
 		metrics.RecordProvider(c, 200)
@@ -49,6 +50,7 @@ func RelayRecraftAI(c *gin.Context) {
 	}
 
 	channel := recraftProvider.GetChannel()
+	recordCircuitBreaker(channel.Id, model, apiErr)
 	go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
 
 	retryTimes := config.RetryTimes
@@ -58,7 +60,7 @@ func RelayRecraftAI(c *gin.Context) {
 	}
 
 	for i := retryTimes; i > 0; i-- {
-		shouldCooldowns(c, channel, apiErr)
+		skipChannel(c, channel)
 		if recraftProvider, err = getRecraftProvider(c, model); err != nil {
 			continue
 		}
@@ -68,6 +70,7 @@ func RelayRecraftAI(c *gin.Context) {
 
 		response, apiErr := recraftProvider.CreateRelay(requestURL)
 		if apiErr == nil {
+			recordCircuitBreaker(channel.Id, model, nil)
 			quota.Consume(c, usage, false)
 
 			metrics.RecordProvider(c, 200)
@@ -76,6 +79,7 @@ func RelayRecraftAI(c *gin.Context) {
 			return
 		}
 
+		recordCircuitBreaker(channel.Id, model, apiErr)
 		go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
 		if !shouldRetry(c, apiErr, channel.Type) {
 			break
@@ -0,0 +1,91 @@
+package relay_util
+
+import (
+	"one-api/common/limit"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/metrics"
+	"one-api/model"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const channelSlotsKey = "channel_concurrency_slots"
+
+// 对冲请求会在其他 goroutine 中占用名额，修改上下文中的名额记录时需要加锁
+var channelSlotsLock sync.Mutex
+
+// ChannelQueue 渠道并发用满时的排队队列，key 为 分组:模型
+var ChannelQueue = limit.NewFairQueue(metrics.SetChannelQueueDepth)
+
+// AcquireChannelSlot 占用渠道的并发名额，未限制并发的渠道直接返回 true
+// 占用的名额记录在上下文中，请求结束时由 ReleaseChannelSlots 释放
+func AcquireChannelSlot(c *gin.Context, channel *model.Channel) bool {
+	if channel.MaxConcurrency <= 0 {
+		return true
+	}
+
+	slot, ok, err := limit.GlobalConcurrencyLimiter.Acquire(model.ChannelConcurrencyKey(channel.Id), channel.MaxConcurrency)
+	if err != nil {
+		// 限流器异常时放行
+		logger.SysError("acquire channel concurrency error: " + err.Error())
+		return true
+	}
+	if !ok {
+		return false
+	}
+
+	channelSlotsLock.Lock()
+	slots, _ := utils.GetGinValue[map[int]*limit.ConcurrencySlot](c, channelSlotsKey)
+	if slots == nil {
+		slots = make(map[int]*limit.ConcurrencySlot)
+		c.Set(channelSlotsKey, slots)
+	}
+	// 同一渠道重复占用时释放之前的名额
+	old := slots[channel.Id]
+	slots[channel.Id] = slot
+	channelSlotsLock.Unlock()
+
+	if old != nil {
+		releaseSlot(old)
+	}
+
+	return true
+}
+
+// ReleaseChannelSlot 释放指定渠道的并发名额，用于重试时切换渠道
+func ReleaseChannelSlot(c *gin.Context, channelId int) {
+	channelSlotsLock.Lock()
+	slots, _ := utils.GetGinValue[map[int]*limit.ConcurrencySlot](c, channelSlotsKey)
+	slot := slots[channelId]
+	delete(slots, channelId)
+	channelSlotsLock.Unlock()
+
+	if slot != nil {
+		releaseSlot(slot)
+	}
+}
+
+// ReleaseChannelSlots 释放请求占用的所有并发名额
+func ReleaseChannelSlots(c *gin.Context) {
+	channelSlotsLock.Lock()
+	slots, _ := utils.GetGinValue[map[int]*limit.ConcurrencySlot](c, channelSlotsKey)
+	released := make([]*limit.ConcurrencySlot, 0, len(slots))
+	for channelId, slot := range slots {
+		delete(slots, channelId)
+		released = append(released, slot)
+	}
+	channelSlotsLock.Unlock()
+
+	for _, slot := range released {
+		releaseSlot(slot)
+	}
+}
+
+func releaseSlot(slot *limit.ConcurrencySlot) {
+	if err := limit.GlobalConcurrencyLimiter.Release(slot); err != nil {
+		logger.SysError("release channel concurrency error: " + err.Error())
+	}
+	ChannelQueue.NotifyAll()
+}
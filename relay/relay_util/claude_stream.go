@@ -0,0 +1,233 @@
+package relay_util
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"one-api/common/utils"
+	"one-api/providers/claude"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	claudeBlockNone     = ""
+	claudeBlockText     = claude.ContentTypeText
+	claudeBlockThinking = claude.ContentTypeThinking
+	claudeBlockToolUse  = claude.ContentTypeToolUes
+)
+
+// ClaudeStreamConverter 将 OpenAI Chat 流转换为 Claude Messages 流事件
+type ClaudeStreamConverter struct {
+	c             *gin.Context
+	model         string
+	usage         *types.Usage
+	isStarted     bool
+	blockIndex    int
+	blockType     string
+	toolCallIndex int
+	toolCallId    string
+	hasToolUse    bool
+	finishReason  string
+}
+
+func NewClaudeStreamConverter(c *gin.Context, model string, usage *types.Usage) *ClaudeStreamConverter {
+	return &ClaudeStreamConverter{
+		c:             c,
+		model:         model,
+		usage:         usage,
+		blockIndex:    -1,
+		toolCallIndex: -1,
+	}
+}
+
+func (converter *ClaudeStreamConverter) ProcessStreamData(jsonStr string) {
+	if jsonStr == "[DONE]" {
+		converter.finalizeStream()
+		return
+	}
+
+	var response types.ChatCompletionStreamResponse
+	if err := json.Unmarshal([]byte(jsonStr), &response); err != nil {
+		converter.sendError(fmt.Sprintf("解析JSON失败: %v", err))
+		return
+	}
+
+	if !converter.isStarted {
+		converter.start(response.ID, response.Model)
+	}
+
+	// Claude 只有一个候选结果
+	for _, choice := range response.Choices {
+		if choice.Index != 0 {
+			continue
+		}
+		converter.processChoice(&choice)
+	}
+}
+
+func (converter *ClaudeStreamConverter) ProcessError(jsonStr string) {
+	converter.sendError(jsonStr)
+}
+
+func (converter *ClaudeStreamConverter) start(id, model string) {
+	converter.isStarted = true
+	if model != "" {
+		converter.model = model
+	}
+	if id == "" {
+		id = utils.GetUUID()
+	}
+
+	converter.sendStreamEvent("message_start", map[string]any{
+		"type": "message_start",
+		"message": map[string]any{
+			"id":            "msg_" + id,
+			"type":          "message",
+			"role":          types.ChatMessageRoleAssistant,
+			"content":       []any{},
+			"model":         converter.model,
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage": map[string]any{
+				"input_tokens":  converter.usage.PromptTokens,
+				"output_tokens": 0,
+			},
+		},
+	})
+}
+
+func (converter *ClaudeStreamConverter) processChoice(choice *types.ChatCompletionStreamChoice) {
+	reasoning := choice.Delta.ReasoningContent
+	if reasoning == "" {
+		reasoning = choice.Delta.Reasoning
+	}
+	if reasoning != "" {
+		converter.startBlock(claudeBlockThinking, map[string]any{"type": claudeBlockThinking, "thinking": ""})
+		converter.sendDelta(claude.Delta{Type: claude.ContentStreamTypeThinking, Thinking: reasoning})
+	}
+
+	if choice.Delta.Content != "" {
+		converter.startBlock(claudeBlockText, map[string]any{"type": claudeBlockText, "text": ""})
+		converter.sendDelta(claude.Delta{Type: "text_delta", Text: choice.Delta.Content})
+	}
+
+	for _, toolCall := range choice.Delta.ToolCalls {
+		if toolCall.Function == nil {
+			continue
+		}
+		// 新的工具调用(index 或 id 变化)需要新建 content block
+		if converter.blockType != claudeBlockToolUse || toolCall.Index != converter.toolCallIndex || (toolCall.Id != "" && toolCall.Id != converter.toolCallId) {
+			converter.toolCallIndex = toolCall.Index
+			converter.toolCallId = toolCall.Id
+			converter.hasToolUse = true
+			converter.startNewBlock(claudeBlockToolUse, map[string]any{
+				"type":  claudeBlockToolUse,
+				"id":    claude.ToolUseID(toolCall.Id),
+				"name":  toolCall.Function.Name,
+				"input": map[string]any{},
+			})
+		}
+
+		if toolCall.Function.Arguments != "" {
+			converter.sendDelta(claude.Delta{Type: claude.ContentStreamTypeInputJsonDelta, PartialJson: toolCall.Function.Arguments})
+		}
+	}
+
+	if finishReason, ok := choice.FinishReason.(string); ok && finishReason != "" {
+		converter.finishReason = finishReason
+	}
+}
+
+// startBlock 类型不同时才新建 content block
+func (converter *ClaudeStreamConverter) startBlock(blockType string, contentBlock map[string]any) {
+	if converter.blockType == blockType {
+		return
+	}
+	converter.startNewBlock(blockType, contentBlock)
+}
+
+func (converter *ClaudeStreamConverter) startNewBlock(blockType string, contentBlock map[string]any) {
+	converter.stopBlock()
+
+	converter.blockIndex++
+	converter.blockType = blockType
+	converter.sendStreamEvent("content_block_start", map[string]any{
+		"type":          "content_block_start",
+		"index":         converter.blockIndex,
+		"content_block": contentBlock,
+	})
+}
+
+func (converter *ClaudeStreamConverter) stopBlock() {
+	if converter.blockType == claudeBlockNone {
+		return
+	}
+
+	converter.sendStreamEvent("content_block_stop", map[string]any{
+		"type":  "content_block_stop",
+		"index": converter.blockIndex,
+	})
+	converter.blockType = claudeBlockNone
+}
+
+func (converter *ClaudeStreamConverter) sendDelta(delta claude.Delta) {
+	converter.sendStreamEvent("content_block_delta", map[string]any{
+		"type":  "content_block_delta",
+		"index": converter.blockIndex,
+		"delta": delta,
+	})
+}
+
+// 输出最终的数据
+func (converter *ClaudeStreamConverter) finalizeStream() {
+	if !converter.isStarted {
+		converter.start("", "")
+	}
+
+	converter.stopBlock()
+
+	// 部分渠道返回工具调用时 finish_reason 仍为 stop
+	if converter.hasToolUse && (converter.finishReason == "" || converter.finishReason == types.FinishReasonStop) {
+		converter.finishReason = types.FinishReasonToolCalls
+	}
+
+	outputTokens := converter.usage.CompletionTokens
+	if outputTokens == 0 && converter.usage.TextBuilder.Len() > 0 {
+		outputTokens = common.CountTokenText(converter.usage.TextBuilder.String(), converter.model)
+	}
+
+	converter.sendStreamEvent("message_delta", map[string]any{
+		"type": "message_delta",
+		"delta": map[string]any{
+			"stop_reason":   claude.StopReasonOpenAI2Claude(converter.finishReason),
+			"stop_sequence": nil,
+		},
+		"usage": map[string]any{
+			"output_tokens": outputTokens,
+		},
+	})
+
+	converter.sendStreamEvent("message_stop", map[string]any{
+		"type": "message_stop",
+	})
+}
+
+func (converter *ClaudeStreamConverter) sendStreamEvent(eventType string, data any) {
+	dataStr, _ := json.Marshal(data)
+
+	fmt.Fprintf(converter.c.Writer, "event: %s\ndata: %s\n\n", eventType, string(dataStr))
+	converter.c.Writer.Flush()
+}
+
+// 错误响应
+func (converter *ClaudeStreamConverter) sendError(msg string) {
+	converter.sendStreamEvent("error", map[string]any{
+		"type": "error",
+		"error": map[string]any{
+			"type":    "api_error",
+			"message": msg,
+		},
+	})
+}
@@ -0,0 +1,168 @@
+package relay_util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/requester"
+	"one-api/common/storage"
+	"one-api/model"
+	"one-api/types"
+	"path"
+	"time"
+)
+
+const galleryDownloadTimeout = 5 * time.Minute
+
+var galleryDefaultExt = map[string]string{
+	"image": ".png",
+	"video": ".mp4",
+	"audio": ".mp3",
+}
+
+// ArchiveTaskOutputs 将完成的任务产出加入用户的作品集
+// 开启转存时下载上游文件保存到长期存储，返回与 outputs 一一对应的地址，未转存的为空
+func ArchiveTaskOutputs(userId int, platform string, taskId string, prompt string, outputs []types.TaskOutput) []string {
+	ctx := context.WithValue(context.Background(), logger.RequestIdKey, "Gallery")
+	settings := config.GallerySettingsInstance
+
+	var drive storage.PersistentDrive
+	if settings.RehostEnabled {
+		drive = storage.GetPersistentDrive(settings.RehostDrive)
+		if drive == nil {
+			logger.LogError(ctx, "gallery rehost enabled but no persistent storage configured")
+		}
+	}
+
+	storedUrls := make([]string, len(outputs))
+	items := make([]*model.GalleryItem, 0, len(outputs))
+	for i, output := range outputs {
+		if output.URL == "" {
+			continue
+		}
+
+		item := &model.GalleryItem{
+			UserId:    userId,
+			Platform:  platform,
+			TaskId:    taskId,
+			Type:      output.Type,
+			Prompt:    prompt,
+			URL:       output.URL,
+			SourceURL: output.URL,
+		}
+
+		if drive != nil {
+			key := fmt.Sprintf("gallery/%d/%s/%s_%d%s", userId, platform, taskId, i, galleryFileExt(output))
+			storedUrl, err := rehostFile(output.URL, key, drive, settings.RehostMaxSize)
+			if err != nil {
+				logger.LogError(ctx, fmt.Sprintf("rehost %s task %s output %d error: %s", platform, taskId, i, err.Error()))
+			} else {
+				storedUrls[i] = storedUrl
+				item.URL = storedUrl
+				item.Drive = drive.Name()
+				item.StorageKey = key
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	if err := model.CreateGalleryItems(items); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("create gallery items for %s task %s error: %s", platform, taskId, err.Error()))
+	}
+
+	return storedUrls
+}
+
+// ArchiveMidjourneyTask 将完成的 Midjourney 图片加入作品集，转存成功时记录转存地址
+func ArchiveMidjourneyTask(task *model.Midjourney) {
+	if task.ImageUrl == "" {
+		return
+	}
+
+	storedUrls := ArchiveTaskOutputs(task.UserId, model.TaskRefundPlatformMidjourney, task.MjId, task.Prompt, []types.TaskOutput{
+		{Type: "image", URL: task.ImageUrl},
+	})
+	if storedUrls[0] == "" {
+		return
+	}
+
+	task.StoredImageUrl = storedUrls[0]
+	if err := model.UpdateMidjourneyStoredImageUrl(task.Id, task.StoredImageUrl); err != nil {
+		logger.SysError("update midjourney stored image url error: " + err.Error())
+	}
+}
+
+// DeleteGalleryItem 删除作品，已转存的文件一并删除
+// 删除文件前先清除任务中记录的转存地址，任务查询接口回退到上游地址
+func DeleteGalleryItem(item *model.GalleryItem) error {
+	if item.Drive != "" && item.StorageKey != "" {
+		var err error
+		if item.Platform == model.TaskRefundPlatformMidjourney {
+			err = model.ClearMidjourneyStoredImageUrl(item.UserId, item.TaskId, item.URL)
+		} else {
+			err = model.ClearTaskStoredUrl(item.Platform, item.UserId, item.TaskId, item.URL)
+		}
+		if err != nil {
+			return err
+		}
+
+		if drive := storage.GetPersistentDrive(item.Drive); drive != nil {
+			if err := drive.Delete(item.StorageKey); err != nil {
+				logger.SysError("delete gallery file error: " + err.Error())
+			}
+		}
+	}
+
+	return item.Delete()
+}
+
+func rehostFile(fileUrl string, key string, drive storage.PersistentDrive, maxSize int) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), galleryDownloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileUrl, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := requester.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download status code %d", resp.StatusCode)
+	}
+
+	var body io.Reader = resp.Body
+	limit := int64(maxSize) * 1024 * 1024
+	if limit > 0 {
+		body = io.LimitReader(resp.Body, limit+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	if limit > 0 && int64(len(data)) > limit {
+		return "", errors.New("file too large")
+	}
+
+	return drive.Save(key, data)
+}
+
+func galleryFileExt(output types.TaskOutput) string {
+	if u, err := url.Parse(output.URL); err == nil {
+		if ext := path.Ext(u.Path); ext != "" && len(ext) <= 5 {
+			return ext
+		}
+	}
+
+	return galleryDefaultExt[output.Type]
+}
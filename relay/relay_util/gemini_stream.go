@@ -0,0 +1,202 @@
+package relay_util
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"one-api/providers/gemini"
+	"one-api/types"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GeminiStreamConverter 将 OpenAI Chat 流转换为 Gemini streamGenerateContent(alt=sse) 流
+// Gemini 的函数调用是一次性返回的，所以工具调用的参数需要拼接完整后在结束时输出
+type GeminiStreamConverter struct {
+	c             *gin.Context
+	model         string
+	responseId    string
+	usage         *types.Usage
+	toolCalls     map[int]*types.ChatCompletionToolCallsFunction
+	finishReasons map[int]string
+}
+
+func NewGeminiStreamConverter(c *gin.Context, model string, usage *types.Usage) *GeminiStreamConverter {
+	return &GeminiStreamConverter{
+		c:             c,
+		model:         model,
+		usage:         usage,
+		toolCalls:     make(map[int]*types.ChatCompletionToolCallsFunction),
+		finishReasons: make(map[int]string),
+	}
+}
+
+func (converter *GeminiStreamConverter) ProcessStreamData(jsonStr string) {
+	if jsonStr == "[DONE]" {
+		converter.finalizeStream()
+		return
+	}
+
+	var response types.ChatCompletionStreamResponse
+	if err := json.Unmarshal([]byte(jsonStr), &response); err != nil {
+		converter.sendError(fmt.Sprintf("解析JSON失败: %v", err))
+		return
+	}
+
+	if converter.responseId == "" {
+		converter.responseId = response.ID
+	}
+	if response.Model != "" {
+		converter.model = response.Model
+	}
+
+	candidates := make([]gemini.GeminiChatCandidate, 0, len(response.Choices))
+	for _, choice := range response.Choices {
+		if finishReason, ok := choice.FinishReason.(string); ok && finishReason != "" {
+			converter.finishReasons[choice.Index] = finishReason
+		}
+
+		parts := converter.processChoice(&choice)
+		if len(parts) == 0 {
+			continue
+		}
+
+		candidates = append(candidates, gemini.GeminiChatCandidate{
+			Content: gemini.GeminiChatContent{
+				Role:  "model",
+				Parts: parts,
+			},
+			Index: int64(choice.Index),
+		})
+	}
+
+	if len(candidates) > 0 {
+		converter.sendStreamData(&gemini.GeminiChatResponse{
+			Candidates:   candidates,
+			ModelVersion: converter.model,
+			ResponseId:   converter.responseId,
+		})
+	}
+}
+
+func (converter *GeminiStreamConverter) ProcessError(jsonStr string) {
+	converter.sendError(jsonStr)
+}
+
+func (converter *GeminiStreamConverter) processChoice(choice *types.ChatCompletionStreamChoice) []gemini.GeminiPart {
+	parts := make([]gemini.GeminiPart, 0)
+
+	reasoning := choice.Delta.ReasoningContent
+	if reasoning == "" {
+		reasoning = choice.Delta.Reasoning
+	}
+	if reasoning != "" {
+		parts = append(parts, gemini.GeminiPart{Text: reasoning, Thought: true})
+	}
+
+	if choice.Delta.Content != "" {
+		parts = append(parts, gemini.GeminiPart{Text: choice.Delta.Content})
+	}
+
+	// 多个候选结果时，工具调用按 候选序号*1000+调用序号 区分
+	for _, toolCall := range choice.Delta.ToolCalls {
+		if toolCall.Function == nil {
+			continue
+		}
+		key := choice.Index*1000 + toolCall.Index
+		function, ok := converter.toolCalls[key]
+		if !ok {
+			function = &types.ChatCompletionToolCallsFunction{}
+			converter.toolCalls[key] = function
+		}
+		if toolCall.Function.Name != "" {
+			function.Name = toolCall.Function.Name
+		}
+		function.Arguments += toolCall.Function.Arguments
+	}
+
+	return parts
+}
+
+// 输出最终的数据，包含完整的函数调用、结束原因和用量
+func (converter *GeminiStreamConverter) finalizeStream() {
+	keys := make([]int, 0, len(converter.toolCalls))
+	for key := range converter.toolCalls {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+
+	toolParts := make(map[int][]gemini.GeminiPart)
+	for _, key := range keys {
+		index := key / 1000
+		toolParts[index] = append(toolParts[index], gemini.GeminiPart{
+			FunctionCall: gemini.ToGeminiFunctionCall(converter.toolCalls[key]),
+		})
+	}
+
+	indexes := make(map[int]bool)
+	for index := range converter.finishReasons {
+		indexes[index] = true
+	}
+	for index := range toolParts {
+		indexes[index] = true
+	}
+	if len(indexes) == 0 {
+		indexes[0] = true
+	}
+
+	sortedIndexes := make([]int, 0, len(indexes))
+	for index := range indexes {
+		sortedIndexes = append(sortedIndexes, index)
+	}
+	sort.Ints(sortedIndexes)
+
+	candidates := make([]gemini.GeminiChatCandidate, 0, len(sortedIndexes))
+	for _, index := range sortedIndexes {
+		finishReason := gemini.FinishReasonOpenAI2Gemini(converter.finishReasons[index])
+		parts := toolParts[index]
+		if parts == nil {
+			parts = []gemini.GeminiPart{{Text: ""}}
+		}
+		candidates = append(candidates, gemini.GeminiChatCandidate{
+			Content: gemini.GeminiChatContent{
+				Role:  "model",
+				Parts: parts,
+			},
+			FinishReason: &finishReason,
+			Index:        int64(index),
+		})
+	}
+
+	usageMetadata := gemini.OpenaiUsageToGeminiUsage(converter.usage)
+	if converter.usage.CompletionTokens == 0 && converter.usage.TextBuilder.Len() > 0 {
+		usageMetadata.CandidatesTokenCount = common.CountTokenText(converter.usage.TextBuilder.String(), converter.model)
+		usageMetadata.TotalTokenCount = usageMetadata.PromptTokenCount + usageMetadata.CandidatesTokenCount
+	}
+
+	converter.sendStreamData(&gemini.GeminiChatResponse{
+		Candidates:    candidates,
+		UsageMetadata: usageMetadata,
+		ModelVersion:  converter.model,
+		ResponseId:    converter.responseId,
+	})
+}
+
+func (converter *GeminiStreamConverter) sendStreamData(data any) {
+	dataStr, _ := json.Marshal(data)
+
+	fmt.Fprintf(converter.c.Writer, "data: %s\n\n", string(dataStr))
+	converter.c.Writer.Flush()
+}
+
+// 错误响应
+func (converter *GeminiStreamConverter) sendError(msg string) {
+	converter.sendStreamData(&gemini.GeminiErrorResponse{
+		ErrorInfo: &gemini.GeminiError{
+			Code:    500,
+			Status:  "INTERNAL",
+			Message: msg,
+		},
+	})
+}
@@ -0,0 +1,247 @@
+package relay_util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/types"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CapturePayload 记录的请求和响应内容，流式响应保存拼接后的文本
+type CapturePayload struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	ContentType string `json:"content_type"`
+	Request     string `json:"request"`
+	Response    string `json:"response"`
+	Error       string `json:"error,omitempty"`
+}
+
+type PayloadCapture struct {
+	c         *gin.Context
+	modelName string
+	isStream  bool
+	writer    *responseCaptureWriter
+}
+
+// NewPayloadCapture 令牌或用户开启了记录时返回记录器，否则返回 nil
+func NewPayloadCapture(c *gin.Context, modelName string, isStream bool) *PayloadCapture {
+	if !config.PayloadCaptureSettingsInstance.Enabled || !isPayloadCaptureEnabled(c) {
+		return nil
+	}
+
+	return &PayloadCapture{
+		c:         c,
+		modelName: modelName,
+		isStream:  isStream,
+	}
+}
+
+func isPayloadCaptureEnabled(c *gin.Context) bool {
+	if config.PayloadCaptureSettingsInstance.IsCaptureUser(c.GetInt("id")) {
+		return true
+	}
+
+	if setting, ok := utils.GetGinValue[*model.TokenSetting](c, "token_setting"); ok && setting != nil {
+		return setting.Capture.Enabled
+	}
+
+	return false
+}
+
+// Capture 非流式请求记录写入客户端的响应内容，流式请求使用 usage 中拼接的文本
+func (pc *PayloadCapture) Capture() {
+	if pc.isStream {
+		return
+	}
+
+	pc.writer = &responseCaptureWriter{ResponseWriter: pc.c.Writer}
+	pc.c.Writer = pc.writer
+}
+
+// Store 恢复原始的 writer，并异步保存本次请求
+func (pc *PayloadCapture) Store(channelId int, usage *types.Usage, apiErr *types.OpenAIErrorWithStatusCode) {
+	payload := &CapturePayload{
+		Method:      pc.c.Request.Method,
+		Path:        pc.c.Request.URL.Path,
+		ContentType: pc.c.GetHeader("Content-Type"),
+	}
+
+	if requestBody, ok := utils.GetGinValue[[]byte](pc.c, config.GinRequestBodyKey); ok {
+		payload.Request = capturePayloadText(requestBody)
+	}
+
+	statusCode := 200
+	if pc.writer != nil {
+		pc.c.Writer = pc.writer.ResponseWriter
+		statusCode = pc.writer.Status()
+		payload.Response = capturePayloadText(pc.writer.body.Bytes())
+		pc.writer = nil
+	} else if usage != nil {
+		payload.Response = capturePayloadText([]byte(usage.TextBuilder.String()))
+	}
+
+	if apiErr != nil {
+		statusCode = apiErr.StatusCode
+		payload.Error = config.PayloadCaptureSettingsInstance.Redact(apiErr.Message)
+	}
+
+	record := &model.PayloadCapture{
+		UserId:     pc.c.GetInt("id"),
+		TokenId:    pc.c.GetInt("token_id"),
+		TokenName:  pc.c.GetString("token_name"),
+		ChannelId:  channelId,
+		Model:      pc.modelName,
+		Path:       payload.Path,
+		IsStream:   pc.isStream,
+		StatusCode: statusCode,
+		RequestId:  pc.c.GetString(logger.RequestIdKey),
+	}
+	if usage != nil {
+		record.PromptTokens = usage.PromptTokens
+		record.CompletionTokens = usage.CompletionTokens
+	}
+
+	go func() {
+		if err := savePayloadCapture(record, payload); err != nil {
+			logger.SysError("save payload capture error: " + err.Error())
+		}
+	}()
+}
+
+// capturePayloadText 转换为脱敏后的文本，超过长度限制时截断
+func capturePayloadText(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	if !utf8.Valid(data) {
+		return fmt.Sprintf("[binary %d bytes]", len(data))
+	}
+
+	text := config.PayloadCaptureSettingsInstance.Redact(string(data))
+	maxSize := config.PayloadCaptureSettingsInstance.MaxBodySize * 1024
+	if maxSize > 0 && len(text) > maxSize {
+		text = strings.ToValidUTF8(text[:maxSize], "") + "...[truncated]"
+	}
+
+	return text
+}
+
+func savePayloadCapture(record *model.PayloadCapture, payload *CapturePayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	record.Sink = config.PayloadCaptureSettingsInstance.Sink
+	if record.Sink == "" || record.Sink == config.PayloadCaptureSinkDB {
+		record.Sink = config.PayloadCaptureSinkDB
+		record.Payload = data
+		return record.Insert()
+	}
+
+	sink, err := getPayloadCaptureSink(record.Sink)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s/%d_%s.json", time.Now().Format("2006-01-02"), record.UserId, utils.GetRandomString(16))
+	record.Location, err = sink.Save(key, data)
+	if err != nil {
+		return err
+	}
+
+	return record.Insert()
+}
+
+// LoadPayloadCapture 读取记录的请求和响应内容
+func LoadPayloadCapture(record *model.PayloadCapture) (*CapturePayload, error) {
+	var data []byte
+	if record.Sink == config.PayloadCaptureSinkDB {
+		data = record.Payload
+	} else {
+		sink, err := getPayloadCaptureSink(record.Sink)
+		if err != nil {
+			return nil, err
+		}
+		data, err = sink.Load(record.Location)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(data) == 0 {
+		return nil, errors.New("记录内容为空")
+	}
+
+	payload := &CapturePayload{}
+	if err := json.Unmarshal(data, payload); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// DeletePayloadCapture 删除记录及保存在外部的内容
+func DeletePayloadCapture(record *model.PayloadCapture) error {
+	if err := deletePayloadCaptureContent(record); err != nil {
+		return err
+	}
+
+	return record.Delete()
+}
+
+func deletePayloadCaptureContent(record *model.PayloadCapture) error {
+	if record.Sink == config.PayloadCaptureSinkDB || record.Location == "" {
+		return nil
+	}
+
+	sink, err := getPayloadCaptureSink(record.Sink)
+	if err != nil {
+		return err
+	}
+
+	return sink.Delete(record.Location)
+}
+
+// CleanExpiredPayloadCaptures 删除超过保留天数的记录
+func CleanExpiredPayloadCaptures() (int, error) {
+	retentionDays := config.PayloadCaptureSettingsInstance.RetentionDays
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+
+	timestamp := time.Now().AddDate(0, 0, -retentionDays).Unix()
+	total := 0
+	for {
+		records, err := model.GetExpiredPayloadCaptures(timestamp, 500)
+		if err != nil {
+			return total, err
+		}
+		if len(records) == 0 {
+			return total, nil
+		}
+
+		ids := make([]int, 0, len(records))
+		for _, record := range records {
+			if err := deletePayloadCaptureContent(record); err != nil {
+				logger.SysError(fmt.Sprintf("delete payload capture #%d content error: %s", record.Id, err.Error()))
+			}
+			ids = append(ids, record.Id)
+		}
+
+		if err := model.DeletePayloadCapturesByIds(ids); err != nil {
+			return total, err
+		}
+		total += len(ids)
+	}
+}
@@ -0,0 +1,83 @@
+package relay_util
+
+import (
+	"errors"
+	"one-api/common/config"
+	"one-api/common/storage"
+	"one-api/common/storage/drives"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// payloadCaptureSink 保存在数据库之外的记录内容
+type payloadCaptureSink interface {
+	Save(key string, data []byte) (string, error)
+	Load(location string) ([]byte, error)
+	Delete(location string) error
+}
+
+func getPayloadCaptureSink(name string) (payloadCaptureSink, error) {
+	switch name {
+	case config.PayloadCaptureSinkFile:
+		return &fileCaptureSink{dir: viper.GetString("payload_capture.file_dir")}, nil
+	case config.PayloadCaptureSinkS3:
+		drive := storage.GetS3Drive()
+		if drive == nil {
+			return nil, errors.New("未配置 S3 存储")
+		}
+		return &s3CaptureSink{drive: drive}, nil
+	}
+
+	return nil, errors.New("不支持的保存位置: " + name)
+}
+
+type fileCaptureSink struct {
+	dir string
+}
+
+func (s *fileCaptureSink) Save(key string, data []byte) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0o640); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+func (s *fileCaptureSink) Load(location string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(location)))
+}
+
+func (s *fileCaptureSink) Delete(location string) error {
+	err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(location)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+type s3CaptureSink struct {
+	drive *drives.S3Upload
+}
+
+func (s *s3CaptureSink) Save(key string, data []byte) (string, error) {
+	key = "payload_capture/" + key
+	if err := s.drive.PutObject(key, data); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+func (s *s3CaptureSink) Load(location string) ([]byte, error) {
+	return s.drive.GetObject(location)
+}
+
+func (s *s3CaptureSink) Delete(location string) error {
+	return s.drive.DeleteObject(location)
+}
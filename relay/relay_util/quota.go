@@ -8,11 +8,14 @@ import (
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/logger"
+	"one-api/metrics"
 	"one-api/model"
+	"one-api/tracing"
 	"one-api/types"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Quota struct {
@@ -35,6 +38,17 @@ type Quota struct {
 	startTime         time.Time
 	firstResponseTime time.Time
 	extraBillingData  map[string]ExtraBillingData
+
+	cacheHit      bool
+	cacheHitRatio float64
+
+	isBatch    bool
+	batchRatio float64
+
+	limits    *TokenLimits
+	tpmLimits *TPMLimits
+
+	metricLabels metrics.RelayLabels
 }
 
 func NewQuota(c *gin.Context, modelName string, promptTokens int) *Quota {
@@ -48,6 +62,7 @@ func NewQuota(c *gin.Context, modelName string, promptTokens int) *Quota {
 		tokenId:       c.GetInt("token_id"),
 		HandelStatus:  false,
 		isBackupGroup: isBackupGroup, // 记录是否使用备用分组
+		metricLabels:  metrics.GetRelayLabels(c),
 	}
 
 	quota.price = *model.PricingInstance.GetPrice(quota.modelName)
@@ -57,6 +72,15 @@ func NewQuota(c *gin.Context, modelName string, promptTokens int) *Quota {
 	quota.inputRatio = quota.price.GetInput() * quota.groupRatio
 	quota.outputRatio = quota.price.GetOutput() * quota.groupRatio
 
+	quota.limits = NewTokenLimits(c, quota.tokenId, modelName)
+	quota.tpmLimits = NewTPMLimits(c, quota.userId, quota.tokenId, quota.channelId)
+
+	// 批处理中的请求按批处理折扣计费
+	if c.GetBool("is_batch") {
+		quota.isBatch = true
+		quota.batchRatio = config.BatchSettingsInstance.DiscountRatio
+	}
+
 	return quota
 
 }
@@ -68,6 +92,37 @@ func (q *Quota) PreQuotaConsumption() *types.OpenAIErrorWithStatusCode {
 		q.preConsumedQuota = int(float64(q.promptTokens)*q.inputRatio) + config.PreConsumedQuota
 	}
 
+	if q.limits != nil {
+		if err := q.limits.Reserve(q.preConsumedQuota); err != nil {
+			return err
+		}
+	}
+
+	if q.tpmLimits != nil {
+		if err := q.tpmLimits.Reserve(q.promptTokens); err != nil {
+			q.releaseLimits()
+			return err
+		}
+	}
+
+	if err := q.preConsumeUserQuota(); err != nil {
+		q.releaseLimits()
+		return err
+	}
+
+	return nil
+}
+
+func (q *Quota) releaseLimits() {
+	if q.limits != nil {
+		q.limits.Release()
+	}
+	if q.tpmLimits != nil {
+		q.tpmLimits.Release()
+	}
+}
+
+func (q *Quota) preConsumeUserQuota() *types.OpenAIErrorWithStatusCode {
 	if q.preConsumedQuota == 0 {
 		return nil
 	}
@@ -142,8 +197,22 @@ func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string,
 	}()
 
 	quota := q.GetTotalQuotaByUsage(usage)
+	if q.cacheHit {
+		quota = int(math.Ceil(float64(quota) * q.cacheHitRatio))
+	}
+	if q.isBatch {
+		quota = int(math.Ceil(float64(quota) * q.batchRatio))
+	}
 
-	if quota > 0 {
+	if q.limits != nil {
+		q.limits.Record(quota)
+	}
+	if q.tpmLimits != nil {
+		q.tpmLimits.Record(usage.PromptTokens + usage.CompletionTokens)
+	}
+
+	// 最终额度为 0 时(如免费的缓存命中)也需要退还预扣的额度
+	if quota > 0 || q.preConsumedQuota > 0 {
 		quotaDelta := quota - q.preConsumedQuota
 		err := model.PostConsumeTokenQuota(q.tokenId, quotaDelta)
 		if err != nil {
@@ -153,6 +222,8 @@ func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string,
 		if err != nil {
 			return errors.New("error consuming token remain quota: " + err.Error())
 		}
+	}
+	if quota > 0 {
 		model.UpdateChannelUsedQuota(q.channelId, quota)
 	}
 
@@ -173,10 +244,18 @@ func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string,
 	)
 	model.UpdateUserUsedQuotaAndRequestCount(q.userId, quota)
 
+	cachedTokens := usage.PromptTokensDetails.CachedTokens
+	if cachedTokens == 0 {
+		cachedTokens = usage.PromptTokensDetails.CachedReadTokens
+	}
+	metrics.RecordRelayUsage(q.metricLabels, usage.PromptTokens, usage.CompletionTokens, cachedTokens, usage.CompletionTokensDetails.ReasoningTokens, quota)
+
 	return nil
 }
 
 func (q *Quota) Undo(c *gin.Context) {
+	q.releaseLimits()
+
 	tokenId := c.GetInt("token_id")
 	if q.HandelStatus {
 		go func(ctx context.Context) {
@@ -194,13 +273,35 @@ func (q *Quota) Consume(c *gin.Context, usage *types.Usage, isStream bool) {
 	q.startTime = c.GetTime("requestStartTime")
 	// 如果没有报错，则消费配额
 	go func(ctx context.Context) {
+		ctx, span := tracing.Start(ctx, "relay.quota_consume",
+			attribute.String("model", q.modelName),
+			attribute.Int("channel_id", q.channelId),
+			attribute.Int("token_id", q.tokenId),
+		)
 		err := q.completedQuotaConsumption(usage, tokenName, isStream, c.ClientIP(), ctx)
+		tracing.End(span, err)
 		if err != nil {
 			logger.LogError(ctx, err.Error())
 		}
 	}(c.Request.Context())
 }
 
+// SetCacheHit 响应来自缓存，按缓存计费倍率计费
+func (q *Quota) SetCacheHit(ratio float64) {
+	q.cacheHit = true
+	q.cacheHitRatio = ratio
+	q.channelId = 0
+	q.metricLabels.ChannelId = 0
+	q.metricLabels.ChannelType = 0
+}
+
+// SetChannel 实际完成请求的渠道与预扣费时不同时(如流式对冲)，按实际渠道记录
+func (q *Quota) SetChannel(channel *model.Channel) {
+	q.channelId = channel.Id
+	q.metricLabels.ChannelId = channel.Id
+	q.metricLabels.ChannelType = channel.Type
+}
+
 func (q *Quota) GetInputRatio() float64 {
 	return q.inputRatio
 }
@@ -235,6 +336,16 @@ func (q *Quota) GetLogMeta(usage *types.Usage) map[string]any {
 		meta["extra_billing"] = q.extraBillingData
 	}
 
+	if q.cacheHit {
+		meta["cache_hit"] = true
+		meta["cache_hit_ratio"] = q.cacheHitRatio
+	}
+
+	if q.isBatch {
+		meta["batch"] = true
+		meta["batch_ratio"] = q.batchRatio
+	}
+
 	return meta
 }
 
@@ -0,0 +1,62 @@
+package relay_util
+
+import (
+	"context"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupQuotaDB(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	config.LogConsumeEnabled = false
+	config.BatchUpdateEnabled = false
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&model.User{}, &model.Token{}))
+
+	originDB := model.DB
+	model.DB = db
+	t.Cleanup(func() {
+		model.DB = originDB
+	})
+}
+
+func TestCompletedQuotaConsumptionFreeCacheHit(t *testing.T) {
+	setupQuotaDB(t)
+
+	user := &model.User{Username: "test", Quota: 100000}
+	assert.NoError(t, model.DB.Create(user).Error)
+	token := &model.Token{UserId: user.Id, Key: "test", RemainQuota: 100000}
+	assert.NoError(t, model.DB.Session(&gorm.Session{SkipHooks: true}).Create(token).Error)
+
+	q := &Quota{
+		userId:           user.Id,
+		tokenId:          token.Id,
+		inputRatio:       1,
+		outputRatio:      1,
+		preConsumedQuota: 100,
+	}
+	assert.NoError(t, model.PreConsumeTokenQuota(token.Id, q.preConsumedQuota))
+	q.SetCacheHit(0)
+
+	usage := &types.Usage{PromptTokens: 10, CompletionTokens: 10, TotalTokens: 20}
+	assert.NoError(t, q.completedQuotaConsumption(usage, "test", false, "", context.Background()))
+
+	// 免费命中后预扣的额度全部退还
+	userQuota, err := model.GetUserQuota(user.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 100000, userQuota)
+
+	token, err = model.GetTokenById(token.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 100000, token.RemainQuota)
+}
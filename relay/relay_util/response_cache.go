@@ -0,0 +1,206 @@
+package relay_util
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"one-api/common/cache"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	responseCacheKeyPrefix = "response_cache:"
+	// 请求头 X-OneHub-Cache: bypass 或 Cache-Control: no-cache/no-store 时跳过缓存
+	ResponseCacheHeader       = "X-OneHub-Cache"
+	ResponseCacheHeaderBypass = "bypass"
+	ResponseCacheStatusHeader = "X-OneHub-Cache-Status"
+)
+
+var (
+	responseCacheStore     cache.BytesStore
+	responseCacheStoreOnce sync.Once
+)
+
+func getResponseCacheStore() cache.BytesStore {
+	responseCacheStoreOnce.Do(func() {
+		responseCacheStore = cache.NewBytesStore(viper.GetInt("response_cache.memory_size"))
+	})
+	return responseCacheStore
+}
+
+type ResponseCacheEntry struct {
+	Body             string `json:"body"`
+	IsStream         bool   `json:"is_stream"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	CreatedAt        int64  `json:"created_at"`
+}
+
+type ResponseCache struct {
+	c        *gin.Context
+	key      string
+	ttl      time.Duration
+	isStream bool
+	writer   *responseCaptureWriter
+}
+
+// NewResponseCache 只有确定性的请求(如 temperature 为 0 的对话、向量)才会缓存，不可缓存时返回 nil
+func NewResponseCache(c *gin.Context, modelName string, request any, isStream bool) *ResponseCache {
+	settings := &config.ResponseCacheSettingsInstance
+	if !settings.Enabled || isResponseCacheBypassed(c) {
+		return nil
+	}
+
+	ttl := settings.GetTTL(modelName)
+	if ttl <= 0 {
+		return nil
+	}
+
+	normalized := normalizeCacheRequest(modelName, request)
+	if normalized == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return nil
+	}
+
+	hash := sha256.Sum256(data)
+	return &ResponseCache{
+		c:        c,
+		key:      fmt.Sprintf("%s%d:%t:%s", responseCacheKeyPrefix, c.GetInt("id"), isStream, hex.EncodeToString(hash[:])),
+		ttl:      time.Duration(ttl) * time.Second,
+		isStream: isStream,
+	}
+}
+
+func isResponseCacheBypassed(c *gin.Context) bool {
+	if strings.EqualFold(c.GetHeader(ResponseCacheHeader), ResponseCacheHeaderBypass) {
+		return true
+	}
+
+	cacheControl := strings.ToLower(c.GetHeader("Cache-Control"))
+	if strings.Contains(cacheControl, "no-cache") || strings.Contains(cacheControl, "no-store") {
+		return true
+	}
+
+	if setting, ok := utils.GetGinValue[*model.TokenSetting](c, "token_setting"); ok && setting != nil {
+		return setting.ResponseCache.Disabled
+	}
+
+	return false
+}
+
+// normalizeCacheRequest 生成用于计算缓存 key 的请求，去掉不影响结果的字段
+func normalizeCacheRequest(modelName string, request any) any {
+	switch req := request.(type) {
+	case *types.ChatCompletionRequest:
+		if req.Temperature == nil || *req.Temperature != 0 || (req.N != nil && *req.N > 1) || req.OneOtherArg != "" {
+			return nil
+		}
+		normalized := *req
+		normalized.Model = modelName
+		normalized.User = ""
+		normalized.Stream = false
+		return normalized
+	case *types.EmbeddingRequest:
+		normalized := *req
+		normalized.Model = modelName
+		normalized.User = ""
+		return normalized
+	}
+
+	return nil
+}
+
+func (rc *ResponseCache) Get() *ResponseCacheEntry {
+	data, err := getResponseCacheStore().Get(rc.key)
+	if err != nil {
+		return nil
+	}
+
+	var entry ResponseCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+
+	return &entry
+}
+
+// Capture 记录写入客户端的响应内容，在请求完成后调用 Store 保存
+func (rc *ResponseCache) Capture() {
+	rc.c.Header(ResponseCacheStatusHeader, "MISS")
+	rc.writer = &responseCaptureWriter{ResponseWriter: rc.c.Writer}
+	rc.c.Writer = rc.writer
+}
+
+func (rc *ResponseCache) Store(usage *types.Usage) {
+	if rc.writer == nil {
+		return
+	}
+
+	rc.c.Writer = rc.writer.ResponseWriter
+	body := rc.writer.body.Bytes()
+
+	if rc.writer.Status() != 200 || len(body) == 0 {
+		return
+	}
+
+	// 流式响应必须完整结束才缓存
+	if rc.isStream && !bytes.HasSuffix(bytes.TrimSpace(body), []byte("data: [DONE]")) {
+		return
+	}
+
+	entry := ResponseCacheEntry{
+		Body:             string(body),
+		IsStream:         rc.isStream,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CreatedAt:        utils.GetTimestamp(),
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	if err := getResponseCacheStore().Set(rc.key, data, rc.ttl); err != nil {
+		logger.LogError(rc.c.Request.Context(), "failed to store response cache: "+err.Error())
+	}
+}
+
+// Release 请求失败时恢复原始的 writer
+func (rc *ResponseCache) Release() {
+	if rc.writer != nil {
+		rc.c.Writer = rc.writer.ResponseWriter
+		rc.writer = nil
+	}
+}
+
+type responseCaptureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseCaptureWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseCaptureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
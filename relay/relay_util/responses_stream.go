@@ -396,10 +396,19 @@ func (converter *OpenAIResponsesStreamConverter) finalizeStream() {
 	response.Response.Status = converter.nowStatus
 
 	response.Response.Usage = converter.usage.ToResponsesUsage()
+	converter.isCompleted = true
 
 	converter.sendStreamEvent(response, respType)
 }
 
+// GetResponses 返回流结束后完整的响应，流未正常结束时返回 nil
+func (converter *OpenAIResponsesStreamConverter) GetResponses() *types.OpenAIResponsesResponses {
+	if !converter.isCompleted {
+		return nil
+	}
+	return converter.responses
+}
+
 // 获取响应流字符串
 func (converter *OpenAIResponsesStreamConverter) sendStreamEvent(resp any, responseType string) {
 	respStr, _ := json.Marshal(resp)
@@ -0,0 +1,197 @@
+package relay_util
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/limit"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 同一个令牌的计数器使用相同的 hash tag，保证可以在同一个 Lua 脚本中处理
+const tokenLimitsKeyPrefix = "{token_limits:%d}"
+
+// TokenLimits 令牌级别的消费上限和 RPM 限制（TPM 由 TPMLimits 处理）
+// 预扣费时原子地检查所有上限并按预估额度占用，请求失败时撤销，完成后按实际消耗修正
+type TokenLimits struct {
+	tokenId   int
+	modelName string
+	budget    *model.LimitsBudgetSetting
+	rate      *model.LimitsRateSetting
+
+	reserved []*limit.RollingCounter
+}
+
+func NewTokenLimits(c *gin.Context, tokenId int, modelName string) *TokenLimits {
+	setting, ok := c.Get("token_setting")
+	if !ok {
+		return nil
+	}
+	tokenSetting, ok := setting.(*model.TokenSetting)
+	if !ok || tokenSetting == nil {
+		return nil
+	}
+
+	limits := &TokenLimits{
+		tokenId:   tokenId,
+		modelName: modelName,
+	}
+	if tokenSetting.Limits.LimitsBudgetSetting.Enabled {
+		limits.budget = &tokenSetting.Limits.LimitsBudgetSetting
+	}
+	if tokenSetting.Limits.LimitsRateSetting.Enabled && tokenSetting.Limits.LimitsRateSetting.RPM > 0 {
+		limits.rate = &tokenSetting.Limits.LimitsRateSetting
+	}
+
+	if limits.budget == nil && limits.rate == nil {
+		return nil
+	}
+
+	return limits
+}
+
+type limitCounter struct {
+	counter *limit.RollingCounter
+	budget  bool // 是否为消费上限，RPM 按请求数占用
+	code    string
+	message string
+}
+
+func (l *TokenLimits) counters(estimateQuota int) []limitCounter {
+	prefix := fmt.Sprintf(tokenLimitsKeyPrefix, l.tokenId)
+	counters := make([]limitCounter, 0, 5)
+
+	if l.budget != nil {
+		periods := []struct {
+			period string
+			quota  int
+			code   string
+			name   string
+		}{
+			{limit.PeriodDay, l.budget.DailyQuota, "token_daily_budget_exceeded", "daily"},
+			{limit.PeriodWeek, l.budget.WeeklyQuota, "token_weekly_budget_exceeded", "weekly"},
+			{limit.PeriodMonth, l.budget.MonthlyQuota, "token_monthly_budget_exceeded", "monthly"},
+		}
+		// 未设置上限的周期也记录消耗，修改设置后立即生效
+		for _, period := range periods {
+			counters = append(counters, limitCounter{
+				counter: &limit.RollingCounter{
+					Key:    prefix + ":budget:" + period.period,
+					Window: limit.PeriodWindow(period.period),
+					Limit:  int64(max(period.quota, 0)),
+					Value:  int64(estimateQuota),
+				},
+				budget:  true,
+				code:    period.code,
+				message: fmt.Sprintf("token %s budget exceeded", period.name),
+			})
+		}
+
+		if _, ok := l.budget.ModelQuotas[l.modelName]; ok {
+			counters = append(counters, limitCounter{
+				counter: &limit.RollingCounter{
+					Key:    prefix + ":budget:model:" + l.modelName,
+					Window: limit.PeriodWindow(l.modelPeriod()),
+					Limit:  int64(max(l.budget.ModelQuotas[l.modelName], 0)),
+					Value:  int64(estimateQuota),
+				},
+				budget:  true,
+				code:    "token_model_budget_exceeded",
+				message: fmt.Sprintf("token budget for model %s exceeded", l.modelName),
+			})
+		}
+	}
+
+	if l.rate != nil {
+		counters = append(counters, limitCounter{
+			counter: &limit.RollingCounter{
+				Key:    prefix + ":rpm",
+				Window: limit.PeriodWindow(limit.PeriodMinute),
+				Limit:  int64(l.rate.RPM),
+				Value:  1,
+			},
+			code:    "token_rpm_exceeded",
+			message: "token rate limit exceeded (RPM)",
+		})
+	}
+
+	return counters
+}
+
+func (l *TokenLimits) modelPeriod() string {
+	switch l.budget.ModelPeriod {
+	case limit.PeriodDay, limit.PeriodWeek:
+		return l.budget.ModelPeriod
+	}
+	return limit.PeriodMonth
+}
+
+// Reserve 检查消费上限和 RPM，全部未超出时占用本次请求的预估额度和请求数
+func (l *TokenLimits) Reserve(estimateQuota int) *types.OpenAIErrorWithStatusCode {
+	counters := l.counters(estimateQuota)
+	reserved := make([]*limit.RollingCounter, 0, len(counters))
+	for _, counter := range counters {
+		reserved = append(reserved, counter.counter)
+	}
+
+	index, _, err := limit.ReserveCounters(reserved)
+	if err != nil {
+		return common.ErrorWrapperLocal(err, "get_token_limits_failed", http.StatusInternalServerError)
+	}
+	if index >= 0 {
+		counter := counters[index]
+		if counter.budget {
+			return common.StringErrorWrapperLocal(counter.message, counter.code, http.StatusPaymentRequired)
+		}
+		return common.StringErrorWrapperLocal(counter.message, counter.code, http.StatusTooManyRequests)
+	}
+
+	l.reserved = reserved
+	return nil
+}
+
+// Release 请求失败时撤销占用的额度和请求数，撤销计入占用时的桶
+func (l *TokenLimits) Release() {
+	if len(l.reserved) == 0 {
+		return
+	}
+
+	for _, counter := range l.reserved {
+		counter.Value = -counter.Value
+	}
+	if err := limit.AddCounters(l.reserved); err != nil {
+		logger.SysError("release token limits error: " + err.Error())
+	}
+	l.reserved = nil
+}
+
+// Record 按实际消耗修正占用的消费额度
+func (l *TokenLimits) Record(quota int) {
+	if l.budget == nil {
+		return
+	}
+
+	// counters 的顺序和占用时相同，未占用时直接记录实际消耗
+	counters := l.counters(quota)
+	adjust := make([]*limit.RollingCounter, 0, len(counters))
+	for i, counter := range counters {
+		if !counter.budget {
+			continue
+		}
+		if i < len(l.reserved) {
+			// 修正计入占用时的桶
+			counter.counter.Value -= l.reserved[i].Value
+			counter.counter.Bucket = l.reserved[i].Bucket
+		}
+		adjust = append(adjust, counter.counter)
+	}
+	l.reserved = nil
+
+	if err := limit.AddCounters(adjust); err != nil {
+		logger.SysError("record token budget error: " + err.Error())
+	}
+}
@@ -0,0 +1,122 @@
+package relay_util
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/limit"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	userTPMKeyPrefix  = "user_tpm:%d"
+	tokenTPMKeyPrefix = "token_tpm:%d"
+)
+
+type tpmTarget struct {
+	key   string
+	limit int
+	code  string
+	name  string
+}
+
+// TPMLimits 用户分组、令牌、渠道的 TPM 限制
+// 预扣费时按预估的输入 token 数占用，请求完成后按实际 token 数修正，请求失败时释放
+// 渠道只统计不拒绝，由 model.FilterChannelTPM 在选择渠道时跳过已用满的渠道
+type TPMLimits struct {
+	targets      []tpmTarget
+	reservations []*limit.SlidingWindowReservation
+}
+
+func NewTPMLimits(c *gin.Context, userId, tokenId, channelId int) *TPMLimits {
+	limits := &TPMLimits{}
+
+	if groupTPM := model.GlobalUserGroupRatio.GetTPM(c.GetString("group")); groupTPM > 0 {
+		limits.targets = append(limits.targets, tpmTarget{
+			key:   fmt.Sprintf(userTPMKeyPrefix, userId),
+			limit: groupTPM,
+			code:  "group_tpm_exceeded",
+			name:  "group",
+		})
+	}
+
+	if setting, ok := c.Get("token_setting"); ok {
+		if tokenSetting, ok := setting.(*model.TokenSetting); ok && tokenSetting != nil {
+			rate := tokenSetting.Limits.LimitsRateSetting
+			if rate.Enabled && rate.TPM > 0 {
+				limits.targets = append(limits.targets, tpmTarget{
+					key:   fmt.Sprintf(tokenTPMKeyPrefix, tokenId),
+					limit: rate.TPM,
+					code:  "token_tpm_exceeded",
+					name:  "token",
+				})
+			}
+		}
+	}
+
+	// 渠道未设置 TPM 时不需要统计
+	if channelId > 0 && c.GetInt("channel_tpm") > 0 {
+		limits.targets = append(limits.targets, tpmTarget{
+			key: model.ChannelTPMKey(channelId),
+		})
+	}
+
+	if len(limits.targets) == 0 {
+		return nil
+	}
+
+	return limits
+}
+
+// Reserve 占用预估的输入 token 数，任一限制超出时释放已占用的部分
+func (l *TPMLimits) Reserve(promptTokens int) *types.OpenAIErrorWithStatusCode {
+	l.reservations = make([]*limit.SlidingWindowReservation, len(l.targets))
+	for i, target := range l.targets {
+		reservation, ok, err := limit.GlobalTPMLimiter.Reserve(target.key, target.limit, promptTokens)
+		if err != nil {
+			// 限流器异常时放行
+			logger.SysError("reserve tpm error: " + err.Error())
+			continue
+		}
+		if !ok {
+			l.Release()
+			return common.StringErrorWrapperLocal(fmt.Sprintf("%s rate limit exceeded (TPM)", target.name), target.code, http.StatusTooManyRequests)
+		}
+		l.reservations[i] = reservation
+	}
+
+	return nil
+}
+
+// Release 请求失败时释放占用的 token 数
+func (l *TPMLimits) Release() {
+	for _, reservation := range l.reservations {
+		if reservation == nil {
+			continue
+		}
+		if err := limit.GlobalTPMLimiter.Release(reservation); err != nil {
+			logger.SysError("release tpm error: " + err.Error())
+		}
+	}
+	l.reservations = nil
+}
+
+// Record 按实际 token 数修正占用，未占用的（如缓存命中）直接记录
+func (l *TPMLimits) Record(totalTokens int) {
+	for i, target := range l.targets {
+		var err error
+		if i < len(l.reservations) && l.reservations[i] != nil {
+			err = limit.GlobalTPMLimiter.Adjust(l.reservations[i], totalTokens)
+		} else if totalTokens > 0 {
+			err = limit.GlobalTPMLimiter.Record(target.key, totalTokens)
+		}
+		if err != nil {
+			logger.SysError("record tpm error: " + err.Error())
+		}
+	}
+	l.reservations = nil
+}
@@ -40,8 +40,7 @@ func RelayRerank(c *gin.Context) {
 	}
 
 	for i := retryTimes; i > 0; i-- {
-		// 冻结通道
-		shouldCooldowns(c, channel, apiErr)
+		skipChannel(c, channel)
 		if err := relay.setProvider(relay.getOriginalModel()); err != nil {
 			continue
 		}
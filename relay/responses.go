@@ -2,6 +2,7 @@ package relay
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"one-api/common"
@@ -19,6 +20,9 @@ import (
 type relayResponses struct {
 	relayBase
 	responsesRequest types.OpenAIResponsesRequest
+
+	inputItems         []any  // 本轮请求的输入项，用于存储
+	previousResponseID string // 展开前的 previous_response_id
 }
 
 func NewRelayResponses(c *gin.Context) *relayResponses {
@@ -34,6 +38,10 @@ func (r *relayResponses) setRequest() error {
 
 	r.setOriginalModel(r.responsesRequest.Model)
 
+	if err := r.expandPreviousResponse(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -77,8 +85,10 @@ func (r *relayResponses) send() (err *types.OpenAIErrorWithStatusCode, done bool
 			return ""
 		}
 
-		firstResponseTime := responseGeneralStreamClient(r.c, response, doneStr)
+		storeStream := &responsesStoreStream{StreamReaderInterface: response}
+		firstResponseTime := responseGeneralStreamClient(r.c, storeStream, doneStr)
 		r.SetFirstResponseTime(firstResponseTime)
+		r.saveResponses(storeStream.response)
 	} else {
 		var response *types.OpenAIResponsesResponses
 		response, err = responsesProvider.CreateResponses(&r.responsesRequest)
@@ -89,6 +99,8 @@ func (r *relayResponses) send() (err *types.OpenAIErrorWithStatusCode, done bool
 
 		if openErr != nil {
 			err = openErr
+		} else {
+			r.saveResponses(response)
 		}
 	}
 
@@ -105,6 +117,11 @@ func (r *relayResponses) compatibleSend(chatProvider providersBase.ChatInterface
 		return common.ErrorWrapperLocal(err, "invalid_claude_config", http.StatusInternalServerError), true
 	}
 
+	// 本地没有存储上一轮的响应，Chat 渠道无法还原上下文
+	if r.responsesRequest.PreviousResponseID != "" {
+		return common.StringErrorWrapperLocal(fmt.Sprintf("Previous response with id '%s' not found.", r.responsesRequest.PreviousResponseID), "previous_response_not_found", http.StatusNotFound), true
+	}
+
 	if r.responsesRequest.Stream {
 		var response requester.StreamReaderInterface[string]
 		response, errWithCode = chatProvider.CreateChatCompletionStream(chatReq)
@@ -121,7 +138,9 @@ func (r *relayResponses) compatibleSend(chatProvider providersBase.ChatInterface
 		}
 
 		responseResp := response.ToResponses(&r.responsesRequest)
-		responseJsonClient(r.c, responseResp)
+		if openErr := responseJsonClient(r.c, responseResp); openErr == nil {
+			r.saveResponses(responseResp)
+		}
 	}
 
 	if errWithCode != nil {
@@ -192,5 +211,6 @@ func (r *relayResponses) chatToResponseStreamClient(stream requester.StreamReade
 
 	// 等待处理完成
 	<-done
+	r.saveResponses(converter.GetResponses())
 	return firstResponseTime
 }
@@ -0,0 +1,335 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/requester"
+	"one-api/model"
+	"one-api/types"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	responsesInputItemsDefaultLimit = 20
+	responsesInputItemsMaxLimit     = 100
+)
+
+func (r *relayResponses) isStore() bool {
+	return config.ResponsesStoreSettingsInstance.Enabled && r.responsesRequest.Store != nil && *r.responsesRequest.Store
+}
+
+// expandPreviousResponse 本地存储中存在 previous_response_id 时，将历史对话展开到 input 中
+// 找不到时保留 previous_response_id，原生 Responses 渠道可以自行处理
+func (r *relayResponses) expandPreviousResponse() error {
+	r.inputItems = inputToItems(r.responsesRequest.Input)
+	r.previousResponseID = r.responsesRequest.PreviousResponseID
+
+	if r.previousResponseID == "" || !config.ResponsesStoreSettingsInstance.Enabled {
+		return nil
+	}
+
+	chain, err := model.GetResponsesStoreChain(r.c.GetInt("id"), r.c.GetInt("token_id"), r.previousResponseID)
+	if err != nil {
+		return err
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+
+	input := make([]any, 0)
+	for _, store := range chain {
+		var items []any
+		if err := json.Unmarshal(store.Input, &items); err == nil {
+			input = append(input, items...)
+		}
+
+		var response types.OpenAIResponsesResponses
+		if err := json.Unmarshal(store.Response, &response); err == nil {
+			input = append(input, outputsToInputItems(response.Output)...)
+		}
+	}
+	input = append(input, r.inputItems...)
+
+	r.responsesRequest.Input = input
+	r.responsesRequest.PreviousResponseID = ""
+
+	return nil
+}
+
+func inputToItems(input any) []any {
+	if text, ok := input.(string); ok {
+		return []any{map[string]any{
+			"type":    types.InputTypeMessage,
+			"role":    types.ChatMessageRoleUser,
+			"content": text,
+		}}
+	}
+
+	if items, ok := input.([]any); ok {
+		return items
+	}
+
+	return []any{}
+}
+
+// outputsToInputItems 将历史响应的输出转换为输入项
+// reasoning 等依赖上游状态的输出项不回传，避免在其他渠道上无法解析
+func outputsToInputItems(outputs []types.ResponsesOutput) []any {
+	items := make([]any, 0, len(outputs))
+	for _, output := range outputs {
+		switch output.Type {
+		case types.InputTypeMessage:
+			text := output.StringContent()
+			if text == "" {
+				continue
+			}
+			items = append(items, map[string]any{
+				"type": types.InputTypeMessage,
+				"role": types.ChatMessageRoleAssistant,
+				"content": []map[string]any{{
+					"type": types.ContentTypeOutputText,
+					"text": text,
+				}},
+			})
+		case types.InputTypeFunctionCall:
+			arguments := ""
+			if output.Arguments != nil {
+				arguments = *output.Arguments
+			}
+			items = append(items, map[string]any{
+				"type":      types.InputTypeFunctionCall,
+				"call_id":   output.CallID,
+				"name":      output.Name,
+				"arguments": arguments,
+			})
+		}
+	}
+
+	return items
+}
+
+// saveResponses 保存响应，失败只记录日志
+func (r *relayResponses) saveResponses(response *types.OpenAIResponsesResponses) {
+	if response == nil || response.ID == "" || !r.isStore() {
+		return
+	}
+
+	response.PreviousResponseID = r.previousResponseID
+
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	inputBytes, err := json.Marshal(r.inputItems)
+	if err != nil {
+		return
+	}
+
+	store := &model.ResponsesStore{
+		ResponseID:         response.ID,
+		UserId:             r.c.GetInt("id"),
+		TokenId:            r.c.GetInt("token_id"),
+		Model:              r.getOriginalModel(),
+		PreviousResponseID: r.previousResponseID,
+		Input:              inputBytes,
+		Response:           responseBytes,
+	}
+
+	gopool.Go(func() {
+		if err := store.Insert(); err != nil {
+			logger.SysError("save responses store error: " + err.Error())
+		}
+	})
+}
+
+// responsesStoreStream 包装原生 Responses 流，记录最终的 response 事件
+type responsesStoreStream struct {
+	requester.StreamReaderInterface[string]
+	response *types.OpenAIResponsesResponses
+}
+
+func (s *responsesStoreStream) Recv() (<-chan string, <-chan error) {
+	dataChan, errChan := s.StreamReaderInterface.Recv()
+	outDataChan := make(chan string)
+	outErrChan := make(chan error)
+
+	// 数据和错误在同一个goroutine中按顺序转发，保证结束前的数据不会丢失
+	gopool.Go(func() {
+		for {
+			select {
+			case data, ok := <-dataChan:
+				if !ok {
+					close(outDataChan)
+					return
+				}
+				s.capture(data)
+				outDataChan <- data
+			case err := <-errChan:
+				outErrChan <- err
+				return
+			}
+		}
+	})
+
+	return outDataChan, outErrChan
+}
+
+func (s *responsesStoreStream) capture(data string) {
+	if !strings.Contains(data, `"response.completed"`) && !strings.Contains(data, `"response.incomplete"`) && !strings.Contains(data, `"response.failed"`) {
+		return
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		var streamResponse types.OpenAIResponsesStreamResponses
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &streamResponse); err != nil {
+			continue
+		}
+		if streamResponse.Response != nil {
+			s.response = streamResponse.Response
+		}
+	}
+}
+
+func responsesNotFound(c *gin.Context, responseId string) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": types.OpenAIError{
+			Message: fmt.Sprintf("Response with id '%s' not found.", responseId),
+			Type:    "invalid_request_error",
+			Param:   "response_id",
+			Code:    "response_not_found",
+		},
+	})
+}
+
+func getResponsesStoreByParam(c *gin.Context) *model.ResponsesStore {
+	responseId := c.Param("id")
+	store, err := model.GetResponsesStore(c.GetInt("id"), c.GetInt("token_id"), responseId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": types.OpenAIError{
+				Message: err.Error(),
+				Type:    "one_hub_error",
+				Code:    "get_response_failed",
+			},
+		})
+		return nil
+	}
+
+	if store == nil {
+		responsesNotFound(c, responseId)
+		return nil
+	}
+
+	return store
+}
+
+// GetResponses GET /v1/responses/:id
+func GetResponses(c *gin.Context) {
+	store := getResponsesStoreByParam(c)
+	if store == nil {
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", store.Response)
+}
+
+// DeleteResponses DELETE /v1/responses/:id
+func DeleteResponses(c *gin.Context) {
+	responseId := c.Param("id")
+	deleted, err := model.DeleteResponsesStore(c.GetInt("id"), c.GetInt("token_id"), responseId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": types.OpenAIError{
+				Message: err.Error(),
+				Type:    "one_hub_error",
+				Code:    "delete_response_failed",
+			},
+		})
+		return
+	}
+
+	if !deleted {
+		responsesNotFound(c, responseId)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":      responseId,
+		"object":  "response.deleted",
+		"deleted": true,
+	})
+}
+
+// ListResponsesInputItems GET /v1/responses/:id/input_items
+func ListResponsesInputItems(c *gin.Context) {
+	store := getResponsesStoreByParam(c)
+	if store == nil {
+		return
+	}
+
+	var items []map[string]any
+	if err := json.Unmarshal(store.Input, &items); err != nil {
+		items = make([]map[string]any, 0)
+	}
+
+	// 没有 id 的输入项生成固定的 id，方便分页
+	for i, item := range items {
+		if id, _ := item["id"].(string); id == "" {
+			item["id"] = fmt.Sprintf("%s_in_%d", store.ResponseID, i)
+		}
+	}
+
+	if c.DefaultQuery("order", "desc") != "asc" {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	if after := c.Query("after"); after != "" {
+		for i, item := range items {
+			if item["id"] == after {
+				items = items[i+1:]
+				break
+			}
+		}
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		limit = responsesInputItemsDefaultLimit
+	}
+	if limit > responsesInputItemsMaxLimit {
+		limit = responsesInputItemsMaxLimit
+	}
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+
+	var firstId, lastId any
+	if len(items) > 0 {
+		firstId = items[0]["id"]
+		lastId = items[len(items)-1]["id"]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"object":   "list",
+		"data":     items,
+		"first_id": firstId,
+		"last_id":  lastId,
+		"has_more": hasMore,
+	})
+}
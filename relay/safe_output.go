@@ -0,0 +1,320 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/safty"
+	"one-api/types"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const safeOutputRedactPlaceholder = "[REDACTED]"
+
+// 遇到这些字符时认为一句话结束，检查暂缓输出的内容
+const safeOutputBoundaries = "。！？；\n.!?;"
+
+// outputGuard 输出内容审查
+// 流式输出时按 choice 暂缓输出新内容，遇到句子结束或积累一定字符数后与保留的上文一起检查，通过后再输出
+type outputGuard struct {
+	c        *gin.Context
+	toolName string
+	mode     string
+	window   int
+	interval int
+	buffers  map[int]*outputGuardBuffer
+	template map[string]any // 最近一个分块除 choices 外的字段，用于结束时输出剩余内容
+	logged   bool
+}
+
+type outputGuardBuffer struct {
+	key     string // delta.content 或 completions 的 text
+	checked []rune // 已检查的上文
+	pending []rune // 暂缓输出、尚未检查的内容
+}
+
+// newOutputGuard 未开启输出审查时返回 nil
+// 流式输出只使用本地检查器，配置的检查器不支持时不审查流式输出
+func newOutputGuard(c *gin.Context, stream bool) *outputGuard {
+	if !config.EnableSafe || !config.SafeOutputEnabled {
+		return nil
+	}
+
+	toolName := config.SafeOutputToolName
+	if toolName == "" {
+		toolName = config.SafeToolName
+	}
+	if stream && !safty.IsStreamTool(toolName) {
+		return nil
+	}
+
+	mode := config.SafeOutputMode
+	if mode != config.SafeOutputModeRedact {
+		mode = config.SafeOutputModeTerminate
+	}
+
+	return &outputGuard{
+		c:        c,
+		toolName: toolName,
+		mode:     mode,
+		window:   max(config.SafeOutputWindow, 0),
+		interval: max(config.SafeOutputCheckInterval, 1),
+		buffers:  make(map[int]*outputGuardBuffer),
+	}
+}
+
+// check 检查文本，返回是否安全和命中的类别
+func (g *outputGuard) check(text string) (bool, string) {
+	if text == "" {
+		return true, ""
+	}
+
+	result, err := safty.CheckContentByToolName(g.toolName, text)
+	if err != nil {
+		// 审查工具不可用时不影响输出
+		logger.LogError(g.c.Request.Context(), "output safety check failed: "+err.Error())
+		return true, ""
+	}
+	if result.IsSafe {
+		return true, ""
+	}
+
+	category := strings.Join(result.Details, ",")
+	if category == "" {
+		category = result.Code
+	}
+	return false, category
+}
+
+// redact 替换违规内容，支持脱敏的工具只替换命中的部分，否则替换整段内容
+func (g *outputGuard) redact(text string) string {
+	if tool, ok := safty.Tools[g.toolName]; ok {
+		if masker, ok := tool.(safty.SaftyMasker); ok {
+			if masked, categories := masker.Mask(text); len(categories) > 0 {
+				return masked
+			}
+		}
+	}
+	return safeOutputRedactPlaceholder
+}
+
+// record 记录违规日志，同一请求只记录一次
+func (g *outputGuard) record(category string) {
+	if g.logged {
+		return
+	}
+	g.logged = true
+
+	ctx := g.c.Request.Context()
+	content := fmt.Sprintf("输出内容审查命中(%s)，处理方式：%s", category, g.mode)
+	logger.LogWarn(ctx, fmt.Sprintf("output safety violation: user %d token %d category %s", g.c.GetInt("id"), g.c.GetInt("token_id"), category))
+	model.RecordSafetyLog(ctx, g.c.GetInt("id"), g.c.GetString("token_name"), g.c.GetString("original_model"), g.c.GetInt("channel_id"), category, content)
+}
+
+// inspectChunk 检查流式输出的一个分块，返回需要发送的分块，terminate 为 true 时应结束输出
+// 兼容 chat.completion.chunk 的 delta.content 和 completions 的 text
+func (g *outputGuard) inspectChunk(data string) (string, bool) {
+	var chunk map[string]any
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return data, false
+	}
+
+	choices, ok := chunk["choices"].([]any)
+	if !ok || len(choices) == 0 {
+		return data, false
+	}
+
+	g.template = make(map[string]any, len(chunk))
+	for key, value := range chunk {
+		if key != "choices" && key != "usage" {
+			g.template[key] = value
+		}
+	}
+
+	modified := false
+	terminate := false
+	for _, item := range choices {
+		choice, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		index := 0
+		if value, ok := choice["index"].(float64); ok {
+			index = int(value)
+		}
+
+		var container map[string]any
+		key := "content"
+		if delta, ok := choice["delta"].(map[string]any); ok {
+			container = delta
+		} else if _, ok := choice["text"].(string); ok {
+			container = choice
+			key = "text"
+		} else {
+			continue
+		}
+
+		buffer, ok := g.buffers[index]
+		if !ok {
+			buffer = &outputGuardBuffer{key: key}
+			g.buffers[index] = buffer
+		}
+
+		text, _ := container[key].(string)
+		buffer.pending = append(buffer.pending, []rune(text)...)
+		if len(buffer.pending) == 0 {
+			continue
+		}
+		modified = true
+
+		// 结束的 choice 需要输出全部内容
+		if choice["finish_reason"] == nil && !strings.ContainsAny(text, safeOutputBoundaries) && len(buffer.pending) < g.interval {
+			container[key] = ""
+			continue
+		}
+
+		output, blocked := g.release(buffer)
+		container[key] = output
+		if blocked {
+			choice["finish_reason"] = types.FinishReasonContentFilter
+			terminate = true
+		}
+	}
+
+	if !modified {
+		return data, false
+	}
+
+	body, err := json.Marshal(chunk)
+	if err != nil {
+		return data, terminate
+	}
+	return string(body), terminate
+}
+
+// release 检查暂缓输出的内容，返回可以输出的内容，blocked 为 true 时应结束输出
+func (g *outputGuard) release(buffer *outputGuardBuffer) (string, bool) {
+	text := string(buffer.pending)
+	buffer.pending = nil
+
+	safe, category := g.check(string(buffer.checked) + text)
+	if safe {
+		buffer.checked = append(buffer.checked, []rune(text)...)
+		if len(buffer.checked) > g.window {
+			buffer.checked = buffer.checked[len(buffer.checked)-g.window:]
+		}
+		return text, false
+	}
+
+	g.record(category)
+	// 已处理的内容不再参与后续检查，避免重复命中
+	buffer.checked = nil
+
+	if g.mode == config.SafeOutputModeRedact {
+		return g.redact(text), false
+	}
+	return "", true
+}
+
+// flush 流结束时检查并输出剩余的内容，没有剩余内容时返回空字符串
+func (g *outputGuard) flush() string {
+	if g.template == nil {
+		return ""
+	}
+
+	choices := make([]any, 0)
+	for index, buffer := range g.buffers {
+		if len(buffer.pending) == 0 {
+			continue
+		}
+
+		output, blocked := g.release(buffer)
+		choice := map[string]any{"index": index}
+		if buffer.key == "text" {
+			choice["text"] = output
+		} else {
+			choice["delta"] = map[string]any{"content": output}
+		}
+		if blocked {
+			choice["finish_reason"] = types.FinishReasonContentFilter
+		}
+		choices = append(choices, choice)
+	}
+	if len(choices) == 0 {
+		return ""
+	}
+
+	chunk := make(map[string]any, len(g.template)+1)
+	for key, value := range g.template {
+		chunk[key] = value
+	}
+	chunk["choices"] = choices
+
+	body, err := json.Marshal(chunk)
+	if err != nil {
+		return ""
+	}
+	return string(body)
+}
+
+// inspectText 检查完整的输出内容，返回处理后的内容和是否命中
+func (g *outputGuard) inspectText(text string) (string, bool) {
+	safe, category := g.check(text)
+	if safe {
+		return text, false
+	}
+
+	g.record(category)
+	if g.mode == config.SafeOutputModeRedact {
+		return g.redact(text), true
+	}
+	return "", true
+}
+
+// guardChatResponse 审查非流式对话的输出，命中时改写响应
+func guardChatResponse(c *gin.Context, response *types.ChatCompletionResponse) {
+	guard := newOutputGuard(c, false)
+	if guard == nil || response == nil {
+		return
+	}
+
+	for i := range response.Choices {
+		content, ok := response.Choices[i].Message.Content.(string)
+		if !ok {
+			continue
+		}
+
+		text, flagged := guard.inspectText(content)
+		if !flagged {
+			continue
+		}
+		response.Choices[i].Message.Content = text
+		if guard.mode == config.SafeOutputModeTerminate {
+			response.Choices[i].FinishReason = types.FinishReasonContentFilter
+		}
+	}
+}
+
+// guardCompletionResponse 审查非流式补全的输出，命中时改写响应
+func guardCompletionResponse(c *gin.Context, response *types.CompletionResponse) {
+	guard := newOutputGuard(c, false)
+	if guard == nil || response == nil {
+		return
+	}
+
+	for i := range response.Choices {
+		text, flagged := guard.inspectText(response.Choices[i].Text)
+		if !flagged {
+			continue
+		}
+		response.Choices[i].Text = text
+		if guard.mode == config.SafeOutputModeTerminate {
+			response.Choices[i].FinishReason = types.FinishReasonContentFilter
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package relay
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/providers/claude"
+	"one-api/providers/gemini"
+	"one-api/relay/relay_util"
+	"one-api/safty"
+	"one-api/safty/providers/keyword"
+	"one-api/types"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type testChunkStream struct {
+	chunks []string
+}
+
+func (s *testChunkStream) Recv() (<-chan string, <-chan error) {
+	dataChan := make(chan string)
+	errChan := make(chan error)
+
+	go func() {
+		for _, chunk := range s.chunks {
+			dataChan <- chunk
+		}
+		errChan <- io.EOF
+	}()
+
+	return dataChan, errChan
+}
+
+func (s *testChunkStream) Close() {}
+
+func setupOutputGuard(t *testing.T) {
+	logger.Logger = zap.NewNop()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&model.User{}, &model.Log{}))
+
+	originDB := model.DB
+	originKeyWords := config.SafeKeyWords
+	model.DB = db
+	config.EnableSafe = true
+	config.SafeOutputEnabled = true
+	config.SafeOutputMode = config.SafeOutputModeTerminate
+	config.SafeToolName = "Keyword"
+	config.SafeKeyWords = []string{"forbidden"}
+	safty.RegisterTool("Keyword", keyword.NewKeywordChecker())
+
+	t.Cleanup(func() {
+		model.DB = originDB
+		config.EnableSafe = false
+		config.SafeOutputEnabled = false
+		config.SafeKeyWords = originKeyWords
+	})
+}
+
+func TestResponseConvertStreamClientOutputGuard(t *testing.T) {
+	setupOutputGuard(t)
+
+	chunks := []string{
+		`{"id":"1","model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":"hello. "}}]}`,
+		`{"id":"1","model":"m","choices":[{"index":0,"delta":{"content":"this is forbidden."}}]}`,
+		`{"id":"1","model":"m","choices":[{"index":0,"delta":{"content":"more text."},"finish_reason":"stop"}]}`,
+	}
+
+	cases := []struct {
+		name      string
+		converter func(c *gin.Context) StreamConverter
+		end       string
+	}{
+		{
+			name: "Claude",
+			converter: func(c *gin.Context) StreamConverter {
+				return relay_util.NewClaudeStreamConverter(c, "m", &types.Usage{})
+			},
+			end: "message_stop",
+		},
+		{
+			name: "Gemini",
+			converter: func(c *gin.Context) StreamConverter {
+				return relay_util.NewGeminiStreamConverter(c, "m", &types.Usage{})
+			},
+			end: "SAFETY",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/", nil)
+
+			responseConvertStreamClient(c, &testChunkStream{chunks: chunks}, tc.converter(c))
+
+			body := w.Body.String()
+			assert.Contains(t, body, "hello.")
+			// 违规内容和之后的内容都不会输出
+			assert.NotContains(t, body, "forbidden")
+			assert.NotContains(t, body, "more text")
+			assert.Contains(t, body, tc.end)
+		})
+	}
+}
+
+func TestGuardChatResponseBeforeConvert(t *testing.T) {
+	setupOutputGuard(t)
+
+	response := &types.ChatCompletionResponse{
+		ID:    "1",
+		Model: "m",
+		Choices: []types.ChatCompletionChoice{{
+			Index:        0,
+			Message:      types.ChatCompletionMessage{Role: types.ChatMessageRoleAssistant, Content: "this is forbidden"},
+			FinishReason: types.FinishReasonStop,
+		}},
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/", nil)
+	guardChatResponse(c, response)
+
+	assert.Equal(t, "", response.Choices[0].Message.Content)
+	assert.Equal(t, types.FinishReasonContentFilter, response.Choices[0].FinishReason)
+
+	claudeResponse, _ := json.Marshal(claude.ConvertFromChatResponse(response, &types.Usage{}))
+	assert.NotContains(t, string(claudeResponse), "forbidden")
+
+	geminiResponse := gemini.ConvertFromChatResponse(response, &types.Usage{})
+	assert.Equal(t, "SAFETY", *geminiResponse.Candidates[0].FinishReason)
+}
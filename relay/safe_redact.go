@@ -0,0 +1,133 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/safty"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getSafeRedactMode 敏感信息的处理方式，优先级：令牌 > 用户分组 > 全局设置
+func getSafeRedactMode(c *gin.Context) string {
+	if setting, ok := utils.GetGinValue[*model.TokenSetting](c, "token_setting"); ok && setting != nil && setting.SafeRedact.Mode != "" {
+		return setting.SafeRedact.Mode
+	}
+
+	if mode := model.GlobalUserGroupRatio.GetSafeRedactMode(c.GetString("group")); mode != "" {
+		return mode
+	}
+
+	return config.SafeRedactMode
+}
+
+// 请求中可能包含用户输入文本的字段，覆盖 OpenAI、Claude、Gemini 等格式
+var safeRedactTextKeys = map[string]bool{
+	"content":      true, // chat、claude、responses 的消息内容
+	"text":         true, // 多模态消息、gemini parts
+	"input":        true, // responses、embeddings、moderations
+	"prompt":       true, // completions
+	"instructions": true, // responses
+	"system":       true, // claude
+	"arguments":    true, // 工具调用参数
+}
+
+// applySafeRedact 检查请求中的个人信息和密钥，按策略拒绝请求、替换内容或只记录日志
+// 在统一的转发入口处理原始请求体，之后各类请求解析到的都是处理后的内容
+func applySafeRedact(c *gin.Context) error {
+	mode := getSafeRedactMode(c)
+	if mode != config.SafeRedactModeBlock && mode != config.SafeRedactModeMask && mode != config.SafeRedactModeLog {
+		return nil
+	}
+	if c.Request.Body == nil || !strings.HasPrefix(c.GetHeader("Content-Type"), "application/json") {
+		return nil
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	c.Request.Body.Close()
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var request any
+	if err := decoder.Decode(&request); err != nil {
+		// 格式错误由之后的解析返回
+		return nil
+	}
+
+	found := make(map[string]bool)
+	changed := false
+	mask := func(text string) string {
+		masked, categories := safty.MaskContent(text)
+		for _, category := range categories {
+			found[category] = true
+		}
+		if mode == config.SafeRedactModeMask && masked != text {
+			changed = true
+			return masked
+		}
+		return text
+	}
+	request = redactValue(request, false, mask)
+
+	if changed {
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(request); err != nil {
+			return err
+		}
+		c.Request.Body = io.NopCloser(&buf)
+		c.Request.ContentLength = int64(buf.Len())
+	}
+
+	if len(found) == 0 {
+		return nil
+	}
+
+	categories := make([]string, 0, len(found))
+	for category := range found {
+		categories = append(categories, category)
+	}
+	detail := strings.Join(categories, ",")
+
+	switch mode {
+	case config.SafeRedactModeBlock:
+		return fmt.Errorf("request contains sensitive data: %s", detail)
+	case config.SafeRedactModeMask:
+		logger.LogInfo(c.Request.Context(), fmt.Sprintf("sensitive data masked: user %d token %d categories %s", c.GetInt("id"), c.GetInt("token_id"), detail))
+	default:
+		logger.LogWarn(c.Request.Context(), fmt.Sprintf("sensitive data detected: user %d token %d categories %s", c.GetInt("id"), c.GetInt("token_id"), detail))
+	}
+
+	return nil
+}
+
+// redactValue 处理文本字段中的字符串，对象的字段按名称重新判断，数组沿用所在字段的判断
+func redactValue(value any, isText bool, mask func(string) string) any {
+	switch v := value.(type) {
+	case string:
+		if isText {
+			return mask(v)
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactValue(v[i], isText, mask)
+		}
+	case map[string]any:
+		for key, child := range v {
+			v[key] = redactValue(child, safeRedactTextKeys[key], mask)
+		}
+	}
+	return value
+}
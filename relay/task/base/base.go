@@ -37,6 +37,23 @@ type TaskInterface interface {
 	UpdateTaskStatus(ctx context.Context, taskChannelM map[int][]string, taskM map[string]*model.Task) error
 }
 
+// VideoTaskInterface 支持统一视频接口 /v1/videos 的平台需要实现
+type VideoTaskInterface interface {
+	TaskInterface
+	// InitVideo 将统一的视频请求转换为平台的请求，代替 Init
+	InitVideo(request *types.VideoRequest) *TaskError
+}
+
+// TaskVariantInterface 一个任务有多个产出的平台实现，开启部分计费时按成功的产出比例计费
+type TaskVariantInterface interface {
+	GetVariants(task *model.Task) (succeeded int, total int)
+}
+
+// TaskOutputInterface 从平台的任务数据中提取产出，未实现时统一接口不返回产出
+type TaskOutputInterface interface {
+	GetOutputs(task *model.Task) []types.TaskOutput
+}
+
 func (t *TaskBase) InitTask() {
 	userID := t.C.GetInt("id")
 	tokenId := t.C.GetInt("token_id")
@@ -0,0 +1,53 @@
+package base
+
+import (
+	"one-api/model"
+	"one-api/types"
+)
+
+// NormalizeTaskStatus 将平台的任务状态转换为统一的任务状态
+func NormalizeTaskStatus(status model.TaskStatus) string {
+	switch status {
+	case model.TaskStatusNotStart, model.TaskStatusSubmitted, model.TaskStatusQueued:
+		return types.TaskObjectStatusQueued
+	case model.TaskStatusInProgress:
+		return types.TaskObjectStatusInProgress
+	case model.TaskStatusSuccess:
+		return types.TaskObjectStatusSucceeded
+	case model.TaskStatusFailure:
+		return types.TaskObjectStatusFailed
+	default:
+		return types.TaskObjectStatusUnknown
+	}
+}
+
+// NewTaskObject 转换为统一的任务结构，adaptor 实现了 TaskOutputInterface 时附带任务产出
+func NewTaskObject(task *model.Task, adaptor TaskInterface) *types.TaskObject {
+	object := &types.TaskObject{
+		ID:          task.TaskID,
+		Object:      "task",
+		Platform:    task.Platform,
+		Action:      task.Action,
+		Status:      NormalizeTaskStatus(task.Status),
+		Progress:    task.Progress,
+		FailReason:  task.FailReason,
+		CreatedAt:   task.SubmitTime,
+		StartedAt:   task.StartTime,
+		FinishedAt:  task.FinishTime,
+		CallbackURL: task.NotifyHook,
+	}
+
+	if outputAdaptor, ok := adaptor.(TaskOutputInterface); ok && object.Status == types.TaskObjectStatusSucceeded {
+		object.Outputs = outputAdaptor.GetOutputs(task)
+		// 优先返回转存后的地址
+		if len(task.StoredUrls) == len(object.Outputs) {
+			for i, storedUrl := range task.StoredUrls {
+				if storedUrl != "" {
+					object.Outputs[i].URL = storedUrl
+				}
+			}
+		}
+	}
+
+	return object
+}
@@ -0,0 +1,62 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/relay/task/base"
+)
+
+// SettleTaskBilling 任务结束时结算，提交时已按全价扣费
+// 失败时全额退款；开启部分计费时，成功的任务按未成功的产出比例退款
+func SettleTaskBilling(ctx context.Context, task *model.Task, taskAdaptor base.TaskInterface) {
+	refundQuota := 0
+	reason := ""
+
+	switch task.Status {
+	case model.TaskStatusFailure:
+		refundQuota = task.Quota - task.RefundQuota
+		reason = model.TaskRefundReasonFailure
+	case model.TaskStatusSuccess:
+		if !config.TaskBillingSettingsInstance.PartialBilling {
+			return
+		}
+		variantAdaptor, ok := taskAdaptor.(base.TaskVariantInterface)
+		if !ok {
+			return
+		}
+		succeeded, total := variantAdaptor.GetVariants(task)
+		if total <= 0 || succeeded >= total {
+			return
+		}
+		refundQuota = task.Quota*(total-succeeded)/total - task.RefundQuota
+		reason = model.TaskRefundReasonPartial
+	}
+
+	if refundQuota <= 0 {
+		return
+	}
+
+	if err := model.RefundTaskQuota(task.UserId, task.TokenID, task.ChannelId, refundQuota); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("refund task %s quota error: %s", task.TaskID, err.Error()))
+		return
+	}
+
+	task.RefundQuota += refundQuota
+	if err := model.UpdateTaskRefundQuota(task.ID, task.RefundQuota); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("update task %s refund quota error: %s", task.TaskID, err.Error()))
+	}
+
+	content := fmt.Sprintf("异步任务执行失败 %s，补偿 %s", task.TaskID, common.LogQuota(refundQuota))
+	if reason == model.TaskRefundReasonPartial {
+		content = fmt.Sprintf("异步任务部分产出失败 %s，退还 %s", task.TaskID, common.LogQuota(refundQuota))
+	}
+	model.RecordTaskRefundLog(task.UserId, task.ChannelId, refundQuota, content, map[string]any{
+		"platform":      task.Platform,
+		"task_id":       task.TaskID,
+		"refund_reason": reason,
+	})
+}
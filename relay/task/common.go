@@ -7,6 +7,7 @@ import (
 	"one-api/relay/task/base"
 	"one-api/relay/task/kling"
 	"one-api/relay/task/suno"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,7 +27,45 @@ func GetTaskAdaptor(relayType int, c *gin.Context) (base.TaskInterface, error) {
 	}
 }
 
+// videoAdaptors 统一视频接口支持的平台，按请求的模型名称匹配，新增平台在此注册即可
+var videoAdaptors = []struct {
+	platform string
+	match    func(modelName string) bool
+}{
+	{
+		platform: model.TaskPlatformKling,
+		match: func(modelName string) bool {
+			return strings.HasPrefix(modelName, "kling")
+		},
+	},
+}
+
+func GetVideoTaskAdaptor(modelName string, c *gin.Context) (base.VideoTaskInterface, error) {
+	for _, adaptor := range videoAdaptors {
+		if !adaptor.match(modelName) {
+			continue
+		}
+
+		taskAdaptor, err := getTaskAdaptorByPlatform(adaptor.platform, c)
+		if err != nil {
+			return nil, err
+		}
+
+		videoAdaptor, ok := taskAdaptor.(base.VideoTaskInterface)
+		if !ok {
+			return nil, errors.New("adaptor not support video")
+		}
+		return videoAdaptor, nil
+	}
+
+	return nil, errors.New("adaptor not found")
+}
+
 func GetTaskAdaptorByPlatform(platform string) (base.TaskInterface, error) {
+	return getTaskAdaptorByPlatform(platform, nil)
+}
+
+func getTaskAdaptorByPlatform(platform string, c *gin.Context) (base.TaskInterface, error) {
 	relayType := config.RelayModeUnknown
 
 	switch platform {
@@ -36,7 +75,7 @@ func GetTaskAdaptorByPlatform(platform string) (base.TaskInterface, error) {
 		relayType = config.RelayModeKling
 	}
 
-	return GetTaskAdaptor(relayType, nil)
+	return GetTaskAdaptor(relayType, c)
 }
 
 func getTaskBase(c *gin.Context, platform string) base.TaskBase {
@@ -13,6 +13,7 @@ import (
 	"one-api/relay/task/base"
 	"one-api/types"
 	"sort"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/samber/lo"
@@ -81,14 +82,63 @@ func (t *KlingTask) Relay() *base.TaskError {
 	t.Task.TaskID = resp.Data.TaskID
 	t.Task.ChannelId = t.Provider.Channel.Id
 	t.Task.Action = t.Action
+	t.Task.Prompt = t.Request.Prompt
 
 	return nil
 }
 
+// InitVideo 统一视频接口，有首帧图片时为图生视频
+func (t *KlingTask) InitVideo(request *types.VideoRequest) *base.TaskError {
+	t.Request = &KlingProvider.KlingTask{
+		Prompt:         request.Prompt,
+		NegativePrompt: request.NegativePrompt,
+		ModelName:      request.Model,
+		Mode:           request.Mode,
+		Image:          request.Image,
+		ImageTail:      request.ImageTail,
+	}
+	if request.AspectRatio != "" {
+		t.Request.AspectRatio = request.AspectRatio
+	}
+	if request.Duration > 0 {
+		t.Request.Duration = strconv.Itoa(request.Duration)
+	}
+
+	action := "text2video"
+	if request.Image != "" {
+		action = "image2video"
+	}
+
+	if err := t.requestValidate("videos", action); err != nil {
+		return base.StringTaskError(http.StatusBadRequest, "invalid_request", err.Error(), true)
+	}
+
+	return nil
+}
+
+// GetOutputs 提取生成的视频
+func (t *KlingTask) GetOutputs(task *model.Task) []types.TaskOutput {
+	data := TaskModel2Dto(task)
+	if data.Data == nil || data.Data.TaskResult == nil {
+		return nil
+	}
+
+	outputs := make([]types.TaskOutput, 0, len(data.Data.TaskResult.Videos))
+	for _, video := range data.Data.TaskResult.Videos {
+		outputs = append(outputs, types.TaskOutput{
+			Type: "video",
+			URL:  video.URL,
+		})
+	}
+
+	return outputs
+}
+
 func (t *KlingTask) actionValidate() (err error) {
-	class := t.C.Param("class")
-	action := t.C.Param("action")
+	return t.requestValidate(t.C.Param("class"), t.C.Param("action"))
+}
 
+func (t *KlingTask) requestValidate(class, action string) (err error) {
 	if class != "videos" {
 		err = fmt.Errorf("class is not videos")
 		return
@@ -172,7 +222,7 @@ func updateKlingTaskAll(ctx context.Context, channelId int, taskIds []string, ta
 		return fmt.Errorf("provider not found")
 	}
 
-	taskActions, err := model.GetTaskActionByTaskIds("Kling", taskIds)
+	taskActions, err := model.GetTaskActionByTaskIds(model.TaskPlatformKling, taskIds)
 	if err != nil {
 		return fmt.Errorf("get task action failed: %v", err)
 	}
@@ -203,16 +253,9 @@ func updateKlingTaskAll(ctx context.Context, channelId int, taskIds []string, ta
 
 		if responseItem.FailReason != "" || task.Status == model.TaskStatusFailure {
 			logger.LogError(ctx, task.TaskID+" 构建失败，"+task.FailReason)
+			// 退款在任务状态变化后统一结算
+			task.Status = model.TaskStatusFailure
 			task.Progress = 100
-			quota := task.Quota
-			if quota > 0 {
-				err := model.IncreaseUserQuota(task.UserId, quota)
-				if err != nil {
-					logger.LogError(ctx, "fail to increase user quota: "+err.Error())
-				}
-				logContent := fmt.Sprintf("异步任务执行失败 %s，补偿 %s", task.TaskID, common.LogQuota(quota))
-				model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
-			}
 		}
 
 		if responseItem.Status == model.TaskStatusSuccess {
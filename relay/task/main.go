@@ -16,15 +16,18 @@ import (
 )
 
 func RelayTaskSubmit(c *gin.Context) {
-	var taskErr *base.TaskError
 	taskAdaptor, err := GetTaskAdaptor(GetRelayMode(c), c)
 	if err != nil {
-		taskErr = base.StringTaskError(http.StatusBadRequest, "adaptor_not_found", "adaptor not found", true)
+		taskErr := base.StringTaskError(http.StatusBadRequest, "adaptor_not_found", "adaptor not found", true)
 		c.JSON(http.StatusBadRequest, taskErr)
 		return
 	}
 
-	taskErr = taskAdaptor.Init()
+	relayTaskSubmit(c, taskAdaptor)
+}
+
+func relayTaskSubmit(c *gin.Context, taskAdaptor base.TaskInterface) {
+	taskErr := taskAdaptor.Init()
 	if taskErr != nil {
 		taskAdaptor.HandleError(taskErr)
 		return
@@ -38,11 +41,13 @@ func RelayTaskSubmit(c *gin.Context) {
 
 	quotaInstance := relay_util.NewQuota(c, taskAdaptor.GetModelName(), 1000)
 	if errWithOA := quotaInstance.PreQuotaConsumption(); errWithOA != nil {
+		model.CircuitBreakers.Release(taskAdaptor.GetProvider().GetChannel().Id, taskAdaptor.GetModelName())
 		taskAdaptor.HandleError(base.OpenAIErrToTaskErr(errWithOA))
 		return
 	}
 
 	taskErr = taskAdaptor.Relay()
+	recordTaskOutcome(taskAdaptor.GetProvider().GetChannel().Id, taskAdaptor.GetModelName(), taskErr)
 	if taskErr == nil {
 		CompletedTask(quotaInstance, taskAdaptor, c)
 		// 返回结果
@@ -62,7 +67,6 @@ func RelayTaskSubmit(c *gin.Context) {
 
 	channel := taskAdaptor.GetProvider().GetChannel()
 	for i := retryTimes; i > 0; i-- {
-		model.ChannelGroup.SetCooldowns(channel.Id, taskAdaptor.GetModelName())
 		taskErr = taskAdaptor.SetProvider()
 		if taskErr != nil {
 			continue
@@ -72,8 +76,11 @@ func RelayTaskSubmit(c *gin.Context) {
 		logger.LogError(c.Request.Context(), fmt.Sprintf("using channel #%d(%s) to retry (remain times %d)", channel.Id, channel.Name, i))
 
 		taskErr = taskAdaptor.Relay()
+		recordTaskOutcome(channel.Id, taskAdaptor.GetModelName(), taskErr)
 		if taskErr == nil {
-			go CompletedTask(quotaInstance, taskAdaptor, c)
+			CompletedTask(quotaInstance, taskAdaptor, c)
+			taskAdaptor.GinResponse()
+			metrics.RecordProvider(c, 200)
 			return
 		}
 
@@ -90,11 +97,22 @@ func RelayTaskSubmit(c *gin.Context) {
 
 }
 
+// recordTaskOutcome 记录提交结果到熔断器，参数错误等客户端问题只释放探测名额
+func recordTaskOutcome(channelId int, modelName string, taskErr *base.TaskError) {
+	if taskErr == nil {
+		model.CircuitBreakers.RecordOutcome(channelId, modelName, true, 0, false)
+		return
+	}
+	model.CircuitBreakers.RecordOutcome(channelId, modelName, false, taskErr.StatusCode, taskErr.LocalError)
+}
+
 func CompletedTask(quotaInstance *relay_util.Quota, taskAdaptor base.TaskInterface, c *gin.Context) {
-	quotaInstance.Consume(c, &types.Usage{CompletionTokens: 0, PromptTokens: 1, TotalTokens: 1}, false)
+	usage := &types.Usage{CompletionTokens: 0, PromptTokens: 1, TotalTokens: 1}
+	quotaInstance.Consume(c, usage, false)
 
+	// 记录实际扣除的额度，任务失败时按此退款
 	task := taskAdaptor.GetTask()
-	task.Quota = int(quotaInstance.GetInputRatio() * 1000)
+	task.Quota = quotaInstance.GetTotalQuotaByUsage(usage)
 
 	err := task.Insert()
 	if err != nil {
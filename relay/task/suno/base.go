@@ -1,8 +1,10 @@
 package suno
 
 import (
+	"encoding/json"
 	"fmt"
 	"one-api/model"
+	sunoProvider "one-api/providers/suno"
 	"one-api/types"
 	"regexp"
 
@@ -45,3 +47,54 @@ func TaskModel2Dto(task *model.Task) *types.TaskDto {
 
 	return taskDto
 }
+
+// GetOutputs 提取生成的歌曲或歌词
+func (t *SunoTask) GetOutputs(task *model.Task) []types.TaskOutput {
+	outputs := make([]types.TaskOutput, 0)
+
+	switch task.Action {
+	case sunoProvider.SunoActionMusic:
+		var songs []sunoProvider.SunoSong
+		if err := json.Unmarshal(task.Data, &songs); err != nil {
+			return nil
+		}
+		for _, song := range songs {
+			if song.AudioURL != "" {
+				outputs = append(outputs, types.TaskOutput{Type: "audio", URL: song.AudioURL})
+			}
+			if song.VideoURL != "" {
+				outputs = append(outputs, types.TaskOutput{Type: "video", URL: song.VideoURL})
+			}
+			if song.ImageURL != "" {
+				outputs = append(outputs, types.TaskOutput{Type: "image", URL: song.ImageURL})
+			}
+		}
+	case sunoProvider.SunoActionLyrics:
+		var lyrics sunoProvider.SunoLyrics
+		if err := json.Unmarshal(task.Data, &lyrics); err != nil {
+			return nil
+		}
+		outputs = append(outputs, types.TaskOutput{Type: "text", Content: lyrics.Text})
+	}
+
+	return outputs
+}
+
+// GetVariants 每次生成多首歌曲，统计生成成功的数量
+func (t *SunoTask) GetVariants(task *model.Task) (succeeded int, total int) {
+	if task.Action != sunoProvider.SunoActionMusic {
+		return 0, 0
+	}
+
+	var songs []sunoProvider.SunoSong
+	if err := json.Unmarshal(task.Data, &songs); err != nil {
+		return 0, 0
+	}
+	for _, song := range songs {
+		if song.Status == "complete" {
+			succeeded++
+		}
+	}
+
+	return succeeded, len(songs)
+}
@@ -87,6 +87,7 @@ func (t *SunoTask) Relay() *base.TaskError {
 	}
 	t.Task.ChannelId = t.Provider.Channel.Id
 	t.Task.Action = t.Action
+	t.Task.Prompt = lo.If(t.Request.Prompt != "", t.Request.Prompt).Else(t.Request.GptDescriptionPrompt)
 
 	return nil
 }
@@ -220,16 +221,9 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 
 		if responseItem.FailReason != "" || task.Status == model.TaskStatusFailure {
 			logger.LogError(ctx, task.TaskID+" 构建失败，"+task.FailReason)
+			// 退款在任务状态变化后统一结算
+			task.Status = model.TaskStatusFailure
 			task.Progress = 100
-			quota := task.Quota
-			if quota > 0 {
-				err := model.IncreaseUserQuota(task.UserId, quota)
-				if err != nil {
-					logger.LogError(ctx, "fail to increase user quota: "+err.Error())
-				}
-				logContent := fmt.Sprintf("异步任务执行失败 %s，补偿 %s", task.TaskID, common.LogQuota(quota))
-				model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
-			}
 		}
 
 		if responseItem.Status == model.TaskStatusSuccess {
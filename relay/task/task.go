@@ -6,9 +6,13 @@ import (
 	"one-api/common"
 	"one-api/common/logger"
 	"one-api/model"
+	"one-api/relay/relay_util"
+	"one-api/relay/task/base"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/samber/lo"
 )
 
 var (
@@ -118,5 +122,51 @@ func UpdateTaskByPlatform(ctx context.Context,
 		return
 	}
 
+	// 记录更新前的状态，状态变化时结算并回调客户端
+	statuses := make(map[string]model.TaskStatus, len(taskM))
+	for taskId, task := range taskM {
+		statuses[taskId] = task.Status
+	}
+
 	taskAdaptor.UpdateTaskStatus(ctx, taskChannelM, taskM)
+
+	for taskId, task := range taskM {
+		if task.Status == statuses[taskId] {
+			continue
+		}
+		SettleTaskBilling(ctx, task, taskAdaptor)
+
+		// 成功的任务先归档到作品集，回调中带上转存后的地址
+		if task.Status == model.TaskStatusSuccess {
+			common.SafeGoroutine(func() {
+				ArchiveTask(task, taskAdaptor)
+				NotifyTaskWebhook(task, taskAdaptor)
+			})
+			continue
+		}
+		NotifyTaskWebhook(task, taskAdaptor)
+	}
+}
+
+// ArchiveTask 将任务产出加入作品集，有转存的产出时记录转存地址
+func ArchiveTask(task *model.Task, taskAdaptor base.TaskInterface) {
+	outputAdaptor, ok := taskAdaptor.(base.TaskOutputInterface)
+	if !ok {
+		return
+	}
+
+	outputs := outputAdaptor.GetOutputs(task)
+	if len(outputs) == 0 {
+		return
+	}
+
+	storedUrls := relay_util.ArchiveTaskOutputs(task.UserId, task.Platform, task.TaskID, task.Prompt, outputs)
+	if !lo.SomeBy(storedUrls, func(storedUrl string) bool { return storedUrl != "" }) {
+		return
+	}
+
+	task.StoredUrls = storedUrls
+	if err := model.UpdateTaskStoredUrls(task.ID, storedUrls); err != nil {
+		logger.SysError("update task stored urls error: " + err.Error())
+	}
 }
@@ -0,0 +1,97 @@
+package task
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/relay/task/base"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// videoTask 统一视频接口，请求转换后交给对应平台的 adaptor 处理，响应和错误使用统一的格式
+type videoTask struct {
+	base.VideoTaskInterface
+	c       *gin.Context
+	request *types.VideoRequest
+}
+
+func (t *videoTask) Init() *base.TaskError {
+	return t.InitVideo(t.request)
+}
+
+func (t *videoTask) GetTask() *model.Task {
+	task := t.VideoTaskInterface.GetTask()
+	if task != nil {
+		task.NotifyHook = t.request.CallbackURL
+	}
+	return task
+}
+
+func (t *videoTask) HandleError(err *base.TaskError) {
+	taskErrorResponse(t.c, err.StatusCode, err.Code, err.Message)
+}
+
+func (t *videoTask) GinResponse() {
+	object := base.NewTaskObject(t.GetTask(), t.VideoTaskInterface)
+	object.Object = "video"
+	t.c.JSON(http.StatusOK, object)
+}
+
+// RelayVideoSubmit 统一的视频生成接口，按模型选择平台
+func RelayVideoSubmit(c *gin.Context) {
+	var request types.VideoRequest
+	if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+		taskErrorResponse(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	if err := ValidateCallbackURL(request.CallbackURL); err != nil {
+		taskErrorResponse(c, http.StatusBadRequest, "invalid_callback_url", err.Error())
+		return
+	}
+
+	taskAdaptor, err := GetVideoTaskAdaptor(request.Model, c)
+	if err != nil {
+		taskErrorResponse(c, http.StatusBadRequest, "model_not_supported", "model "+request.Model+" is not supported")
+		return
+	}
+
+	relayTaskSubmit(c, &videoTask{
+		VideoTaskInterface: taskAdaptor,
+		c:                  c,
+		request:            &request,
+	})
+}
+
+// GetTaskByID 以统一的格式查询任意平台的任务
+func GetTaskByID(c *gin.Context) {
+	task, err := model.GetUserTaskByTaskId(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		taskErrorResponse(c, http.StatusInternalServerError, "get_task_failed", err.Error())
+		return
+	}
+
+	if task == nil {
+		taskErrorResponse(c, http.StatusNotFound, "task_not_exist", "task not exist")
+		return
+	}
+
+	taskAdaptor, _ := GetTaskAdaptorByPlatform(task.Platform)
+	c.JSON(http.StatusOK, base.NewTaskObject(task, taskAdaptor))
+}
+
+func taskErrorResponse(c *gin.Context, statusCode int, code, message string) {
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+
+	c.JSON(statusCode, types.OpenAIErrorResponse{
+		Error: types.OpenAIError{
+			Message: message,
+			Type:    "one_hub_error",
+			Code:    code,
+		},
+	})
+}
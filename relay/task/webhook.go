@@ -0,0 +1,195 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/relay/task/base"
+	"one-api/types"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// 任务状态变化时 POST 到提交任务时指定的 callback_url
+// 签名: X-Webhook-Signature = "sha256=" + hex(HMAC-SHA256(令牌的回调签名密钥, X-Webhook-Timestamp + "." + 请求体))
+// 签名密钥与令牌分开保存，可以通过 POST /api/token/:id/webhook_secret 重置
+//
+// 回调只尽力投递：待重试的回调保存在内存中，服务重启后不会继续投递，也不保证只投递一次
+// 接收方应按 X-Webhook-Id 去重，并以 GET /v1/tasks/:id 查询到的状态为准
+const (
+	TaskWebhookEventUpdated = "task.updated"
+
+	taskWebhookRetryDelay    = 10 * time.Second
+	taskWebhookMaxRetryDelay = time.Hour
+)
+
+type TaskWebhookEvent struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	CreatedAt int64             `json:"created_at"`
+	Data      *types.TaskObject `json:"data"`
+}
+
+var errLocalCallbackAddress = errors.New("callback address is not allowed")
+
+// 在建立连接时检查解析后的地址，防止通过域名访问内网
+var taskWebhookClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !isAllowedCallbackIP(ip) {
+					return errLocalCallbackAddress
+				}
+				return nil
+			},
+		}).DialContext,
+	},
+}
+
+// ValidateCallbackURL 校验回调地址，为空时不回调
+func ValidateCallbackURL(callbackURL string) error {
+	if callbackURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("callback_url must be http or https")
+	}
+	if u.Hostname() == "" {
+		return errors.New("callback_url host is empty")
+	}
+
+	// 域名解析后的地址在建立连接时检查
+	if config.TaskWebhookSettingsInstance.AllowLocal {
+		return nil
+	}
+	if u.Hostname() == "localhost" {
+		return errLocalCallbackAddress
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !isAllowedCallbackIP(ip) {
+		return errLocalCallbackAddress
+	}
+
+	return nil
+}
+
+func isAllowedCallbackIP(ip net.IP) bool {
+	if config.TaskWebhookSettingsInstance.AllowLocal {
+		return true
+	}
+
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast())
+}
+
+// NotifyTaskWebhook 异步回调任务的最新状态，失败时按指数退避重试，重试状态不持久化
+func NotifyTaskWebhook(task *model.Task, taskAdaptor base.TaskInterface) {
+	if task.NotifyHook == "" {
+		return
+	}
+
+	event := &TaskWebhookEvent{
+		ID:        utils.GetUUID(),
+		Type:      TaskWebhookEventUpdated,
+		CreatedAt: utils.GetTimestamp(),
+		Data:      base.NewTaskObject(task, taskAdaptor),
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.SysError("marshal task webhook error: " + err.Error())
+		return
+	}
+
+	callbackURL := task.NotifyHook
+	tokenId := task.TokenID
+	common.SafeGoroutine(func() {
+		deliverTaskWebhook(callbackURL, tokenId, event.ID, body)
+	})
+}
+
+func deliverTaskWebhook(callbackURL string, tokenId int, eventId string, body []byte) {
+	secret, err := model.GetTokenWebhookSecret(tokenId)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("task webhook %s get secret error: %s", eventId, err.Error()))
+		return
+	}
+
+	delay := taskWebhookRetryDelay
+	for attempt := 0; ; attempt++ {
+		err = sendTaskWebhook(callbackURL, secret, eventId, body)
+		if err == nil {
+			return
+		}
+
+		if attempt >= config.TaskWebhookSettingsInstance.MaxRetries {
+			logger.SysError(fmt.Sprintf("task webhook %s failed after %d attempts: %s", eventId, attempt+1, err.Error()))
+			return
+		}
+
+		time.Sleep(delay)
+		delay = min(delay*2, taskWebhookMaxRetryDelay)
+	}
+}
+
+func sendTaskWebhook(callbackURL, secret, eventId string, body []byte) error {
+	timeout := time.Duration(config.TaskWebhookSettingsInstance.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", eventId)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+signTaskWebhook(secret, timestamp, body))
+
+	resp, err := taskWebhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func signTaskWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
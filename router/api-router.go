@@ -144,6 +144,8 @@ func SetApiRouter(router *gin.Engine) {
 		{
 			channelRoute.GET("/", controller.GetChannelsList)
 			channelRoute.GET("/models", relay.ListModelsForAdmin)
+			channelRoute.GET("/stats", controller.GetChannelsStats)
+			channelRoute.GET("/circuit_breakers", controller.GetCircuitBreakers)
 			channelRoute.POST("/provider_models_list", controller.GetModelList)
 			channelRoute.GET("/:id", controller.GetChannel)
 			channelRoute.GET("/test", controller.TestAllChannels)
@@ -156,9 +158,25 @@ func SetApiRouter(router *gin.Engine) {
 			channelRoute.PUT("/batch/del_model", controller.BatchDelModelChannels)
 			channelRoute.DELETE("/disabled", controller.DeleteDisabledChannel)
 			channelRoute.DELETE("/:id/tag", controller.DeleteChannelTag)
+			channelRoute.DELETE("/:id/circuit_breaker", controller.ResetChannelCircuitBreaker)
 			channelRoute.DELETE("/:id", controller.DeleteChannel)
 			channelRoute.DELETE("/batch", controller.BatchDeleteChannel)
 		}
+		channelProbeRoute := apiRouter.Group("/channel_probe")
+		channelProbeRoute.Use(middleware.AdminAuth())
+		{
+			channelProbeRoute.GET("/", controller.GetChannelProbes)
+			channelProbeRoute.GET("/history", controller.GetChannelProbeHistory)
+			channelProbeRoute.GET("/trend", controller.GetChannelProbeTrend)
+			channelProbeRoute.POST("/", controller.AddChannelProbe)
+			channelProbeRoute.PUT("/", controller.UpdateChannelProbe)
+			channelProbeRoute.PUT("/capability", controller.UpdateChannelCapability)
+			channelProbeRoute.GET("/fingerprint", controller.GetChannelFingerprints)
+			channelProbeRoute.POST("/fingerprint", controller.RunChannelFingerprint)
+			channelProbeRoute.DELETE("/fingerprint", controller.DeleteChannelFingerprint)
+			channelProbeRoute.POST("/:id/run", controller.RunChannelProbe)
+			channelProbeRoute.DELETE("/:id", controller.DeleteChannelProbe)
+		}
 		channelTagRoute := apiRouter.Group("/channel_tag")
 		channelTagRoute.Use(middleware.AdminAuth())
 		{
@@ -182,6 +200,7 @@ func SetApiRouter(router *gin.Engine) {
 			tokenRoute.POST("/", controller.AddToken)
 			tokenRoute.PUT("/", controller.UpdateToken)
 			tokenRoute.DELETE("/:id", controller.DeleteToken)
+			tokenRoute.POST("/:id/webhook_secret", controller.ResetTokenWebhookSecret)
 		}
 		redemptionRoute := apiRouter.Group("/redemption")
 		redemptionRoute.Use(middleware.AdminAuth())
@@ -192,6 +211,24 @@ func SetApiRouter(router *gin.Engine) {
 			redemptionRoute.PUT("/", controller.UpdateRedemption)
 			redemptionRoute.DELETE("/:id", controller.DeleteRedemption)
 		}
+		payloadCaptureRoute := apiRouter.Group("/payload_capture")
+		payloadCaptureRoute.Use(middleware.AdminAuth())
+		{
+			payloadCaptureRoute.GET("/", controller.GetPayloadCaptures)
+			payloadCaptureRoute.GET("/:id", controller.GetPayloadCapture)
+			payloadCaptureRoute.POST("/:id/replay", controller.ReplayPayloadCapture)
+			payloadCaptureRoute.DELETE("/:id", controller.DeletePayloadCapture)
+		}
+		virtualModelRoute := apiRouter.Group("/virtual_model")
+		virtualModelRoute.Use(middleware.AdminAuth())
+		{
+			virtualModelRoute.GET("/", controller.GetVirtualModels)
+			virtualModelRoute.GET("/:id", controller.GetVirtualModelById)
+			virtualModelRoute.POST("/", controller.AddVirtualModel)
+			virtualModelRoute.PUT("/", controller.UpdateVirtualModel)
+			virtualModelRoute.DELETE("/:id", controller.DeleteVirtualModel)
+		}
+
 		logRoute := apiRouter.Group("/log")
 		logRoute.GET("/", middleware.AdminAuth(), controller.GetLogsList)
 		logRoute.DELETE("/", middleware.AdminAuth(), controller.DeleteHistoryLogs)
@@ -245,6 +282,14 @@ func SetApiRouter(router *gin.Engine) {
 		taskRoute := apiRouter.Group("/task")
 		taskRoute.GET("/self", middleware.UserAuth(), controller.GetUserAllTask)
 		taskRoute.GET("/", middleware.AdminAuth(), controller.GetAllTask)
+		taskRoute.GET("/refund_report", middleware.AdminAuth(), controller.GetTaskRefundReport)
+
+		galleryRoute := apiRouter.Group("/gallery")
+		galleryRoute.Use(middleware.UserAuth())
+		{
+			galleryRoute.GET("/", controller.GetUserGallery)
+			galleryRoute.DELETE("/:id", controller.DeleteUserGalleryItem)
+		}
 	}
 
 	sseRouter := router.Group("/api/sse")
@@ -3,6 +3,7 @@ package router
 import (
 	"one-api/middleware"
 	"one-api/relay"
+	"one-api/relay/batch"
 	"one-api/relay/midjourney"
 	"one-api/relay/task"
 	"one-api/relay/task/kling"
@@ -30,6 +31,13 @@ func setOpenAIRouter(router *gin.Engine) {
 		modelsRouter.GET("", relay.ListModelsByToken)
 		modelsRouter.GET("/:model", relay.RetrieveModel)
 	}
+	responsesRouter := router.Group("/v1/responses")
+	responsesRouter.Use(middleware.RelayPanicRecover(), middleware.OpenaiAuth())
+	{
+		responsesRouter.GET("/:id", relay.GetResponses)
+		responsesRouter.DELETE("/:id", relay.DeleteResponses)
+		responsesRouter.GET("/:id/input_items", relay.ListResponsesInputItems)
+	}
 	relayV1Router := router.Group("/v1")
 	relayV1Router.Use(middleware.RelayPanicRecover(), middleware.OpenaiAuth(), middleware.Distribute(), middleware.DynamicRedisRateLimiter())
 	{
@@ -49,16 +57,24 @@ func setOpenAIRouter(router *gin.Engine) {
 		relayV1Router.POST("/rerank", relay.RelayRerank)
 		relayV1Router.GET("/realtime", relay.ChatRealtime)
 
+		// 指定渠道时透传到上游，否则使用本地批处理
+		relayV1Router.Any("/files", batch.RelayFiles)
+		relayV1Router.Any("/files/*any", batch.RelayFiles)
+		relayV1Router.Any("/batches", batch.RelayBatches)
+		relayV1Router.Any("/batches/*any", batch.RelayBatches)
+
+		// 统一的异步任务接口
+		relayV1Router.POST("/videos", task.RelayVideoSubmit)
+		relayV1Router.GET("/videos/:id", task.GetTaskByID)
+		relayV1Router.GET("/tasks/:id", task.GetTaskByID)
+
 		relayV1Router.Use(middleware.SpecifiedChannel())
 		{
-			relayV1Router.Any("/files", relay.RelayOnly)
-			relayV1Router.Any("/files/*any", relay.RelayOnly)
 			relayV1Router.Any("/fine_tuning/*any", relay.RelayOnly)
 			relayV1Router.Any("/assistants", relay.RelayOnly)
 			relayV1Router.Any("/assistants/*any", relay.RelayOnly)
 			relayV1Router.Any("/threads", relay.RelayOnly)
 			relayV1Router.Any("/threads/*any", relay.RelayOnly)
-			relayV1Router.Any("/batches/*any", relay.RelayOnly)
 			relayV1Router.Any("/vector_stores/*any", relay.RelayOnly)
 			relayV1Router.DELETE("/models/:model", relay.RelayOnly)
 		}
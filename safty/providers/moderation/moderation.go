@@ -0,0 +1,267 @@
+package moderation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/common/cache"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/providers"
+	providersBase "one-api/providers/base"
+	saftyTypes "one-api/safty/types"
+	"one-api/types"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	SafeModerationCode        = "content_moderation_flagged"
+	SafeModerationUnavailable = "content_moderation_unavailable"
+)
+
+// ModerationChecker 调用实现了审核接口的渠道(如 OpenAI omni-moderation)进行内容审查
+// 渠道通过系统自身的分组和负载均衡选择，按类别分数和阈值判断是否违规
+type ModerationChecker struct{}
+
+// moderationResult 审核接口返回的单条结果，缓存时只保存原始分数，阈值调整后无需重新请求
+type moderationResult struct {
+	Flagged        bool               `json:"flagged"`
+	Categories     map[string]bool    `json:"categories"`
+	CategoryScores map[string]float64 `json:"category_scores"`
+}
+
+// NewModerationChecker 创建审核模型检查器实例
+func NewModerationChecker() *ModerationChecker {
+	return &ModerationChecker{}
+}
+
+// Name 返回检查器名称
+func (m *ModerationChecker) Name() string {
+	return "Moderation"
+}
+
+// Init 初始化审核模型检查器
+func (m *ModerationChecker) Init() error {
+	return nil
+}
+
+// Check 调用审核接口检查内容
+// 接口调用失败时按 SafeModerationFailClosed 决定拦截或放行
+func (m *ModerationChecker) Check(data string) (saftyTypes.CheckResult, error) {
+	result, err := m.moderate(data)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("Safety tool %s check failed: %v", m.Name(), err))
+		if config.SafeModerationFailClosed {
+			return saftyTypes.CheckResult{
+				IsSafe:    false,
+				Code:      SafeModerationUnavailable,
+				Reason:    saftyTypes.SafeDefaultErrorMessage,
+				Details:   []string{SafeModerationUnavailable},
+				RiskLevel: 1,
+			}, nil
+		}
+		return safeResult(), nil
+	}
+
+	categories, maxScore := evaluate(result)
+	if len(categories) == 0 {
+		return safeResult(), nil
+	}
+
+	return saftyTypes.CheckResult{
+		IsSafe:    false,
+		Code:      SafeModerationCode,
+		Reason:    saftyTypes.SafeDefaultErrorMessage,
+		Details:   categories,
+		RiskLevel: riskLevel(maxScore),
+	}, nil
+}
+
+// moderate 获取审核结果，优先读取缓存
+func (m *ModerationChecker) moderate(data string) (*moderationResult, error) {
+	ttl := time.Duration(config.SafeModerationCacheTTL) * time.Second
+	if ttl <= 0 {
+		return request(data)
+	}
+
+	hash := sha256.Sum256([]byte(config.SafeModerationModel + ":" + data))
+	cacheKey := "safe_moderation:" + hex.EncodeToString(hash[:])
+
+	if result, err := cache.GetCache[*moderationResult](cacheKey); err == nil && result != nil {
+		return result, nil
+	}
+
+	result, err := request(data)
+	if err != nil {
+		return nil, err
+	}
+	cache.SetCache(cacheKey, result, ttl)
+
+	return result, nil
+}
+
+// request 从审核分组中选择渠道并调用审核接口
+// 与 relay.Relay 相同，失败的渠道会被跳过并在重试次数内换用其他渠道，结果计入熔断器
+func request(data string) (*moderationResult, error) {
+	modelName := config.SafeModerationModel
+	skipChannelIds := make([]int, 0)
+	var lastErr error
+
+	for i := 0; i <= config.RetryTimes; i++ {
+		channel, err := model.ChannelGroup.Next(config.SafeModerationGroup, modelName, model.FilterChannelId(skipChannelIds))
+		if err != nil {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, err
+		}
+
+		result, errWithCode := requestChannel(channel, modelName, data)
+		recordCircuitBreaker(channel.Id, modelName, errWithCode)
+		if errWithCode == nil {
+			return result, nil
+		}
+
+		lastErr = fmt.Errorf("channel %d: %s", channel.Id, errWithCode.Message)
+		logger.SysError(fmt.Sprintf("Safety moderation channel %d failed: %s", channel.Id, errWithCode.Message))
+		skipChannelIds = append(skipChannelIds, channel.Id)
+	}
+
+	return nil, lastErr
+}
+
+// requestChannel 使用指定渠道调用审核接口
+func requestChannel(channel *model.Channel, modelName, data string) (*moderationResult, *types.OpenAIErrorWithStatusCode) {
+	req, err := http.NewRequest(http.MethodPost, "/v1/moderations", nil)
+	if err != nil {
+		return nil, common.ErrorWrapperLocal(err, "new_request_failed", http.StatusInternalServerError)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = req
+
+	provider := providers.GetProvider(channel, c)
+	if provider == nil {
+		return nil, common.ErrorWrapperLocal(errors.New("channel not implemented"), "channel_error", http.StatusServiceUnavailable)
+	}
+	moderationProvider, ok := provider.(providersBase.ModerationInterface)
+	if !ok {
+		return nil, common.ErrorWrapperLocal(errors.New("channel does not support moderation"), "channel_error", http.StatusServiceUnavailable)
+	}
+	provider.SetUsage(&types.Usage{})
+	provider.SetOriginalModel(modelName)
+
+	newModelName, err := provider.ModelMappingHandler(modelName)
+	if err != nil {
+		return nil, common.ErrorWrapperLocal(err, "model_mapping_error", http.StatusInternalServerError)
+	}
+	newModelName = strings.TrimPrefix(newModelName, "+")
+
+	response, errWithCode := moderationProvider.CreateModeration(&types.ModerationRequest{
+		Input: data,
+		Model: newModelName,
+	})
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	jsonBytes, err := json.Marshal(response.Results)
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "marshal_response_body_failed", http.StatusInternalServerError)
+	}
+
+	var results []*moderationResult
+	if err := json.Unmarshal(jsonBytes, &results); err != nil || len(results) == 0 {
+		return nil, common.StringErrorWrapper("moderation result is empty", "empty_response", http.StatusInternalServerError)
+	}
+
+	return results[0], nil
+}
+
+// recordCircuitBreaker 将调用结果计入熔断器，本地错误和请求参数错误只释放探测名额
+func recordCircuitBreaker(channelId int, modelName string, apiErr *types.OpenAIErrorWithStatusCode) {
+	if apiErr == nil {
+		model.CircuitBreakers.RecordOutcome(channelId, modelName, true, 0, false)
+		return
+	}
+	model.CircuitBreakers.RecordOutcome(channelId, modelName, false, apiErr.StatusCode, apiErr.LocalError)
+}
+
+// evaluate 按阈值判断命中的类别
+// 配置了阈值(类别阈值或 "*" 默认阈值)的类别按分数判断，否则使用接口返回的类别结果
+func evaluate(result *moderationResult) ([]string, float64) {
+	thresholds := config.SafeModerationThresholds
+	defaultThreshold, hasDefault := thresholds["*"]
+
+	categories := make([]string, 0)
+	maxScore := 0.0
+	for category, score := range result.CategoryScores {
+		threshold, ok := thresholds[category]
+		if !ok && hasDefault {
+			threshold, ok = defaultThreshold, true
+		}
+
+		hit := false
+		if ok {
+			hit = score >= threshold
+		} else {
+			hit = result.Categories[category]
+		}
+
+		if hit {
+			categories = append(categories, category)
+			if score > maxScore {
+				maxScore = score
+			}
+		}
+	}
+
+	// 接口只返回 flagged 而没有类别分数时
+	if len(result.CategoryScores) == 0 && result.Flagged {
+		for category, flagged := range result.Categories {
+			if flagged {
+				categories = append(categories, category)
+			}
+		}
+		if len(categories) == 0 {
+			categories = append(categories, "flagged")
+		}
+		maxScore = 1
+	}
+
+	sort.Strings(categories)
+	return categories, maxScore
+}
+
+// riskLevel 将最高分数换算为 1-10 的风险等级
+func riskLevel(score float64) int {
+	level := int(score*10 + 0.5)
+	if level < 1 {
+		level = 1
+	}
+	if level > 10 {
+		level = 10
+	}
+	return level
+}
+
+func safeResult() saftyTypes.CheckResult {
+	return saftyTypes.CheckResult{
+		IsSafe:    true,
+		Code:      saftyTypes.SafeDefaultSuccessCode,
+		Reason:    saftyTypes.SafeDefaultSuccessMessage,
+		Details:   make([]string, 0),
+		RiskLevel: 0,
+	}
+}
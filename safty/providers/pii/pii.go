@@ -0,0 +1,113 @@
+package pii
+
+import (
+	"one-api/safty/types"
+	"regexp"
+	"strings"
+)
+
+const (
+	CategoryEmail      = "email"
+	CategoryPhone      = "phone"
+	CategoryIDCard     = "id_card"
+	CategoryCreditCard = "credit_card"
+)
+
+// PIIChecker 基于正则的个人信息检查器
+// 检测邮箱、手机号、身份证号、信用卡号，身份证和信用卡会校验校验位以减少误判
+type PIIChecker struct {
+	rules []*types.MaskRule
+}
+
+// NewPIIChecker 创建个人信息检查器实例
+// 规则按顺序执行，较长的号码(身份证、信用卡)先于手机号匹配
+func NewPIIChecker() *PIIChecker {
+	return &PIIChecker{
+		rules: []*types.MaskRule{
+			{
+				Category:    CategoryIDCard,
+				Pattern:     regexp.MustCompile(`\b[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`),
+				Validate:    validIDCard,
+				Placeholder: "[ID_CARD]",
+			},
+			{
+				Category:    CategoryCreditCard,
+				Pattern:     regexp.MustCompile(`\b(?:4\d|5[1-5]|2[2-7]|3[47]|6[025])(?:[ -]?\d){11,17}\b`),
+				Validate:    validCreditCard,
+				Placeholder: "[CREDIT_CARD]",
+			},
+			{
+				Category:    CategoryPhone,
+				Pattern:     regexp.MustCompile(`(?:\+86[- ]?)?\b1[3-9]\d{9}\b|\+[1-9]\d{0,2}[- ]?\d{3,4}[- ]?\d{3,4}[- ]?\d{0,4}\b`),
+				Placeholder: "[PHONE]",
+			},
+			{
+				Category:    CategoryEmail,
+				Pattern:     regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+				Placeholder: "[EMAIL]",
+			},
+		},
+	}
+}
+
+// Name 返回检查器名称
+func (p *PIIChecker) Name() string {
+	return "PII"
+}
+
+// Init 初始化个人信息检查器
+func (p *PIIChecker) Init() error {
+	return nil
+}
+
+// Check 检查内容中是否包含个人信息
+func (p *PIIChecker) Check(data string) (types.CheckResult, error) {
+	_, categories := p.Mask(data)
+	return types.MaskCheckResult(categories, 5), nil
+}
+
+// Mask 替换内容中的个人信息
+func (p *PIIChecker) Mask(data string) (string, []string) {
+	return types.MaskByRules(p.rules, data)
+}
+
+var idCardWeights = []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+var idCardCheckCodes = "10X98765432"
+
+// validIDCard 校验 18 位身份证号的校验位
+func validIDCard(id string) bool {
+	if len(id) != 18 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < 17; i++ {
+		sum += int(id[i]-'0') * idCardWeights[i]
+	}
+
+	return idCardCheckCodes[sum%11] == strings.ToUpper(id[17:])[0]
+}
+
+// validCreditCard 校验信用卡号的 Luhn 校验位
+func validCreditCard(number string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(number)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+
+	return sum%10 == 0
+}
@@ -0,0 +1,124 @@
+package pii
+
+import (
+	"one-api/common/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidIDCard(t *testing.T) {
+	cases := []struct {
+		id       string
+		expected bool
+	}{
+		{"11010519491231002X", true},
+		{"11010519491231002x", true},
+		{"110101199003074477", true},
+		{"110101199003074478", false},
+		{"110105194912310021", false},
+		{"11010519491231002", false},
+		{"1101051949123100211", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, validIDCard(c.id), c.id)
+	}
+}
+
+func TestValidCreditCard(t *testing.T) {
+	cases := []struct {
+		number   string
+		expected bool
+	}{
+		{"4111111111111111", true},
+		{"4111 1111 1111 1111", true},
+		{"4111-1111-1111-1111", true},
+		{"5500000000000004", true},
+		{"378282246310005", true},
+		{"4111111111111112", false},
+		{"411111111111", false},
+		{"41111111111111111111", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, validCreditCard(c.number), c.number)
+	}
+}
+
+func TestMask(t *testing.T) {
+	checker := NewPIIChecker()
+
+	cases := []struct {
+		name       string
+		input      string
+		expected   string
+		categories []string
+	}{
+		{
+			name:       "没有个人信息",
+			input:      "hello world 12345",
+			expected:   "hello world 12345",
+			categories: []string{},
+		},
+		{
+			name:       "邮箱",
+			input:      "contact me at foo.bar@example.com please",
+			expected:   "contact me at [EMAIL] please",
+			categories: []string{CategoryEmail},
+		},
+		{
+			name:       "手机号",
+			input:      "电话 13812345678",
+			expected:   "电话 [PHONE]",
+			categories: []string{CategoryPhone},
+		},
+		{
+			name:       "身份证号",
+			input:      "身份证 11010519491231002X 已登记",
+			expected:   "身份证 [ID_CARD] 已登记",
+			categories: []string{CategoryIDCard},
+		},
+		{
+			name:       "校验位错误的身份证号不替换",
+			input:      "编号 110105194912310021",
+			expected:   "编号 110105194912310021",
+			categories: []string{},
+		},
+		{
+			name:       "信用卡号",
+			input:      "card 4111 1111 1111 1111 exp 12/30",
+			expected:   "card [CREDIT_CARD] exp 12/30",
+			categories: []string{CategoryCreditCard},
+		},
+		{
+			name:       "校验位错误的信用卡号不替换",
+			input:      "order 4111111111111112",
+			expected:   "order 4111111111111112",
+			categories: []string{},
+		},
+		{
+			name:       "多种个人信息",
+			input:      "a@b.io 13812345678 4111111111111111",
+			expected:   "[EMAIL] [PHONE] [CREDIT_CARD]",
+			categories: []string{CategoryCreditCard, CategoryPhone, CategoryEmail},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			masked, categories := checker.Mask(c.input)
+			assert.Equal(t, c.expected, masked)
+			assert.Equal(t, c.categories, categories)
+		})
+	}
+}
+
+func TestMaskCategoryFilter(t *testing.T) {
+	config.SafeRedactCategories = []string{CategoryEmail}
+	defer func() { config.SafeRedactCategories = nil }()
+
+	masked, categories := NewPIIChecker().Mask("a@b.io 13812345678")
+	assert.Equal(t, "[EMAIL] 13812345678", masked)
+	assert.Equal(t, []string{CategoryEmail}, categories)
+}
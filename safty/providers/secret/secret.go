@@ -0,0 +1,66 @@
+package secret
+
+import (
+	"one-api/safty/types"
+	"regexp"
+)
+
+const CategorySecret = "secret"
+
+// SecretChecker 检测 API Key、访问令牌、私钥等密钥信息
+type SecretChecker struct {
+	rules []*types.MaskRule
+}
+
+// NewSecretChecker 创建密钥检查器实例
+func NewSecretChecker() *SecretChecker {
+	patterns := []string{
+		`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`, // 私钥
+		`\bsk-(?:proj-|ant-)?[A-Za-z0-9_\-]{20,}`,                                    // OpenAI、Anthropic 等
+		`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,                                              // AWS Access Key
+		`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,                                             // GitHub Token
+		`\bgithub_pat_[A-Za-z0-9_]{22,}\b`,                                           // GitHub Fine-grained Token
+		`\bAIza[0-9A-Za-z_\-]{35}\b`,                                                 // Google API Key
+		`\bxox[abposr]-[A-Za-z0-9\-]{10,}\b`,                                         // Slack Token
+		`\beyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}\b`,       // JWT
+	}
+
+	rules := make([]*types.MaskRule, 0, len(patterns)+1)
+	for _, pattern := range patterns {
+		rules = append(rules, &types.MaskRule{
+			Category:    CategorySecret,
+			Pattern:     regexp.MustCompile(pattern),
+			Placeholder: "[SECRET]",
+		})
+	}
+
+	// 形如 api_key=xxx、password: xxx 的赋值，只替换值
+	rules = append(rules, &types.MaskRule{
+		Category:    CategorySecret,
+		Pattern:     regexp.MustCompile(`(?i)(\b(?:api[_-]?key|secret[_-]?key|client[_-]?secret|password|passwd|access[_-]?token)["']?\s*[:=]\s*["']?)[^\s"']{8,}`),
+		Placeholder: "${1}[SECRET]",
+	})
+
+	return &SecretChecker{rules: rules}
+}
+
+// Name 返回检查器名称
+func (s *SecretChecker) Name() string {
+	return "Secret"
+}
+
+// Init 初始化密钥检查器
+func (s *SecretChecker) Init() error {
+	return nil
+}
+
+// Check 检查内容中是否包含密钥
+func (s *SecretChecker) Check(data string) (types.CheckResult, error) {
+	_, categories := s.Mask(data)
+	return types.MaskCheckResult(categories, 10), nil
+}
+
+// Mask 替换内容中的密钥
+func (s *SecretChecker) Mask(data string) (string, []string) {
+	return types.MaskByRules(s.rules, data)
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"one-api/common/logger"
 	"one-api/safty/providers/keyword"
+	"one-api/safty/providers/moderation"
+	"one-api/safty/providers/pii"
+	"one-api/safty/providers/secret"
 	"one-api/safty/types"
 )
 
@@ -26,6 +29,15 @@ type SaftyTool interface {
 	Check(data string) (types.CheckResult, error)
 }
 
+// SaftyMasker 支持脱敏的检查器
+type SaftyMasker interface {
+	// Mask 替换内容中的敏感信息
+	// 返回值:
+	//   - string: 替换后的内容
+	//   - []string: 命中的敏感信息类别
+	Mask(data string) (string, []string)
+}
+
 // Tools 存储所有注册的安全检查器
 // key: 检查器名称
 // value: 检查器实例
@@ -38,6 +50,13 @@ func InitSaftyTools() error {
 	keywordChecker := keyword.NewKeywordChecker()
 	RegisterTool("Keyword", keywordChecker)
 
+	// 注册个人信息、密钥检查器
+	RegisterTool("Secret", secret.NewSecretChecker())
+	RegisterTool("PII", pii.NewPIIChecker())
+
+	// 注册审核模型检查器
+	RegisterTool("Moderation", moderation.NewModerationChecker())
+
 	// 初始化所有已注册的检查器
 	for name, tool := range Tools {
 		if err := tool.Init(); err != nil {
@@ -6,6 +6,7 @@ import (
 	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/safty/types"
+	"slices"
 )
 
 // RegisterTool 注册一个新的安全检查器
@@ -173,3 +174,45 @@ func CheckContent(content interface{}) (types.CheckResult, error) {
 
 	return tool.Check(contentStr)
 }
+
+// 流式输出时可以使用的本地检查器，其他检查器(如调用接口的 Moderation)耗时较长，只用于完整内容
+var streamToolNames = []string{"Keyword", "PII", "Secret"}
+
+// IsStreamTool 判断检查器是否可以用于流式输出的分段检查
+func IsStreamTool(name string) bool {
+	return slices.Contains(streamToolNames, name)
+}
+
+// 脱敏时按顺序执行的检查器，密钥先于个人信息处理，避免密钥中的数字被识别为号码
+var maskToolNames = []string{"Secret", "PII"}
+
+// MaskContent 使用支持脱敏的检查器替换内容中的敏感信息
+// 参数:
+//   - content: 要处理的内容
+//
+// 返回值:
+//   - string: 替换后的内容
+//   - []string: 命中的敏感信息类别
+func MaskContent(content string) (string, []string) {
+	categories := make([]string, 0)
+	if content == "" {
+		return content, categories
+	}
+
+	for _, name := range maskToolNames {
+		tool, err := getTool(name)
+		if err != nil {
+			continue
+		}
+		masker, ok := tool.(SaftyMasker)
+		if !ok {
+			continue
+		}
+
+		var found []string
+		content, found = masker.Mask(content)
+		categories = append(categories, found...)
+	}
+
+	return content, categories
+}
@@ -0,0 +1,89 @@
+package types
+
+import (
+	"one-api/common/config"
+	"regexp"
+)
+
+const SafeSensitiveDataCode = "sensitive_data_detected"
+const SafeSensitiveDataMessage = "content contains personal information or secrets"
+
+// MaskRule 敏感信息的匹配规则
+type MaskRule struct {
+	// Category 类别，如 email、phone、secret
+	Category string
+	Pattern  *regexp.Regexp
+	// Validate 对匹配结果的二次校验，如身份证校验位、信用卡 Luhn 校验
+	Validate func(match string) bool
+	// Placeholder 替换匹配内容的占位符，支持 ${1} 等分组引用
+	Placeholder string
+}
+
+// IsCategoryEnabled 判断类别是否启用，未配置时全部启用
+func IsCategoryEnabled(category string) bool {
+	if len(config.SafeRedactCategories) == 0 {
+		return true
+	}
+
+	for _, item := range config.SafeRedactCategories {
+		if item == category {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskByRules 按规则替换内容中的敏感信息，返回替换后的内容和命中的类别
+func MaskByRules(rules []*MaskRule, data string) (string, []string) {
+	categories := make([]string, 0)
+	for _, rule := range rules {
+		if !IsCategoryEnabled(rule.Category) {
+			continue
+		}
+
+		matched := false
+		data = rule.Pattern.ReplaceAllStringFunc(data, func(match string) string {
+			if rule.Validate != nil && !rule.Validate(match) {
+				return match
+			}
+			matched = true
+			return string(rule.Pattern.ExpandString(nil, rule.Placeholder, match, rule.Pattern.FindStringSubmatchIndex(match)))
+		})
+
+		if matched && !containsCategory(categories, rule.Category) {
+			categories = append(categories, rule.Category)
+		}
+	}
+
+	return data, categories
+}
+
+// MaskCheckResult 按命中的类别生成检查结果
+func MaskCheckResult(categories []string, riskLevel int) CheckResult {
+	if len(categories) == 0 {
+		return CheckResult{
+			IsSafe:    true,
+			Code:      SafeDefaultSuccessCode,
+			Reason:    SafeDefaultSuccessMessage,
+			Details:   make([]string, 0),
+			RiskLevel: 0,
+		}
+	}
+
+	return CheckResult{
+		IsSafe:    false,
+		Code:      SafeSensitiveDataCode,
+		Reason:    SafeSensitiveDataMessage,
+		Details:   categories,
+		RiskLevel: riskLevel,
+	}
+}
+
+func containsCategory(categories []string, category string) bool {
+	for _, item := range categories {
+		if item == category {
+			return true
+		}
+	}
+	return false
+}
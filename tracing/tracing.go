@@ -0,0 +1,235 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName    = "one-api"
+	TraceIdHeader = "X-Trace-Id"
+)
+
+var (
+	enabled        bool
+	tracerProvider *sdktrace.TracerProvider
+)
+
+// InitTracing 初始化 OTLP 链路追踪，未开启时使用 otel 默认的空实现，所有 span 操作均无开销
+func InitTracing() {
+	viper.SetDefault("otel.service_name", "one-hub")
+	viper.SetDefault("otel.sample_ratio", 1.0)
+
+	if !viper.GetBool("otel.enabled") {
+		return
+	}
+
+	options := []otlptracehttp.Option{}
+	if endpoint := viper.GetString("otel.endpoint"); endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpointURL(endpoint))
+	}
+	if headers := viper.GetStringMapString("otel.headers"); len(headers) > 0 {
+		options = append(options, otlptracehttp.WithHeaders(headers))
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		logger.SysError("failed to create otlp trace exporter: " + err.Error())
+		return
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(viper.GetString("otel.service_name")),
+		semconv.ServiceVersion(config.Version),
+	))
+	if err != nil {
+		logger.SysError("failed to create otel resource: " + err.Error())
+		return
+	}
+
+	tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(newSampler(viper.GetFloat64("otel.sample_ratio"))),
+	)
+
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	enabled = true
+
+	logger.SysLog("otel tracing enabled")
+}
+
+// newSampler 客户端传入的 traceparent 未经认证，忽略其中的采样标记，由本服务按比例随机采样
+// 不按 trace id 采样，防止客户端构造 trace id 强制采样；本服务内部的子 span 沿用父级的采样结果
+func newSampler(ratio float64) sdktrace.Sampler {
+	remoteSampler := randomSampler{ratio: ratio}
+	return sdktrace.ParentBased(
+		sdktrace.TraceIDRatioBased(ratio),
+		sdktrace.WithRemoteParentSampled(remoteSampler),
+		sdktrace.WithRemoteParentNotSampled(remoteSampler),
+	)
+}
+
+// randomSampler 按比例随机采样，不受 trace id 和父级采样标记影响
+type randomSampler struct {
+	ratio float64
+}
+
+func (s randomSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	decision := sdktrace.Drop
+	if s.ratio >= 1 || (s.ratio > 0 && rand.Float64() < s.ratio) {
+		decision = sdktrace.RecordAndSample
+	}
+
+	return sdktrace.SamplingResult{
+		Decision:   decision,
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+func (s randomSampler) Description() string {
+	return fmt.Sprintf("RandomSampler{%g}", s.ratio)
+}
+
+// Shutdown 导出剩余的 span 并关闭
+func Shutdown(ctx context.Context) error {
+	if tracerProvider == nil {
+		return nil
+	}
+	return tracerProvider.Shutdown(ctx)
+}
+
+func Enabled() bool {
+	return enabled
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Start 创建一个子 span
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End 结束 span，err 不为空时标记为失败
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// RecordSpan 记录一个已经发生的时间段，例如流式请求的首字时间
+func RecordSpan(ctx context.Context, name string, start, end time.Time, attrs ...attribute.KeyValue) {
+	if !enabled || start.IsZero() || end.IsZero() {
+		return
+	}
+	_, span := tracer().Start(ctx, name, trace.WithTimestamp(start), trace.WithAttributes(attrs...))
+	span.End(trace.WithTimestamp(end))
+}
+
+// GinAttributes 当前请求的渠道、模型和令牌信息
+func GinAttributes(c *gin.Context) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{}
+	if tokenId := c.GetInt("token_id"); tokenId > 0 {
+		attrs = append(attrs, attribute.Int("token_id", tokenId))
+	}
+	if channelId := c.GetInt("channel_id"); channelId > 0 {
+		attrs = append(attrs, attribute.Int("channel_id", channelId))
+	}
+	if modelName := c.GetString("original_model"); modelName != "" {
+		attrs = append(attrs, attribute.String("model", modelName))
+	}
+	return attrs
+}
+
+// StartUpstream 为上游请求创建 client span，并通过 traceparent 头将链路传递给上游
+func StartUpstream(ctx context.Context, req *http.Request) trace.Span {
+	ctx, span := tracer().Start(ctx, "upstream "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Hostname()),
+			semconv.URLPath(req.URL.Path),
+		),
+	)
+	if enabled {
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	}
+	return span
+}
+
+// EndUpstream 结束上游请求的 span，记录响应状态码
+func EndUpstream(span trace.Span, resp *http.Response, err error) {
+	if err != nil {
+		End(span, err)
+		return
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	span.End()
+}
+
+// Middleware 为每个请求创建 server span，会沿用客户端传入的 traceparent 中的 trace id，但不沿用采样标记
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled {
+			c.Next()
+			return
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		name := c.Request.Method
+		if route != "" {
+			name += " " + route
+		}
+
+		ctx, span := tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				attribute.String("request_id", c.GetString(logger.RequestIdKey)),
+			),
+		)
+		defer span.End()
+
+		if span.SpanContext().IsValid() {
+			c.Header(TraceIdHeader, span.SpanContext().TraceID().String())
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		span.SetAttributes(GinAttributes(c)...)
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
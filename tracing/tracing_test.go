@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSamplerIgnoresRemoteParent(t *testing.T) {
+	traceId, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanId, _ := trace.SpanIDFromHex("0102030405060708")
+
+	cases := []struct {
+		name     string
+		ratio    float64
+		flags    trace.TraceFlags
+		expected sdktrace.SamplingDecision
+	}{
+		{"客户端要求采样但比例为 0", 0, trace.FlagsSampled, sdktrace.Drop},
+		{"客户端要求不采样但比例为 1", 1, 0, sdktrace.RecordAndSample},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			parent := trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    traceId,
+				SpanID:     spanId,
+				TraceFlags: c.flags,
+				Remote:     true,
+			})
+
+			result := newSampler(c.ratio).ShouldSample(sdktrace.SamplingParameters{
+				ParentContext: trace.ContextWithRemoteSpanContext(context.Background(), parent),
+				TraceID:       traceId,
+				Name:          "test",
+			})
+			assert.Equal(t, c.expected, result.Decision)
+		})
+	}
+}
@@ -25,3 +25,47 @@ type TaskDto struct {
 	Progress   string         `json:"progress"`
 	Data       datatypes.JSON `json:"data"`
 }
+
+// VideoRequest 统一视频生成接口的请求
+type VideoRequest struct {
+	Model          string `json:"model" binding:"required"`
+	Prompt         string `json:"prompt,omitempty"`
+	NegativePrompt string `json:"negative_prompt,omitempty"`
+	Image          string `json:"image,omitempty"`      // 首帧图片，为空时为文生视频
+	ImageTail      string `json:"image_tail,omitempty"` // 尾帧图片
+	Mode           string `json:"mode,omitempty"`
+	Duration       int    `json:"duration,omitempty"` // 视频时长，单位秒
+	AspectRatio    string `json:"aspect_ratio,omitempty"`
+	CallbackURL    string `json:"callback_url,omitempty"` // 任务状态变化时回调的地址
+}
+
+const (
+	TaskObjectStatusQueued     = "queued"
+	TaskObjectStatusInProgress = "in_progress"
+	TaskObjectStatusSucceeded  = "succeeded"
+	TaskObjectStatusFailed     = "failed"
+	TaskObjectStatusUnknown    = "unknown"
+)
+
+// TaskObject 各平台统一的异步任务结构
+type TaskObject struct {
+	ID          string       `json:"id"`
+	Object      string       `json:"object"`
+	Platform    string       `json:"platform"`
+	Action      string       `json:"action"`
+	Status      string       `json:"status"`
+	Progress    int          `json:"progress"`
+	FailReason  string       `json:"fail_reason,omitempty"`
+	CreatedAt   int64        `json:"created_at"`
+	StartedAt   int64        `json:"started_at,omitempty"`
+	FinishedAt  int64        `json:"finished_at,omitempty"`
+	Outputs     []TaskOutput `json:"outputs,omitempty"`
+	CallbackURL string       `json:"callback_url,omitempty"`
+}
+
+// TaskOutput 任务产出，Type 为 video、audio、image 或 text
+type TaskOutput struct {
+	Type    string `json:"type"`
+	URL     string `json:"url,omitempty"`
+	Content string `json:"content,omitempty"`
+}